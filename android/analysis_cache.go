@@ -0,0 +1,951 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/blueprint"
+	"google.golang.org/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+// Incremental analysis.
+//
+// When SOONG_INCREMENTAL_ANALYSIS=true the results of GenerateAndroidBuildActions for module types
+// that support it are stored in a persistent cache in $OUT_DIR/soong/.analysis_cache, or in
+// $SOONG_ANALYSIS_CACHE_DIR if set.  Each entry is keyed by a hash of everything the results can
+// depend on: the module's type, name, variant and properties, the product configuration, the
+// soong_build binary, and the keys of the module's direct dependencies.  When a module's key is
+// found in the cache the environment variables that its GenerateAndroidBuildActions read through
+// ModuleContext.Config() and the globs it used are rechecked and, if they haven't changed, its build actions and installed files are replayed
+// from the cache instead of calling GenerateAndroidBuildActions.  The number of hits and misses is
+// reported in the soong_build metrics.
+//
+// Only module types registered with RegisterIncrementalModuleType whose modules implement
+// IncrementalModule are cached, and a module is only cached if all of its direct dependencies are.
+// A module is not cached if its GenerateAndroidBuildActions does something that cannot be replayed,
+// e.g. setting a provider, defining a rule, or building with a rule or path type that the cache
+// does not know about.
+
+const (
+	// Environment variable used to enable incremental analysis.
+	envVariableIncrementalAnalysis = "SOONG_INCREMENTAL_ANALYSIS"
+	// Environment variable used to override the location of the analysis cache.
+	envVariableAnalysisCacheDir = "SOONG_ANALYSIS_CACHE_DIR"
+
+	// Must be incremented whenever the format of the cache entries changes.
+	analysisCacheVersion = 2
+)
+
+// IncrementalModule is implemented by module types whose GenerateAndroidBuildActions results can be
+// stored in the analysis cache.
+type IncrementalModule interface {
+	Module
+
+	// SaveAnalysisState stores every field that GenerateAndroidBuildActions sets on the module in
+	// state.  It is called after GenerateAndroidBuildActions when the results are being cached.
+	SaveAnalysisState(state *AnalysisState)
+
+	// RestoreAnalysisState restores the fields stored by SaveAnalysisState.  It is called instead of
+	// GenerateAndroidBuildActions when the results are found in the cache, before the cached build
+	// actions and installed files are replayed.
+	RestoreAnalysisState(ctx ModuleContext, state *AnalysisState)
+}
+
+// The module types that support incremental analysis.  Module types are registered by name rather
+// than relying on the IncrementalModule interface alone so that module types that embed an
+// IncrementalModule and override GenerateAndroidBuildActions are not cached by accident.
+var incrementalModuleTypes = make(map[string]bool)
+
+// RegisterIncrementalModuleType marks a module type as supporting incremental analysis.  The
+// modules of the type must implement IncrementalModule.  Must be called from an init function.
+func RegisterIncrementalModuleType(name string) {
+	incrementalModuleTypes[name] = true
+}
+
+// The rules that may be used by the build actions of cached modules, keyed by name.
+var analysisCacheRules = map[string]blueprint.Rule{
+	"Cp":           Cp,
+	"CpExecutable": CpExecutable,
+	"Symlink":      Symlink,
+	"Touch":        Touch,
+}
+
+func analysisCacheRuleName(rule blueprint.Rule) (string, bool) {
+	for name, r := range analysisCacheRules {
+		if r == rule {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AnalysisState holds the fields of an IncrementalModule that are set by
+// GenerateAndroidBuildActions.
+type AnalysisState struct {
+	PathValues map[string][]cachedPath `json:"paths,omitempty"`
+
+	err error
+}
+
+// SetPath stores a path under the given key.
+func (s *AnalysisState) SetPath(key string, path Path) {
+	if path == nil {
+		s.SetPaths(key, nil)
+		return
+	}
+	s.SetPaths(key, Paths{path})
+}
+
+// SetPaths stores a list of paths under the given key.
+func (s *AnalysisState) SetPaths(key string, paths Paths) {
+	encoded, err := encodePaths(paths)
+	if err != nil {
+		s.err = fmt.Errorf("%s: %s", key, err)
+		return
+	}
+	if s.PathValues == nil {
+		s.PathValues = make(map[string][]cachedPath)
+	}
+	s.PathValues[key] = encoded
+}
+
+// Path returns the path stored under the given key, or nil if there isn't one.
+func (s *AnalysisState) Path(key string) Path {
+	paths := s.Paths(key)
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths[0]
+}
+
+// Paths returns the list of paths stored under the given key.
+func (s *AnalysisState) Paths(key string) Paths {
+	paths, err := decodePaths(s.PathValues[key])
+	if err != nil {
+		s.err = fmt.Errorf("%s: %s", key, err)
+	}
+	return paths
+}
+
+// A path in the analysis cache.  Only the path types that are produced by the supported module
+// types can be stored.
+type cachedPath struct {
+	Type         string `json:"type"`
+	Path         string `json:"path"`
+	Rel          string `json:"rel"`
+	Dir          string `json:"dir,omitempty"`
+	FullPath     string `json:"full_path,omitempty"`
+	PartitionDir string `json:"partition_dir,omitempty"`
+	MakePath     bool   `json:"make_path,omitempty"`
+}
+
+func encodePath(path Path) (cachedPath, error) {
+	encodeOutputPath := func(typ string, p OutputPath) cachedPath {
+		return cachedPath{Type: typ, Path: p.path, Rel: p.rel, Dir: p.buildDir, FullPath: p.fullPath}
+	}
+
+	switch p := path.(type) {
+	case SourcePath:
+		return cachedPath{Type: "source", Path: p.path, Rel: p.rel, Dir: p.srcDir}, nil
+	case OutputPath:
+		return encodeOutputPath("output", p), nil
+	case ModuleOutPath:
+		return encodeOutputPath("module_out", p.OutputPath), nil
+	case ModuleGenPath:
+		return encodeOutputPath("module_gen", p.OutputPath), nil
+	case ModuleObjPath:
+		return encodeOutputPath("module_obj", p.OutputPath), nil
+	case ModuleResPath:
+		return encodeOutputPath("module_res", p.OutputPath), nil
+	case InstallPath:
+		return cachedPath{Type: "install", Path: p.path, Rel: p.rel, Dir: p.buildDir,
+			PartitionDir: p.partitionDir, MakePath: p.makePath}, nil
+	default:
+		return cachedPath{}, fmt.Errorf("unsupported path type %T", path)
+	}
+}
+
+func decodePath(c cachedPath) (Path, error) {
+	base := basePath{path: c.Path, rel: c.Rel}
+	output := OutputPath{basePath: base, buildDir: c.Dir, fullPath: c.FullPath}
+
+	switch c.Type {
+	case "source":
+		return SourcePath{basePath: base, srcDir: c.Dir}, nil
+	case "output":
+		return output, nil
+	case "module_out":
+		return ModuleOutPath{output}, nil
+	case "module_gen":
+		return ModuleGenPath{ModuleOutPath{output}}, nil
+	case "module_obj":
+		return ModuleObjPath{ModuleOutPath{output}}, nil
+	case "module_res":
+		return ModuleResPath{ModuleOutPath{output}}, nil
+	case "install":
+		return InstallPath{basePath: base, buildDir: c.Dir, partitionDir: c.PartitionDir,
+			makePath: c.MakePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown path type %q", c.Type)
+	}
+}
+
+func encodePaths(paths Paths) ([]cachedPath, error) {
+	var encoded []cachedPath
+	for _, path := range paths {
+		c, err := encodePath(path)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, c)
+	}
+	return encoded, nil
+}
+
+func decodePaths(encoded []cachedPath) (Paths, error) {
+	var paths Paths
+	for _, c := range encoded {
+		path, err := decodePath(c)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func encodeOptionalPath(path Path) (*cachedPath, error) {
+	if path == nil {
+		return nil, nil
+	}
+	c, err := encodePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func decodeOptionalPath(c *cachedPath) (Path, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return decodePath(*c)
+}
+
+func decodeOptionalWritablePath(c *cachedPath) (WritablePath, error) {
+	path, err := decodeOptionalPath(c)
+	if path == nil || err != nil {
+		return nil, err
+	}
+	if writable, ok := path.(WritablePath); ok {
+		return writable, nil
+	}
+	return nil, fmt.Errorf("%s is not a writable path", path)
+}
+
+func decodeWritablePaths(encoded []cachedPath) (WritablePaths, error) {
+	var paths WritablePaths
+	for i := range encoded {
+		path, err := decodeOptionalWritablePath(&encoded[i])
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// The BuildParams of a single build action in the analysis cache.
+type cachedBuildParams struct {
+	Rule            string            `json:"rule"`
+	Deps            blueprint.Deps    `json:"deps,omitempty"`
+	Depfile         *cachedPath       `json:"depfile,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	Output          *cachedPath       `json:"output,omitempty"`
+	Outputs         []cachedPath      `json:"outputs,omitempty"`
+	SymlinkOutput   *cachedPath       `json:"symlink_output,omitempty"`
+	SymlinkOutputs  []cachedPath      `json:"symlink_outputs,omitempty"`
+	ImplicitOutput  *cachedPath       `json:"implicit_output,omitempty"`
+	ImplicitOutputs []cachedPath      `json:"implicit_outputs,omitempty"`
+	Input           *cachedPath       `json:"input,omitempty"`
+	Inputs          []cachedPath      `json:"inputs,omitempty"`
+	Implicit        *cachedPath       `json:"implicit,omitempty"`
+	Implicits       []cachedPath      `json:"implicits,omitempty"`
+	OrderOnly       []cachedPath      `json:"order_only,omitempty"`
+	Validation      *cachedPath       `json:"validation,omitempty"`
+	Validations     []cachedPath      `json:"validations,omitempty"`
+	Default         bool              `json:"default,omitempty"`
+	Args            map[string]string `json:"args,omitempty"`
+}
+
+func encodeBuildParams(params BuildParams) (*cachedBuildParams, error) {
+	rule, ok := analysisCacheRuleName(params.Rule)
+	if !ok {
+		return nil, fmt.Errorf("unsupported rule %s", params.Rule)
+	}
+
+	// The build actions are replayed with a different PackageContext, so they must not refer to
+	// any variables.
+	if strings.Contains(params.Description, "$") {
+		return nil, fmt.Errorf("description %q refers to a variable", params.Description)
+	}
+	for name, value := range params.Args {
+		if strings.Contains(value, "$") {
+			return nil, fmt.Errorf("arg %s %q refers to a variable", name, value)
+		}
+	}
+
+	c := &cachedBuildParams{
+		Rule:        rule,
+		Deps:        params.Deps,
+		Description: params.Description,
+		Default:     params.Default,
+		Args:        params.Args,
+	}
+
+	var err error
+	optionalPath := func(path Path) *cachedPath {
+		var c *cachedPath
+		if err == nil {
+			c, err = encodeOptionalPath(path)
+		}
+		return c
+	}
+	paths := func(paths Paths) []cachedPath {
+		var c []cachedPath
+		if err == nil {
+			c, err = encodePaths(paths)
+		}
+		return c
+	}
+
+	c.Depfile = optionalPath(params.Depfile)
+	c.Output = optionalPath(params.Output)
+	c.Outputs = paths(params.Outputs.Paths())
+	c.SymlinkOutput = optionalPath(params.SymlinkOutput)
+	c.SymlinkOutputs = paths(params.SymlinkOutputs.Paths())
+	c.ImplicitOutput = optionalPath(params.ImplicitOutput)
+	c.ImplicitOutputs = paths(params.ImplicitOutputs.Paths())
+	c.Input = optionalPath(params.Input)
+	c.Inputs = paths(params.Inputs)
+	c.Implicit = optionalPath(params.Implicit)
+	c.Implicits = paths(params.Implicits)
+	c.OrderOnly = paths(params.OrderOnly)
+	c.Validation = optionalPath(params.Validation)
+	c.Validations = paths(params.Validations)
+
+	return c, err
+}
+
+func decodeBuildParams(c *cachedBuildParams) (BuildParams, error) {
+	rule, ok := analysisCacheRules[c.Rule]
+	if !ok {
+		return BuildParams{}, fmt.Errorf("unknown rule %q", c.Rule)
+	}
+
+	params := BuildParams{
+		Rule:        rule,
+		Deps:        c.Deps,
+		Description: c.Description,
+		Default:     c.Default,
+		Args:        c.Args,
+	}
+
+	var err error
+	optionalPath := func(c *cachedPath) Path {
+		var path Path
+		if err == nil {
+			path, err = decodeOptionalPath(c)
+		}
+		return path
+	}
+	optionalWritablePath := func(c *cachedPath) WritablePath {
+		var path WritablePath
+		if err == nil {
+			path, err = decodeOptionalWritablePath(c)
+		}
+		return path
+	}
+	paths := func(c []cachedPath) Paths {
+		var paths Paths
+		if err == nil {
+			paths, err = decodePaths(c)
+		}
+		return paths
+	}
+	writablePaths := func(c []cachedPath) WritablePaths {
+		var paths WritablePaths
+		if err == nil {
+			paths, err = decodeWritablePaths(c)
+		}
+		return paths
+	}
+
+	params.Depfile = optionalWritablePath(c.Depfile)
+	params.Output = optionalWritablePath(c.Output)
+	params.Outputs = writablePaths(c.Outputs)
+	params.SymlinkOutput = optionalWritablePath(c.SymlinkOutput)
+	params.SymlinkOutputs = writablePaths(c.SymlinkOutputs)
+	params.ImplicitOutput = optionalWritablePath(c.ImplicitOutput)
+	params.ImplicitOutputs = writablePaths(c.ImplicitOutputs)
+	params.Input = optionalPath(c.Input)
+	params.Inputs = paths(c.Inputs)
+	params.Implicit = optionalPath(c.Implicit)
+	params.Implicits = paths(c.Implicits)
+	params.OrderOnly = paths(c.OrderOnly)
+	params.Validation = optionalPath(c.Validation)
+	params.Validations = paths(c.Validations)
+
+	return params, err
+}
+
+const (
+	cachedInstallFile            = "file"
+	cachedInstallExecutable      = "executable"
+	cachedInstallSymlink         = "symlink"
+	cachedInstallAbsoluteSymlink = "absolute_symlink"
+	cachedInstallPackageFile     = "package_file"
+)
+
+// A call to one of the ModuleContext methods that install or package a file.
+type cachedInstall struct {
+	Kind    string       `json:"kind"`
+	Dir     cachedPath   `json:"dir"`
+	Name    string       `json:"name"`
+	Src     *cachedPath  `json:"src,omitempty"`
+	Deps    []cachedPath `json:"deps,omitempty"`
+	AbsPath string       `json:"abs_path,omitempty"`
+}
+
+func encodeInstall(kind string, dir InstallPath, name string, src Path, deps Paths,
+	absPath string) (*cachedInstall, error) {
+
+	c := &cachedInstall{Kind: kind, Name: name, AbsPath: absPath}
+	var err error
+	if c.Dir, err = encodePath(dir); err != nil {
+		return nil, err
+	}
+	if c.Src, err = encodeOptionalPath(src); err != nil {
+		return nil, err
+	}
+	if c.Deps, err = encodePaths(deps); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// A single build action or installed file, in the order that GenerateAndroidBuildActions created
+// them.
+type cachedAction struct {
+	Build   *cachedBuildParams `json:"build,omitempty"`
+	Install *cachedInstall     `json:"install,omitempty"`
+}
+
+// A glob performed by GenerateAndroidBuildActions and its results.
+type cachedGlob struct {
+	Pattern  string   `json:"pattern"`
+	Excludes []string `json:"excludes,omitempty"`
+	Files    []string `json:"files"`
+}
+
+// The cached results of GenerateAndroidBuildActions for a single module.
+type analysisCacheEntry struct {
+	Version int `json:"version"`
+
+	// The environment variables read by GenerateAndroidBuildActions and their values.
+	Env map[string]string `json:"env,omitempty"`
+
+	Globs         []cachedGlob   `json:"globs,omitempty"`
+	NinjaFileDeps []string       `json:"ninja_file_deps,omitempty"`
+	Actions       []cachedAction `json:"actions,omitempty"`
+	State         *AnalysisState `json:"state"`
+}
+
+// Records the effects of GenerateAndroidBuildActions on the moduleContext so that they can be
+// stored in the analysis cache.
+type analysisRecorder struct {
+	// The reason why the results cannot be cached, or empty if they can.
+	uncacheable string
+
+	globs         []cachedGlob
+	ninjaFileDeps []string
+	actions       []cachedAction
+
+	// The environment variables read through the Config returned by ModuleContext.Config.  The
+	// Config may be kept by the module and used from other goroutines, so it is locked.
+	envLock sync.Mutex
+	env     map[string]string
+
+	// Greater than zero while an install method is running, the build actions it creates are not
+	// recorded as they will be recreated when the install is replayed.
+	installDepth int
+}
+
+func (r *analysisRecorder) fail(format string, args ...interface{}) {
+	if r.uncacheable == "" {
+		r.uncacheable = fmt.Sprintf(format, args...)
+	}
+}
+
+func (r *analysisRecorder) recordEnv(key, value string) {
+	r.envLock.Lock()
+	defer r.envLock.Unlock()
+	if r.env == nil {
+		r.env = make(map[string]string)
+	}
+	r.env[key] = value
+}
+
+func (r *analysisRecorder) envSnapshot() map[string]string {
+	r.envLock.Lock()
+	defer r.envLock.Unlock()
+	snapshot := make(map[string]string, len(r.env))
+	for key, value := range r.env {
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
+func (r *analysisRecorder) recordBuild(params BuildParams) {
+	if r.installDepth > 0 {
+		return
+	}
+	c, err := encodeBuildParams(params)
+	if err != nil {
+		r.fail("build: %s", err)
+		return
+	}
+	r.actions = append(r.actions, cachedAction{Build: c})
+}
+
+// Records a call to one of the install methods and returns a function that must be called when the
+// install method returns.
+func (m *moduleContext) recordInstall(kind string, dir InstallPath, name string, src Path, deps Paths,
+	absPath string) func() {
+
+	r := m.analysisRecorder
+	if r == nil {
+		return func() {}
+	}
+
+	if r.installDepth == 0 {
+		install, err := encodeInstall(kind, dir, name, src, deps, absPath)
+		if err != nil {
+			r.fail("install %s: %s", name, err)
+		} else {
+			r.actions = append(r.actions, cachedAction{Install: install})
+		}
+	}
+
+	r.installDepth++
+	return func() {
+		r.installDepth--
+	}
+}
+
+// Config returns the Config of the module, which also records the environment variables read through
+// it if the results of GenerateAndroidBuildActions are being cached.
+func (m *moduleContext) Config() Config {
+	config := m.baseModuleContext.Config()
+	config.analysisRecorder = m.analysisRecorder
+	return config
+}
+
+// GlobWithDeps forwards to blueprint, recording the glob and its results if the results of
+// GenerateAndroidBuildActions are being cached.
+func (m *moduleContext) GlobWithDeps(pattern string, excludes []string) ([]string, error) {
+	files, err := m.bp.GlobWithDeps(pattern, excludes)
+	if r := m.analysisRecorder; r != nil {
+		if err != nil {
+			r.fail("glob %s: %s", pattern, err)
+		} else {
+			r.globs = append(r.globs, cachedGlob{Pattern: pattern, Excludes: excludes, Files: files})
+		}
+	}
+	return files, err
+}
+
+// Glob and GlobFiles are overridden so that their globs go through moduleContext.GlobWithDeps
+// rather than earlyModuleContext.GlobWithDeps.
+
+func (m *moduleContext) Glob(globPattern string, excludes []string) Paths {
+	return Glob(m, globPattern, excludes)
+}
+
+func (m *moduleContext) GlobFiles(globPattern string, excludes []string) Paths {
+	return GlobFiles(m, globPattern, excludes)
+}
+
+// AddNinjaFileDeps forwards to blueprint, recording the dependencies if the results of
+// GenerateAndroidBuildActions are being cached.
+func (m *moduleContext) AddNinjaFileDeps(deps ...string) {
+	if r := m.analysisRecorder; r != nil {
+		r.ninjaFileDeps = append(r.ninjaFileDeps, deps...)
+	}
+	m.bp.AddNinjaFileDeps(deps...)
+}
+
+// SetProvider forwards to blueprint.  Providers cannot be stored in the analysis cache, so modules
+// that set them are never cached.
+func (m *moduleContext) SetProvider(provider blueprint.ProviderKey, value interface{}) {
+	if r := m.analysisRecorder; r != nil {
+		r.fail("sets a provider")
+	}
+	m.baseModuleContext.SetProvider(provider, value)
+}
+
+type analysisCache struct {
+	dir string
+
+	// The number of variants that were replayed from the cache and that were analyzed.
+	hits   int64
+	misses int64
+}
+
+// addToMetrics adds the number of hits and misses of the cache to the soong_build metrics, if
+// incremental analysis is enabled.
+func (c *analysisCache) addToMetrics(metrics *soong_metrics_proto.SoongBuildMetrics) {
+	if c == nil {
+		return
+	}
+	metrics.AnalysisCache = &soong_metrics_proto.AnalysisCacheInfo{
+		Hits:   proto.Uint32(uint32(atomic.LoadInt64(&c.hits))),
+		Misses: proto.Uint32(uint32(atomic.LoadInt64(&c.misses))),
+	}
+}
+
+var analysisCacheOnceKey = NewOnceKey("analysisCache")
+
+// Returns the analysis cache, or nil if incremental analysis is not enabled.
+func analysisCacheForConfig(config Config) *analysisCache {
+	return config.Once(analysisCacheOnceKey, func() interface{} {
+		// Mixed builds replace the results of GenerateAndroidBuildActions with results from Bazel,
+		// which are not part of the cache key.
+		if !config.IsEnvTrue(envVariableIncrementalAnalysis) || config.BazelContext.BazelEnabled() {
+			return (*analysisCache)(nil)
+		}
+		dir := config.Getenv(envVariableAnalysisCacheDir)
+		if dir == "" {
+			dir = filepath.Join(config.BuildDir(), ".analysis_cache")
+		}
+		return &analysisCache{dir: dir}
+	}).(*analysisCache)
+}
+
+func (c *analysisCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Returns the cache entry with the given key and its encoded form, or nil if there isn't one.
+func (c *analysisCache) load(key string) (*analysisCacheEntry, []byte) {
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, nil
+	}
+	entry := &analysisCacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.Version != analysisCacheVersion || entry.State == nil {
+		return nil, nil
+	}
+	return entry, data
+}
+
+// Stores the encoded cache entry with the given key.  The entry is written to a temporary file and
+// then renamed so that concurrent builds sharing a cache never see partially written entries.
+func (c *analysisCache) store(key string, data []byte) error {
+	path := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+var analysisCacheConfigKey = NewOnceKey("analysisCacheConfig")
+
+// Returns a hash of the parts of the configuration that can affect the results of any module's
+// GenerateAndroidBuildActions, or an empty string if it could not be computed.  The environment is
+// not part of the hash, each cache entry records the environment variables that had been read when
+// it was stored instead.
+func analysisCacheConfigHash(config Config) string {
+	return config.Once(analysisCacheConfigKey, func() interface{} {
+		h := sha256.New()
+		fmt.Fprintf(h, "version %d\n", analysisCacheVersion)
+
+		// A new soong_build binary may generate different build actions for the same inputs.
+		executable, err := os.Executable()
+		if err != nil {
+			return ""
+		}
+		f, err := os.Open(executable)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return ""
+		}
+
+		productVariables, err := json.Marshal(config.productVariables)
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "\nproduct variables %s\nbuild dir %s\n", productVariables, config.buildDir)
+
+		return hex.EncodeToString(h.Sum(nil))
+	}).(string)
+}
+
+// Returns the key of the module in the analysis cache, or false if the module cannot be cached.
+func analysisCacheKeyForModule(ctx *moduleContext) (string, bool) {
+	configHash := analysisCacheConfigHash(ctx.Config())
+	if configHash == "" {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "config %s\n", configHash)
+	fmt.Fprintf(h, "module %s %s %s %s\n", ctx.ModuleType(), ctx.ModuleDir(), ctx.ModuleName(),
+		ctx.bp.ModuleSubDir())
+
+	for _, props := range ctx.module.GetProperties() {
+		data, err := json.Marshal(props)
+		if err != nil {
+			return "", false
+		}
+		fmt.Fprintf(h, "properties %T %s\n", props, data)
+	}
+
+	// The results of GenerateAndroidBuildActions can depend on anything provided by the direct
+	// dependencies, so they must all have been cached, and their keys are part of this key.
+	ok := true
+	ctx.VisitDirectDepsBlueprint(func(dep blueprint.Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		// Licenses are handled outside GenerateAndroidBuildActions.
+		if tag == licensesTag {
+			return
+		}
+		var depKey string
+		if module, isModule := dep.(Module); isModule {
+			depKey = module.base().analysisCacheKey
+		}
+		if depKey == "" {
+			ok = false
+			return
+		}
+		fmt.Fprintf(h, "dep %T %+v %s\n", tag, tag, depKey)
+	})
+	if !ok {
+		return "", false
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// Calls GenerateAndroidBuildActions for the module, or replays its results from the analysis cache.
+func generateAndroidBuildActionsWithCache(ctx *moduleContext) {
+	m := ctx.module.base()
+	m.analysisCacheKey = ""
+
+	cache := analysisCacheForConfig(ctx.Config())
+	module, isIncremental := ctx.module.(IncrementalModule)
+	if cache == nil || !isIncremental || !incrementalModuleTypes[ctx.ModuleType()] {
+		ctx.module.GenerateAndroidBuildActions(ctx)
+		return
+	}
+
+	key, ok := analysisCacheKeyForModule(ctx)
+	if !ok {
+		ctx.module.GenerateAndroidBuildActions(ctx)
+		return
+	}
+
+	if entry, data := cache.load(key); entry != nil && replayAnalysis(ctx, module, entry) {
+		atomic.AddInt64(&cache.hits, 1)
+		m.analysisCacheKey = analysisCacheResultKey(key, data)
+		return
+	}
+	atomic.AddInt64(&cache.misses, 1)
+
+	recorder := &analysisRecorder{}
+	ctx.analysisRecorder = recorder
+	module.GenerateAndroidBuildActions(ctx)
+	ctx.analysisRecorder = nil
+
+	if ctx.Failed() || recorder.uncacheable != "" {
+		return
+	}
+
+	entry := &analysisCacheEntry{
+		Version:       analysisCacheVersion,
+		Env:           recorder.envSnapshot(),
+		Globs:         recorder.globs,
+		NinjaFileDeps: recorder.ninjaFileDeps,
+		Actions:       recorder.actions,
+		State:         &AnalysisState{},
+	}
+	module.SaveAnalysisState(entry.State)
+	if entry.State.err != nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// Failing to write the cache only makes the next build slower, so it is not an error.
+	if err := cache.store(key, data); err != nil {
+		return
+	}
+	m.analysisCacheKey = analysisCacheResultKey(key, data)
+}
+
+// Returns the key that dependent modules use to refer to the results of a module.  The key of a
+// module's inputs is not enough as the same inputs can produce different results if the globs
+// they use match different files.
+func analysisCacheResultKey(key string, data []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", key)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Replays the results of GenerateAndroidBuildActions from a cache entry.  Returns false without
+// replaying anything if the entry is out of date or cannot be decoded.
+func replayAnalysis(ctx *moduleContext, module IncrementalModule, entry *analysisCacheEntry) bool {
+	// Reading the environment variables through Getenv also makes soong_build rerun when they
+	// change.
+	for name, value := range entry.Env {
+		if ctx.Config().Getenv(name) != value {
+			return false
+		}
+	}
+
+	// Rerun the globs to check that their results have not changed, which also adds the
+	// dependencies on them to the ninja file.
+	for _, glob := range entry.Globs {
+		files, err := ctx.bp.GlobWithDeps(glob.Pattern, glob.Excludes)
+		if err != nil || !equalGlobResults(files, glob.Files) {
+			return false
+		}
+	}
+
+	// Decode all of the actions before replaying any of them.
+	var replays []func()
+	for _, action := range entry.Actions {
+		if action.Build != nil {
+			params, err := decodeBuildParams(action.Build)
+			if err != nil {
+				return false
+			}
+			replays = append(replays, func() { ctx.Build(pctx, params) })
+		} else if action.Install != nil {
+			replay, err := decodeInstall(ctx, action.Install)
+			if err != nil {
+				return false
+			}
+			replays = append(replays, replay)
+		}
+	}
+
+	// GenerateAndroidBuildActions will overwrite anything that was restored if this fails.
+	module.RestoreAnalysisState(ctx, entry.State)
+	if entry.State.err != nil {
+		return false
+	}
+
+	if len(entry.NinjaFileDeps) > 0 {
+		ctx.bp.AddNinjaFileDeps(entry.NinjaFileDeps...)
+	}
+	for _, replay := range replays {
+		replay()
+	}
+	return true
+}
+
+func equalGlobResults(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a function that replays a call to one of the install methods.
+func decodeInstall(ctx *moduleContext, c *cachedInstall) (func(), error) {
+	dir, err := decodePath(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	installDir, ok := dir.(InstallPath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an install path", dir)
+	}
+	src, err := decodeOptionalPath(c.Src)
+	if err != nil {
+		return nil, err
+	}
+	deps, err := decodePaths(c.Deps)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Kind {
+	case cachedInstallFile:
+		return func() { ctx.InstallFile(installDir, c.Name, src, deps...) }, nil
+	case cachedInstallExecutable:
+		return func() { ctx.InstallExecutable(installDir, c.Name, src, deps...) }, nil
+	case cachedInstallSymlink:
+		srcInstallPath, ok := src.(InstallPath)
+		if !ok {
+			return nil, fmt.Errorf("symlink target %s is not an install path", src)
+		}
+		return func() { ctx.InstallSymlink(installDir, c.Name, srcInstallPath) }, nil
+	case cachedInstallAbsoluteSymlink:
+		return func() { ctx.InstallAbsoluteSymlink(installDir, c.Name, c.AbsPath) }, nil
+	case cachedInstallPackageFile:
+		return func() { ctx.PackageFile(installDir, c.Name, src) }, nil
+	default:
+		return nil, fmt.Errorf("unknown install kind %q", c.Kind)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sync/atomic"
+	"testing"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+func TestAnalysisCache(t *testing.T) {
+	// The build directory is part of the cache key so it must be the same for every build.
+	buildDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	bp := `
+		filegroup {
+			name: "fg",
+			srcs: ["*.txt"],
+		}
+
+		filegroup {
+			name: "fg_user",
+			srcs: [":fg", "other.java"],
+		}
+	`
+
+	build := func(t *testing.T, bp string, fs MockFS) (*TestResult, int64, int64) {
+		t.Helper()
+		preparer := GroupFixturePreparers(
+			PrepareForTestWithFilegroup,
+			FixtureMergeEnv(map[string]string{
+				envVariableIncrementalAnalysis: "true",
+				envVariableAnalysisCacheDir:    cacheDir,
+			}),
+			FixtureWithRootAndroidBp(bp),
+			fs.AddToFixture(),
+		)
+		result := createFixture(t, buildDir, preparer.list()).RunTest()
+		cache := analysisCacheForConfig(result.Config)
+		return result, atomic.LoadInt64(&cache.hits), atomic.LoadInt64(&cache.misses)
+	}
+
+	srcs := func(result *TestResult, name string) []string {
+		return result.ModuleForTests(name, "").Module().(*fileGroup).srcs.Strings()
+	}
+
+	fs := MockFS{
+		"a.txt":      nil,
+		"b.txt":      nil,
+		"other.java": nil,
+	}
+
+	result, hits, misses := build(t, bp, fs)
+	AssertIntEquals(t, "first build hits", 0, int(hits))
+	AssertIntEquals(t, "first build misses", 2, int(misses))
+	AssertDeepEquals(t, "first build srcs", []string{"a.txt", "b.txt", "a.txt", "b.txt", "other.java"},
+		append(srcs(result, "fg"), srcs(result, "fg_user")...))
+
+	result, hits, misses = build(t, bp, fs)
+	AssertIntEquals(t, "unchanged build hits", 2, int(hits))
+	AssertIntEquals(t, "unchanged build misses", 0, int(misses))
+	AssertDeepEquals(t, "unchanged build srcs", []string{"a.txt", "b.txt", "a.txt", "b.txt", "other.java"},
+		append(srcs(result, "fg"), srcs(result, "fg_user")...))
+
+	// A new file matching the glob invalidates fg, which invalidates fg_user through its key.
+	fs["c.txt"] = nil
+	result, hits, misses = build(t, bp, fs)
+	AssertIntEquals(t, "new file hits", 0, int(hits))
+	AssertIntEquals(t, "new file misses", 2, int(misses))
+	AssertDeepEquals(t, "new file srcs", []string{"a.txt", "b.txt", "c.txt"}, srcs(result, "fg"))
+
+	// Changing the properties of fg_user only invalidates fg_user.
+	changedBp := `
+		filegroup {
+			name: "fg",
+			srcs: ["*.txt"],
+		}
+
+		filegroup {
+			name: "fg_user",
+			srcs: [":fg"],
+		}
+	`
+	result, hits, misses = build(t, changedBp, fs)
+	AssertIntEquals(t, "changed properties hits", 1, int(hits))
+	AssertIntEquals(t, "changed properties misses", 1, int(misses))
+	AssertDeepEquals(t, "changed properties srcs", []string{"a.txt", "b.txt", "c.txt"}, srcs(result, "fg_user"))
+}
+
+func init() {
+	RegisterIncrementalModuleType("test_incremental_module")
+}
+
+type testIncrementalModule struct {
+	ModuleBase
+	properties struct {
+		// Environment variables to read, used by TestAnalysisCacheEnv.
+		Getenv []string
+	}
+	out ModuleOutPath
+}
+
+func testIncrementalModuleFactory() Module {
+	module := &testIncrementalModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, DeviceSupported, MultilibCommon)
+	return module
+}
+
+func (m *testIncrementalModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	for _, name := range m.properties.Getenv {
+		ctx.Config().Getenv(name)
+	}
+
+	m.out = PathForModuleOut(ctx, "out")
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: m.out,
+	})
+	installDir := PathForModuleInstall(ctx, "etc")
+	installed := ctx.InstallFile(installDir, ctx.ModuleName(), m.out)
+	ctx.InstallSymlink(installDir, ctx.ModuleName()+".link", installed)
+}
+
+func (m *testIncrementalModule) SaveAnalysisState(state *AnalysisState) {
+	state.SetPath("out", m.out)
+}
+
+func (m *testIncrementalModule) RestoreAnalysisState(ctx ModuleContext, state *AnalysisState) {
+	m.out, _ = state.Path("out").(ModuleOutPath)
+}
+
+func TestAnalysisCacheReplay(t *testing.T) {
+	buildDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	build := func(t *testing.T) (*TestResult, int64) {
+		t.Helper()
+		preparer := GroupFixturePreparers(
+			PrepareForTestWithArchMutator,
+			FixtureRegisterWithContext(func(ctx RegistrationContext) {
+				ctx.RegisterModuleType("test_incremental_module", testIncrementalModuleFactory)
+			}),
+			FixtureMergeEnv(map[string]string{
+				envVariableIncrementalAnalysis: "true",
+				envVariableAnalysisCacheDir:    cacheDir,
+			}),
+			FixtureWithRootAndroidBp(`
+				test_incremental_module {
+					name: "foo",
+				}
+			`),
+		)
+		result := createFixture(t, buildDir, preparer.list()).RunTest()
+		return result, atomic.LoadInt64(&analysisCacheForConfig(result.Config).hits)
+	}
+
+	first, hits := build(t)
+	AssertIntEquals(t, "first build hits", 0, int(hits))
+	second, hits := build(t)
+	AssertIntEquals(t, "second build hits", 1, int(hits))
+
+	firstFoo := first.ModuleForTests("foo", "android_common")
+	secondFoo := second.ModuleForTests("foo", "android_common")
+
+	AssertDeepEquals(t, "outputs", firstFoo.AllOutputs(), secondFoo.AllOutputs())
+	installed := "out/soong/target/product/test_device/system/etc/foo"
+	AssertStringEquals(t, "install description", firstFoo.Output(installed).Description,
+		secondFoo.Output(installed).Description)
+	AssertDeepEquals(t, "symlink args", firstFoo.Output(installed+".link").Args,
+		secondFoo.Output(installed+".link").Args)
+	AssertDeepEquals(t, "installed files",
+		firstFoo.Module().FilesToInstall().Strings(), secondFoo.Module().FilesToInstall().Strings())
+	AssertStringEquals(t, "restored state", firstFoo.Module().(*testIncrementalModule).out.String(),
+		secondFoo.Module().(*testIncrementalModule).out.String())
+
+	metrics := &soong_metrics_proto.SoongBuildMetrics{}
+	analysisCacheForConfig(second.Config).addToMetrics(metrics)
+	AssertIntEquals(t, "metrics hits", 1, int(metrics.GetAnalysisCache().GetHits()))
+	AssertIntEquals(t, "metrics misses", 0, int(metrics.GetAnalysisCache().GetMisses()))
+}
+
+func TestAnalysisCacheEnv(t *testing.T) {
+	buildDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	build := func(t *testing.T, env map[string]string) (int64, int64) {
+		t.Helper()
+		preparer := GroupFixturePreparers(
+			PrepareForTestWithArchMutator,
+			FixtureRegisterWithContext(func(ctx RegistrationContext) {
+				ctx.RegisterModuleType("test_incremental_module", testIncrementalModuleFactory)
+			}),
+			FixtureMergeEnv(map[string]string{
+				envVariableIncrementalAnalysis: "true",
+				envVariableAnalysisCacheDir:    cacheDir,
+			}),
+			FixtureMergeEnv(env),
+			FixtureWithRootAndroidBp(`
+				test_incremental_module {
+					name: "foo",
+					getenv: ["TEST_ANALYSIS_CACHE_ENV"],
+				}
+
+				test_incremental_module {
+					name: "bar",
+					getenv: ["TEST_ANALYSIS_CACHE_OTHER"],
+				}
+			`),
+		)
+		result := createFixture(t, buildDir, preparer.list()).RunTest()
+		cache := analysisCacheForConfig(result.Config)
+		return atomic.LoadInt64(&cache.hits), atomic.LoadInt64(&cache.misses)
+	}
+
+	hits, misses := build(t, nil)
+	AssertIntEquals(t, "first build hits", 0, int(hits))
+	AssertIntEquals(t, "first build misses", 2, int(misses))
+
+	// Variables that aren't read through Config.Getenv don't invalidate the cache.
+	hits, misses = build(t, map[string]string{"TEST_ANALYSIS_CACHE_UNUSED": "1"})
+	AssertIntEquals(t, "unused variable hits", 2, int(hits))
+	AssertIntEquals(t, "unused variable misses", 0, int(misses))
+
+	// Variables read by a module only invalidate that module, not the modules analyzed after it.
+	hits, misses = build(t, map[string]string{"TEST_ANALYSIS_CACHE_ENV": "1"})
+	AssertIntEquals(t, "changed variable hits", 1, int(hits))
+	AssertIntEquals(t, "changed variable misses", 1, int(misses))
+
+	hits, misses = build(t, map[string]string{"TEST_ANALYSIS_CACHE_ENV": "1"})
+	AssertIntEquals(t, "unchanged variable hits", 2, int(hits))
+	AssertIntEquals(t, "unchanged variable misses", 0, int(misses))
+
+	hits, misses = build(t, map[string]string{"TEST_ANALYSIS_CACHE_ENV": "1", "TEST_ANALYSIS_CACHE_OTHER": "1"})
+	AssertIntEquals(t, "other variable hits", 1, int(hits))
+	AssertIntEquals(t, "other variable misses", 1, int(misses))
+}
+
+func TestAnalysisCacheDisabled(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureWithRootAndroidBp(`
+			filegroup {
+				name: "fg",
+				srcs: ["a.txt"],
+			}
+		`),
+	).RunTest(t)
+
+	if analysisCacheForConfig(result.Config) != nil {
+		t.Errorf("expected the analysis cache to be disabled without %s", envVariableIncrementalAnalysis)
+	}
+	AssertStringEquals(t, "analysis cache key", "",
+		result.ModuleForTests("fg", "").Module().base().analysisCacheKey)
+}
+
+func TestAnalysisCachePaths(t *testing.T) {
+	paths := Paths{
+		SourcePath{basePath: basePath{path: "a/b.txt", rel: "b.txt"}},
+		OutputPath{basePath: basePath{path: "c/d", rel: "d"}, buildDir: "out/soong", fullPath: "out/soong/c/d"},
+		ModuleOutPath{OutputPath{basePath: basePath{path: ".intermediates/e", rel: "e"}, buildDir: "out/soong"}},
+		InstallPath{basePath: basePath{path: "system/etc/f", rel: "f"}, buildDir: "out/soong",
+			partitionDir: "target/product/test_device/system"},
+	}
+
+	state := &AnalysisState{}
+	state.SetPaths("paths", paths)
+	if state.err != nil {
+		t.Fatalf("unexpected error: %s", state.err)
+	}
+	AssertDeepEquals(t, "decoded paths", paths, state.Paths("paths"))
+
+	state.SetPath("unsupported", PhonyPath{basePath{path: "phony"}})
+	if state.err == nil {
+		t.Errorf("expected an error for an unsupported path type")
+	}
+}
@@ -486,6 +486,29 @@ func (m *ApexModuleBase) SetNotAvailableForPlatform() {
 	m.ApexProperties.NotAvailableForPlatform = true
 }
 
+// VisitBuildableModules calls visit for each module whose outputs a singleton can depend on, for
+// singletons that collect the outputs of all modules.  Modules that are not exported to Make when
+// Kati is enabled and stray platform variants of modules that are not available for the platform
+// are skipped.
+func VisitBuildableModules(ctx SingletonContext, visit func(Module)) {
+	ctx.VisitAllModules(func(m Module) {
+		if ctx.Config().KatiEnabled() && !m.ExportedToMake() {
+			return
+		}
+
+		if apex, ok := m.(ApexModule); ok && apex.NotAvailableForPlatform() {
+			apexInfo := ctx.ModuleProvider(m, ApexInfoProvider).(ApexInfo)
+			if apexInfo.IsForPlatform() {
+				// There are stray platform variants of modules in apexes that are not available for
+				// the platform, and they sometimes can't be built.  Don't depend on them.
+				return
+			}
+		}
+
+		visit(m)
+	})
+}
+
 // This function makes sure that the apex_available property is valid
 func (m *ApexModuleBase) checkApexAvailableProperty(mctx BaseModuleContext) {
 	for _, n := range m.ApexProperties.Apex_available {
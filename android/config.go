@@ -64,6 +64,11 @@ const productVariablesFileName = "soong.variables"
 // A Config object represents the entire build configuration for Android.
 type Config struct {
 	*config
+
+	// Non-nil in the Config returned by ModuleContext.Config while the results of
+	// GenerateAndroidBuildActions are being recorded for the analysis cache, so that the
+	// environment variables read by the module are recorded too.
+	analysisRecorder *analysisRecorder
 }
 
 // BuildDir returns the build output directory for the configuration.
@@ -338,7 +343,7 @@ func TestConfig(buildDir string, env map[string]string, bp string, fs map[string
 
 	config.bp2buildModuleTypeConfig = map[string]bool{}
 
-	return Config{config}
+	return Config{config: config}
 }
 
 func modifyTestConfigToSupportArchMutator(testConfig Config) {
@@ -505,7 +510,7 @@ func NewConfig(buildDir string, moduleListFile string, availableEnv map[string]s
 	config.bp2buildPackageConfig = bp2buildDefaultConfig
 	config.bp2buildModuleTypeConfig = make(map[string]bool)
 
-	return Config{config}, err
+	return Config{config: config}, err
 }
 
 // mockFileSystem replaces all reads with accesses to the provided map of
@@ -631,7 +636,17 @@ func (c *config) Getenv(key string) string {
 	return val
 }
 
-func (c *config) GetenvWithDefault(key string, defaultValue string) string {
+// Getenv returns the value of an environment variable, and makes soong_build rerun when it
+// changes.
+func (c Config) Getenv(key string) string {
+	val := c.config.Getenv(key)
+	if c.analysisRecorder != nil {
+		c.analysisRecorder.recordEnv(key, val)
+	}
+	return val
+}
+
+func (c Config) GetenvWithDefault(key string, defaultValue string) string {
 	ret := c.Getenv(key)
 	if ret == "" {
 		return defaultValue
@@ -639,12 +654,12 @@ func (c *config) GetenvWithDefault(key string, defaultValue string) string {
 	return ret
 }
 
-func (c *config) IsEnvTrue(key string) bool {
+func (c Config) IsEnvTrue(key string) bool {
 	value := c.Getenv(key)
 	return value == "1" || value == "y" || value == "yes" || value == "on" || value == "true"
 }
 
-func (c *config) IsEnvFalse(key string) bool {
+func (c Config) IsEnvFalse(key string) bool {
 	value := c.Getenv(key)
 	return value == "0" || value == "n" || value == "no" || value == "off" || value == "false"
 }
@@ -930,18 +945,18 @@ func (c *config) UseRemoteBuild() bool {
 	return c.UseGoma() || c.UseRBE()
 }
 
-func (c *config) RunErrorProne() bool {
+func (c Config) RunErrorProne() bool {
 	return c.IsEnvTrue("RUN_ERROR_PRONE")
 }
 
 // XrefCorpusName returns the Kythe cross-reference corpus name.
-func (c *config) XrefCorpusName() string {
+func (c Config) XrefCorpusName() string {
 	return c.Getenv("XREF_CORPUS")
 }
 
 // XrefCuEncoding returns the compilation unit encoding to use for Kythe code
 // xrefs. Can be 'json' (default), 'proto' or 'all'.
-func (c *config) XrefCuEncoding() string {
+func (c Config) XrefCuEncoding() string {
 	if enc := c.Getenv("KYTHE_KZIP_ENCODING"); enc != "" {
 		return enc
 	}
@@ -967,7 +982,7 @@ func (c Config) XrefCuJavaSourceMax() string {
 
 }
 
-func (c *config) EmitXrefRules() bool {
+func (c Config) EmitXrefRules() bool {
 	return c.XrefCorpusName() != ""
 }
 
@@ -1872,6 +1887,6 @@ func (c *config) ApexBootJars() ConfiguredJarList {
 	return c.productVariables.ApexBootJars
 }
 
-func (c *config) RBEWrapper() string {
+func (c Config) RBEWrapper() string {
 	return c.GetenvWithDefault("RBE_WRAPPER", remoteexec.DefaultWrapperPath)
 }
@@ -22,6 +22,7 @@ import (
 
 func init() {
 	RegisterModuleType("filegroup", FileGroupFactory)
+	RegisterIncrementalModuleType("filegroup")
 	RegisterBp2BuildMutator("filegroup", FilegroupBp2Build)
 }
 
@@ -124,6 +125,16 @@ func (fg *fileGroup) GenerateAndroidBuildActions(ctx ModuleContext) {
 	}
 }
 
+var _ IncrementalModule = (*fileGroup)(nil)
+
+func (fg *fileGroup) SaveAnalysisState(state *AnalysisState) {
+	state.SetPaths("srcs", fg.srcs)
+}
+
+func (fg *fileGroup) RestoreAnalysisState(ctx ModuleContext, state *AnalysisState) {
+	fg.srcs = state.Paths("srcs")
+}
+
 func (fg *fileGroup) Srcs() Paths {
 	return append(Paths{}, fg.srcs...)
 }
@@ -15,9 +15,15 @@
 package android
 
 import (
+	"container/heap"
 	"io/ioutil"
 	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/blueprint"
 	"google.golang.org/protobuf/proto"
 
 	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
@@ -55,6 +61,185 @@ func (soongMetricsSingleton) GenerateBuildActions(ctx SingletonContext) {
 	})
 }
 
+// The number of variants reported in SoongBuildMetrics.SlowestModules.
+const slowestModulesCount = 20
+
+// analysisTimings collects the time spent in each mutator and in GenerateAndroidBuildActions.
+type analysisTimings struct {
+	mutatorsLock sync.Mutex
+	mutators     []*mutatorTiming
+
+	modulesLock sync.Mutex
+	moduleTypes map[string]*moduleTypeTiming
+	slowest     moduleTimingHeap
+}
+
+var analysisTimingsOnceKey = NewOnceKey("analysis timings")
+
+func analysisTimingsForConfig(config Config) *analysisTimings {
+	return config.Once(analysisTimingsOnceKey, func() interface{} {
+		return &analysisTimings{
+			moduleTypes: make(map[string]*moduleTypeTiming),
+		}
+	}).(*analysisTimings)
+}
+
+// mutatorTiming records the wall time of a single mutator.  Blueprint runs one mutator at a time,
+// so the wall time of a mutator is the time between the start of its first call and the end of its
+// last call, even when it runs in parallel.
+type mutatorTiming struct {
+	name string
+
+	// The times of the start of the first call and the end of the last call, in nanoseconds since
+	// the epoch, or 0 if the mutator has not run.
+	start int64
+	end   int64
+}
+
+// mutator returns a new mutatorTiming for a mutator that is being registered.
+func (t *analysisTimings) mutator(name string) *mutatorTiming {
+	timing := &mutatorTiming{name: name}
+	t.mutatorsLock.Lock()
+	defer t.mutatorsLock.Unlock()
+	t.mutators = append(t.mutators, timing)
+	return timing
+}
+
+func (t *mutatorTiming) record(start, end time.Time) {
+	atomic.CompareAndSwapInt64(&t.start, 0, start.UnixNano())
+	for {
+		last := atomic.LoadInt64(&t.end)
+		if end.UnixNano() <= last || atomic.CompareAndSwapInt64(&t.end, last, end.UnixNano()) {
+			return
+		}
+	}
+}
+
+func (t *mutatorTiming) bottomUp(mutator blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	return func(ctx blueprint.BottomUpMutatorContext) {
+		start := time.Now()
+		mutator(ctx)
+		t.record(start, time.Now())
+	}
+}
+
+func (t *mutatorTiming) topDown(mutator blueprint.TopDownMutator) blueprint.TopDownMutator {
+	return func(ctx blueprint.TopDownMutatorContext) {
+		start := time.Now()
+		mutator(ctx)
+		t.record(start, time.Now())
+	}
+}
+
+type moduleTypeTiming struct {
+	variants  int
+	totalTime time.Duration
+}
+
+type moduleTiming struct {
+	name       string
+	moduleType string
+	variant    string
+	realTime   time.Duration
+}
+
+// moduleTimingHeap is a min-heap of moduleTimings ordered by realTime, used to keep the slowest
+// variants.
+type moduleTimingHeap []moduleTiming
+
+func (h moduleTimingHeap) Len() int            { return len(h) }
+func (h moduleTimingHeap) Less(i, j int) bool  { return h[i].realTime < h[j].realTime }
+func (h moduleTimingHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *moduleTimingHeap) Push(x interface{}) { *h = append(*h, x.(moduleTiming)) }
+func (h *moduleTimingHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// recordModule records the time spent in GenerateAndroidBuildActions for a single variant.
+func (t *analysisTimings) recordModule(ctx ModuleContext, realTime time.Duration) {
+	t.modulesLock.Lock()
+	defer t.modulesLock.Unlock()
+
+	typeTiming := t.moduleTypes[ctx.ModuleType()]
+	if typeTiming == nil {
+		typeTiming = &moduleTypeTiming{}
+		t.moduleTypes[ctx.ModuleType()] = typeTiming
+	}
+	typeTiming.variants++
+	typeTiming.totalTime += realTime
+
+	if len(t.slowest) == slowestModulesCount {
+		if realTime <= t.slowest[0].realTime {
+			return
+		}
+		heap.Pop(&t.slowest)
+	}
+	heap.Push(&t.slowest, moduleTiming{
+		name:       ctx.ModuleName(),
+		moduleType: ctx.ModuleType(),
+		variant:    ctx.ModuleSubDir(),
+		realTime:   realTime,
+	})
+}
+
+// addToMetrics adds the timings to the soong_build metrics.
+func (t *analysisTimings) addToMetrics(metrics *soong_metrics_proto.SoongBuildMetrics) {
+	t.mutatorsLock.Lock()
+	mutators := append([]*mutatorTiming(nil), t.mutators...)
+	t.mutatorsLock.Unlock()
+
+	for _, mutator := range mutators {
+		start, end := atomic.LoadInt64(&mutator.start), atomic.LoadInt64(&mutator.end)
+		if start == 0 {
+			continue
+		}
+		metrics.Mutators = append(metrics.Mutators, &soong_metrics_proto.PerfInfo{
+			Name:      proto.String(mutator.name),
+			StartTime: proto.Uint64(uint64(start)),
+			RealTime:  proto.Uint64(uint64(end - start)),
+		})
+	}
+	// Mutators are registered in the order that they run, but the same Config may be used by more
+	// than one Context.
+	sort.SliceStable(metrics.Mutators, func(i, j int) bool {
+		return metrics.Mutators[i].GetStartTime() < metrics.Mutators[j].GetStartTime()
+	})
+
+	t.modulesLock.Lock()
+	defer t.modulesLock.Unlock()
+
+	for _, moduleType := range SortedStringKeys(t.moduleTypes) {
+		timing := t.moduleTypes[moduleType]
+		metrics.ModuleTypes = append(metrics.ModuleTypes, &soong_metrics_proto.ModuleTypeAnalysisInfo{
+			ModuleType: proto.String(moduleType),
+			Variants:   proto.Uint32(uint32(timing.variants)),
+			TotalTime:  proto.Uint64(uint64(timing.totalTime)),
+		})
+	}
+	sort.SliceStable(metrics.ModuleTypes, func(i, j int) bool {
+		return metrics.ModuleTypes[i].GetTotalTime() > metrics.ModuleTypes[j].GetTotalTime()
+	})
+
+	slowest := append(moduleTimingHeap(nil), t.slowest...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		if slowest[i].realTime != slowest[j].realTime {
+			return slowest[i].realTime > slowest[j].realTime
+		}
+		return slowest[i].name < slowest[j].name
+	})
+	for _, module := range slowest {
+		metrics.SlowestModules = append(metrics.SlowestModules, &soong_metrics_proto.ModuleAnalysisInfo{
+			Name:       proto.String(module.name),
+			ModuleType: proto.String(module.moduleType),
+			Variant:    proto.String(module.variant),
+			RealTime:   proto.Uint64(uint64(module.realTime)),
+		})
+	}
+}
+
 func collectMetrics(config Config) *soong_metrics_proto.SoongBuildMetrics {
 	metrics := &soong_metrics_proto.SoongBuildMetrics{}
 
@@ -68,6 +253,9 @@ func collectMetrics(config Config) *soong_metrics_proto.SoongBuildMetrics {
 	metrics.TotalAllocCount = proto.Uint64(memStats.Mallocs)
 	metrics.TotalAllocSize = proto.Uint64(memStats.TotalAlloc)
 
+	analysisTimingsForConfig(config).addToMetrics(metrics)
+	analysisCacheForConfig(config).addToMetrics(metrics)
+
 	return metrics
 }
 
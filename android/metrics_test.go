@@ -0,0 +1,65 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+func TestAnalysisTimings(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureWithRootAndroidBp(`
+			filegroup {
+				name: "fg1",
+				srcs: ["a.txt"],
+			}
+
+			filegroup {
+				name: "fg2",
+				srcs: ["b.txt"],
+			}
+		`),
+	).RunTest(t)
+
+	metrics := &soong_metrics_proto.SoongBuildMetrics{}
+	analysisTimingsForConfig(result.Config).addToMetrics(metrics)
+
+	var mutators []string
+	for _, mutator := range metrics.GetMutators() {
+		mutators = append(mutators, mutator.GetName())
+	}
+	AssertStringListContains(t, "mutators", mutators, "deps")
+
+	for i := 1; i < len(metrics.GetMutators()); i++ {
+		if metrics.Mutators[i].GetStartTime() < metrics.Mutators[i-1].GetStartTime() {
+			t.Errorf("mutator %q started before %q", metrics.Mutators[i].GetName(),
+				metrics.Mutators[i-1].GetName())
+		}
+	}
+
+	AssertIntEquals(t, "module types", 1, len(metrics.GetModuleTypes()))
+	AssertStringEquals(t, "module type", "filegroup", metrics.ModuleTypes[0].GetModuleType())
+	AssertIntEquals(t, "variants", 2, int(metrics.ModuleTypes[0].GetVariants()))
+
+	var slowest []string
+	for _, module := range metrics.GetSlowestModules() {
+		slowest = append(slowest, module.GetName())
+		AssertStringEquals(t, "slowest module type", "filegroup", module.GetModuleType())
+	}
+	AssertArrayString(t, "slowest modules", []string{"fg1", "fg2"}, SortedUniqueStrings(slowest))
+}
@@ -23,6 +23,7 @@ import (
 	"regexp"
 	"strings"
 	"text/scanner"
+	"time"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -1188,13 +1189,17 @@ type ModuleBase struct {
 
 	noAddressSanitizer   bool
 	installFiles         InstallPaths
-	installFilesDepSet   *installPathsDepSet
+	installFilesDepSet   *DepSet[InstallPath]
 	checkbuildFiles      Paths
 	packagingSpecs       []PackagingSpec
-	packagingSpecsDepSet *packagingSpecsDepSet
+	packagingSpecsDepSet *DepSet[PackagingSpec]
 	noticeFiles          Paths
 	phonies              map[string]Paths
 
+	// The key of the results of GenerateAndroidBuildActions in the analysis cache, or empty if they
+	// were not cached.
+	analysisCacheKey string
+
 	// The files to copy to the dist as explicitly specified in the .bp file.
 	distFiles TaggedDistFiles
 
@@ -1586,9 +1591,9 @@ func (m *ModuleBase) EffectiveLicenseFiles() Paths {
 
 // computeInstallDeps finds the installed paths of all dependencies that have a dependency
 // tag that is annotated as needing installation via the IsInstallDepNeeded method.
-func (m *ModuleBase) computeInstallDeps(ctx ModuleContext) ([]*installPathsDepSet, []*packagingSpecsDepSet) {
-	var installDeps []*installPathsDepSet
-	var packagingSpecs []*packagingSpecsDepSet
+func (m *ModuleBase) computeInstallDeps(ctx ModuleContext) ([]*DepSet[InstallPath], []*DepSet[PackagingSpec]) {
+	var installDeps []*DepSet[InstallPath]
+	var packagingSpecs []*DepSet[PackagingSpec]
 	ctx.VisitDirectDeps(func(dep Module) {
 		if IsInstallDepNeeded(ctx.OtherModuleDependencyTag(dep)) && !dep.IsHideFromMake() && !dep.IsSkipInstall() {
 			installDeps = append(installDeps, dep.base().installFilesDepSet)
@@ -1856,7 +1861,7 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 	// set m.installFilesDepSet to only the transitive dependencies to be used as the dependencies
 	// of installed files of this module.  It will be replaced by a depset including the installed
 	// files of this module at the end for use by modules that depend on this one.
-	m.installFilesDepSet = newInstallPathsDepSet(nil, dependencyInstallFiles)
+	m.installFilesDepSet = NewDepSet[InstallPath](TOPOLOGICAL, nil, dependencyInstallFiles)
 
 	// Temporarily continue to call blueprintCtx.GetMissingDependencies() to maintain the previous behavior of never
 	// reporting missing dependency errors in Blueprint when AllowMissingDependencies == true.
@@ -1934,7 +1939,9 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			return
 		}
 
-		m.module.GenerateAndroidBuildActions(ctx)
+		start := time.Now()
+		generateAndroidBuildActionsWithCache(ctx)
+		analysisTimingsForConfig(ctx.Config()).recordModule(ctx, time.Since(start))
 		if ctx.Failed() {
 			return
 		}
@@ -1981,8 +1988,8 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 		}
 	}
 
-	m.installFilesDepSet = newInstallPathsDepSet(m.installFiles, dependencyInstallFiles)
-	m.packagingSpecsDepSet = newPackagingSpecsDepSet(m.packagingSpecs, dependencyPackagingSpecs)
+	m.installFilesDepSet = NewDepSet[InstallPath](TOPOLOGICAL, m.installFiles, dependencyInstallFiles)
+	m.packagingSpecsDepSet = NewDepSet[PackagingSpec](TOPOLOGICAL, m.packagingSpecs, dependencyPackagingSpecs)
 
 	m.buildParams = ctx.buildParams
 	m.ruleParams = ctx.ruleParams
@@ -2167,6 +2174,10 @@ type moduleContext struct {
 	buildParams []BuildParams
 	ruleParams  map[blueprint.Rule]blueprint.RuleParams
 	variables   map[string]string
+
+	// Non-nil while the results of GenerateAndroidBuildActions are being recorded for the analysis
+	// cache.
+	analysisRecorder *analysisRecorder
 }
 
 func (m *moduleContext) ninjaError(params BuildParams, err error) (PackageContext, BuildParams) {
@@ -2259,6 +2270,10 @@ func convertBuildParams(params BuildParams) blueprint.BuildParams {
 }
 
 func (m *moduleContext) Variable(pctx PackageContext, name, value string) {
+	if m.analysisRecorder != nil {
+		m.analysisRecorder.fail("defines variable %s", name)
+	}
+
 	if m.config.captureBuild {
 		m.variables[name] = value
 	}
@@ -2269,6 +2284,10 @@ func (m *moduleContext) Variable(pctx PackageContext, name, value string) {
 func (m *moduleContext) Rule(pctx PackageContext, name string, params blueprint.RuleParams,
 	argNames ...string) blueprint.Rule {
 
+	if m.analysisRecorder != nil {
+		m.analysisRecorder.fail("defines rule %s", name)
+	}
+
 	if m.config.UseRemoteBuild() {
 		if params.Pool == nil {
 			// When USE_GOMA=true or USE_RBE=true are set and the rule is not supported by goma/RBE, restrict
@@ -2292,11 +2311,18 @@ func (m *moduleContext) Rule(pctx PackageContext, name string, params blueprint.
 }
 
 func (m *moduleContext) Build(pctx PackageContext, params BuildParams) {
+	if m.analysisRecorder != nil {
+		m.analysisRecorder.recordBuild(params)
+	}
+
 	if params.Description != "" {
 		params.Description = "${moduleDesc}" + params.Description + "${moduleDescSuffix}"
 	}
 
 	if missingDeps := m.GetMissingDependencies(); len(missingDeps) > 0 {
+		if m.analysisRecorder != nil {
+			m.analysisRecorder.fail("missing dependencies")
+		}
 		pctx, params = m.ninjaError(params, fmt.Errorf("module %s missing dependencies: %s\n",
 			m.ModuleName(), strings.Join(missingDeps, ", ")))
 	}
@@ -2317,6 +2343,9 @@ func (m *moduleContext) Build(pctx PackageContext, params BuildParams) {
 }
 
 func (m *moduleContext) Phony(name string, deps ...Path) {
+	if m.analysisRecorder != nil {
+		m.analysisRecorder.fail("creates phony %s", name)
+	}
 	addPhony(m.config, name, deps...)
 }
 
@@ -2756,15 +2785,18 @@ func (m *moduleContext) skipInstall() bool {
 
 func (m *moduleContext) InstallFile(installPath InstallPath, name string, srcPath Path,
 	deps ...Path) InstallPath {
+	defer m.recordInstall(cachedInstallFile, installPath, name, srcPath, deps, "")()
 	return m.installFile(installPath, name, srcPath, deps, false)
 }
 
 func (m *moduleContext) InstallExecutable(installPath InstallPath, name string, srcPath Path,
 	deps ...Path) InstallPath {
+	defer m.recordInstall(cachedInstallExecutable, installPath, name, srcPath, deps, "")()
 	return m.installFile(installPath, name, srcPath, deps, true)
 }
 
 func (m *moduleContext) PackageFile(installPath InstallPath, name string, srcPath Path) PackagingSpec {
+	defer m.recordInstall(cachedInstallPackageFile, installPath, name, srcPath, nil, "")()
 	fullInstallPath := installPath.Join(m, name)
 	return m.packageFile(fullInstallPath, srcPath, false)
 }
@@ -2786,7 +2818,7 @@ func (m *moduleContext) installFile(installPath InstallPath, name string, srcPat
 	m.module.base().hooks.runInstallHooks(m, srcPath, fullInstallPath, false)
 
 	if !m.skipInstall() {
-		deps = append(deps, m.module.base().installFilesDepSet.ToList().Paths()...)
+		deps = append(deps, InstallPaths(m.module.base().installFilesDepSet.ToList()).Paths()...)
 
 		var implicitDeps, orderOnlyDeps Paths
 
@@ -2824,6 +2856,7 @@ func (m *moduleContext) installFile(installPath InstallPath, name string, srcPat
 }
 
 func (m *moduleContext) InstallSymlink(installPath InstallPath, name string, srcPath InstallPath) InstallPath {
+	defer m.recordInstall(cachedInstallSymlink, installPath, name, srcPath, nil, "")()
 	fullInstallPath := installPath.Join(m, name)
 	m.module.base().hooks.runInstallHooks(m, srcPath, fullInstallPath, true)
 
@@ -2861,6 +2894,7 @@ func (m *moduleContext) InstallSymlink(installPath InstallPath, name string, src
 // installPath/name -> absPath where absPath might be a path that is available only at runtime
 // (e.g. /apex/...)
 func (m *moduleContext) InstallAbsoluteSymlink(installPath InstallPath, name string, absPath string) InstallPath {
+	defer m.recordInstall(cachedInstallAbsoluteSymlink, installPath, name, nil, nil, absPath)()
 	fullInstallPath := installPath.Join(m, name)
 	m.module.base().hooks.runInstallHooks(m, nil, fullInstallPath, true)
 
@@ -2889,6 +2923,9 @@ func (m *moduleContext) InstallAbsoluteSymlink(installPath InstallPath, name str
 }
 
 func (m *moduleContext) CheckbuildFile(srcPath Path) {
+	if m.analysisRecorder != nil {
+		m.analysisRecorder.fail("adds checkbuild file %s", srcPath)
+	}
 	m.checkbuildFiles = append(m.checkbuildFiles, srcPath)
 }
 
@@ -3287,23 +3324,3 @@ func CheckBlueprintSyntax(ctx BaseModuleContext, filename string, contents strin
 	bpctx := ctx.blueprintBaseModuleContext()
 	return blueprint.CheckBlueprintSyntax(bpctx.ModuleFactories(), filename, contents)
 }
-
-// installPathsDepSet is a thin type-safe wrapper around the generic depSet.  It always uses
-// topological order.
-type installPathsDepSet struct {
-	depSet
-}
-
-// newInstallPathsDepSet returns an immutable packagingSpecsDepSet with the given direct and
-// transitive contents.
-func newInstallPathsDepSet(direct InstallPaths, transitive []*installPathsDepSet) *installPathsDepSet {
-	return &installPathsDepSet{*newDepSet(TOPOLOGICAL, direct, transitive)}
-}
-
-// ToList returns the installPathsDepSet flattened to a list in topological order.
-func (d *installPathsDepSet) ToList() InstallPaths {
-	if d == nil {
-		return nil
-	}
-	return d.depSet.ToList().(InstallPaths)
-}
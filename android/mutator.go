@@ -177,6 +177,9 @@ var preArch = []RegisterMutatorFunc{
 	// This must come after the defaults mutators to ensure that any visibility supplied
 	// in a defaults module has been successfully applied before the rules are gathered.
 	RegisterVisibilityRuleGatherer,
+
+	// Gather the rules declared in neverallow_rules modules for use by the neverallow mutator.
+	RegisterNeverallowRulesGatherer,
 }
 
 func registerArchMutator(ctx RegisterMutatorsContext) {
@@ -466,11 +469,12 @@ func (mutator *mutator) componentName() string {
 
 func (mutator *mutator) register(ctx *Context) {
 	blueprintCtx := ctx.Context
+	timing := analysisTimingsForConfig(ctx.config).mutator(mutator.name)
 	var handle blueprint.MutatorHandle
 	if mutator.bottomUpMutator != nil {
-		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, mutator.bottomUpMutator)
+		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, timing.bottomUp(mutator.bottomUpMutator))
 	} else if mutator.topDownMutator != nil {
-		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, mutator.topDownMutator)
+		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, timing.topDown(mutator.topDownMutator))
 	}
 	if mutator.parallel {
 		handle.Parallel()
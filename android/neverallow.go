@@ -41,6 +41,9 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "Without" properties matched (same rules as above)
+//
+// Rules can also be declared in Android.bp files using the neverallow_rules module type, see
+// neverallow_rules.go.
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
@@ -237,7 +240,7 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 
 	osClass := ctx.Module().Target().Os.Class
 
-	for _, r := range neverallowRules(ctx.Config()) {
+	for _, r := range allNeverallowRules(ctx.Config()) {
 		n := r.(*rule)
 		if !n.appliesToPath(dir) {
 			continue
@@ -255,7 +258,8 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
-		if !n.appliesToDirectDeps(ctx) {
+		directDep, ok := n.appliesToDirectDeps(ctx)
+		if !ok {
 			continue
 		}
 
@@ -263,7 +267,13 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
-		ctx.ModuleErrorf("violates " + n.String())
+		reportPolicyViolation(ctx, policyViolation{
+			Kind:       policyViolationNeverallow,
+			Rule:       n.String(),
+			Reason:     n.reason,
+			Properties: n.propertyNames(),
+			Dependency: directDep,
+		}, "violates "+n.String())
 	}
 }
 
@@ -516,20 +526,37 @@ func (r *rule) appliesToPath(dir string) bool {
 	return includePath && !excludePath
 }
 
-func (r *rule) appliesToDirectDeps(ctx BottomUpMutatorContext) bool {
+// Returns true if the rule applies to the module's direct dependencies, along with the qualified
+// name of the first dependency that matched, if any.
+func (r *rule) appliesToDirectDeps(ctx BottomUpMutatorContext) (string, bool) {
 	if len(r.directDeps) == 0 {
-		return true
+		return "", true
 	}
 
-	matches := false
+	matched := ""
 	ctx.VisitDirectDeps(func(m Module) {
-		if !matches {
+		if matched == "" {
 			name := ctx.OtherModuleName(m)
-			matches = r.directDeps[name]
+			if r.directDeps[name] {
+				matched = qualifiedModuleName{ctx.OtherModuleDir(m), name}.String()
+			}
 		}
 	})
 
-	return matches
+	return matched, matched != ""
+}
+
+// Returns the names of the properties that the rule requires to be matched.
+func (r *rule) propertyNames() []string {
+	var names []string
+	for _, p := range r.props {
+		fields := make([]string, len(p.fields))
+		for i, f := range p.fields {
+			fields[i] = proptools.PropertyNameForField(f)
+		}
+		names = append(names, strings.Join(fields, "."))
+	}
+	return names
 }
 
 func (r *rule) appliesToBootclasspathJar(ctx BottomUpMutatorContext) bool {
@@ -684,6 +711,17 @@ func neverallowRules(config Config) []Rule {
 	}).([]Rule)
 }
 
+var allNeverallowRulesKey = NewOnceKey("allNeverallowRules")
+
+// Returns the rules defined in Go followed by the rules declared in neverallow_rules modules.
+func allNeverallowRules(config Config) []Rule {
+	return config.Once(allNeverallowRulesKey, func() interface{} {
+		rules := neverallowRules(config)
+		declared := declaredNeverallowRules(config)
+		return append(rules[:len(rules):len(rules)], declared...)
+	}).([]Rule)
+}
+
 // Overrides the default neverallow rules for the supplied config.
 //
 // For testing only.
@@ -702,6 +740,7 @@ func PrepareForTestWithNeverallowRules(testRules []Rule) FixturePreparer {
 			}
 		}),
 		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.PreArchMutators(RegisterNeverallowRulesGatherer)
 			ctx.PostDepsMutators(registerNeverallowMutator)
 		}),
 	)
@@ -0,0 +1,290 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// The neverallow_rules module type allows neverallow rules to be declared in Android.bp files
+// rather than in Go, e.g.
+//
+//    neverallow_rules {
+//        name: "partner_policy",
+//        rules: [
+//            {
+//                in: ["vendor/partner"],
+//                not_in: ["vendor/partner/legacy"],
+//                module_type: ["cc_library"],
+//                with: [
+//                    {
+//                        property: "include_dirs",
+//                        starts_with: "external/",
+//                    },
+//                ],
+//                because: "partner code must not reach into external/ directly",
+//            },
+//        ],
+//    }
+//
+// The rules are gathered before the arch mutators and enforced by the neverallowMutator alongside
+// the rules defined in Go.
+
+func init() {
+	RegisterNeverallowRulesBuildComponents(InitRegistrationContext)
+}
+
+// Register the neverallow_rules module type.
+func RegisterNeverallowRulesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("neverallow_rules", NeverallowRulesFactory)
+}
+
+// A property matcher in a neverallow_rules module.
+//
+// Exactly one of value, starts_with, regexp, is_set or not_in_list must be specified.
+type neverallowPropertyMatcherProperties struct {
+	// The name of the property to match, nested properties are separated with a '.', e.g.
+	// "vndk.enabled".
+	Property *string
+
+	// Matches if the property value is equal to this value. "*" matches any value.
+	Value *string
+
+	// Matches if the property value starts with this prefix.
+	Starts_with *string
+
+	// Matches if the property value matches this regular expression.
+	Regexp *string
+
+	// Matches if the property is set to a non-empty value.
+	Is_set *bool
+
+	// Matches if the property value is not one of these values.
+	Not_in_list []string
+}
+
+// A single rule in a neverallow_rules module, see Rule for the meaning of each property.
+type neverallowRuleProperties struct {
+	// The directories to which the rule applies. If empty the rule applies to all directories.
+	In []string
+
+	// The directories to which the rule does not apply.
+	Not_in []string
+
+	// The module types to which the rule applies. If empty the rule applies to all module types.
+	Module_type []string
+
+	// The module types to which the rule does not apply.
+	Not_module_type []string
+
+	// The rule only applies to modules that directly depend on one of these modules.
+	In_direct_deps []string
+
+	// The rule only applies to modules whose properties match all of these matchers.
+	With []neverallowPropertyMatcherProperties
+
+	// The rule does not apply to modules whose properties match any of these matchers.
+	Without []neverallowPropertyMatcherProperties
+
+	// Why the rule exists, reported with any violations. Required.
+	Because *string
+}
+
+type neverallowRulesProperties struct {
+	// The neverallow rules to enforce.
+	Rules []neverallowRuleProperties
+}
+
+type neverallowRulesModule struct {
+	ModuleBase
+
+	properties neverallowRulesProperties
+}
+
+func (m *neverallowRulesModule) DepsMutator(ctx BottomUpMutatorContext) {
+	// Nothing to do.
+}
+
+func (m *neverallowRulesModule) GenerateAndroidBuildActions(ModuleContext) {
+	// Nothing to do.
+}
+
+func NeverallowRulesFactory() Module {
+	module := &neverallowRulesModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// Registers the function that gathers the rules declared in neverallow_rules modules.
+//
+// The rules are not dependent on arch so this is registered before the arch phase to avoid
+// having to process multiple variants for each module.
+func RegisterNeverallowRulesGatherer(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("neverallowRulesGatherer", neverallowRulesGatherer).Parallel()
+}
+
+var neverallowRulesModulesKey = NewOnceKey("neverallowRulesModules")
+
+// The rules declared by each neverallow_rules module, keyed by the module's qualified name.
+type declaredNeverallowRulesMap struct {
+	sync.Mutex
+	rules map[string][]Rule
+}
+
+func neverallowRulesModules(config Config) *declaredNeverallowRulesMap {
+	return config.Once(neverallowRulesModulesKey, func() interface{} {
+		return &declaredNeverallowRulesMap{rules: make(map[string][]Rule)}
+	}).(*declaredNeverallowRulesMap)
+}
+
+var declaredNeverallowRulesKey = NewOnceKey("declaredNeverallowRules")
+
+// Returns the rules declared in all neverallow_rules modules, ordered by the name of the module
+// that declared them.
+//
+// Must only be called once all the rules have been gathered.
+func declaredNeverallowRules(config Config) []Rule {
+	return config.Once(declaredNeverallowRulesKey, func() interface{} {
+		modules := neverallowRulesModules(config)
+		modules.Lock()
+		defer modules.Unlock()
+
+		names := make([]string, 0, len(modules.rules))
+		for name := range modules.rules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var rules []Rule
+		for _, name := range names {
+			rules = append(rules, modules.rules[name]...)
+		}
+		return rules
+	}).([]Rule)
+}
+
+// Converts the rules in a neverallow_rules module into Rules and stores them for use by the
+// neverallowMutator.
+func neverallowRulesGatherer(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(*neverallowRulesModule)
+	if !ok {
+		return
+	}
+
+	var rules []Rule
+	for i, p := range m.properties.Rules {
+		property := fmt.Sprintf("rules[%d]", i)
+		if rule := neverallowRuleFromProperties(ctx, property, p); rule != nil {
+			rules = append(rules, rule)
+		}
+	}
+
+	qualified := createQualifiedModuleName(ctx).String()
+	modules := neverallowRulesModules(ctx.Config())
+	modules.Lock()
+	defer modules.Unlock()
+	modules.rules[qualified] = rules
+}
+
+func neverallowRuleFromProperties(ctx BaseModuleContext, property string, p neverallowRuleProperties) Rule {
+	if String(p.Because) == "" {
+		ctx.PropertyErrorf(property+".because", "is required")
+		return nil
+	}
+
+	rule := NeverAllow().Because(String(p.Because))
+	// In and NotIn match whole directories, they clean each path and append a "/" so that
+	// "vendor/partner" doesn't match vendor/partner2.
+	if len(p.In) > 0 {
+		rule.In(p.In...)
+	}
+	if len(p.Not_in) > 0 {
+		rule.NotIn(p.Not_in...)
+	}
+	if len(p.Module_type) > 0 {
+		rule.ModuleType(p.Module_type...)
+	}
+	if len(p.Not_module_type) > 0 {
+		rule.NotModuleType(p.Not_module_type...)
+	}
+	if len(p.In_direct_deps) > 0 {
+		rule.InDirectDeps(p.In_direct_deps...)
+	}
+
+	valid := true
+	for i, with := range p.With {
+		name, matcher := neverallowMatcherFromProperties(ctx, fmt.Sprintf("%s.with[%d]", property, i), with)
+		if matcher == nil {
+			valid = false
+			continue
+		}
+		rule.WithMatcher(name, matcher)
+	}
+	for i, without := range p.Without {
+		name, matcher := neverallowMatcherFromProperties(ctx, fmt.Sprintf("%s.without[%d]", property, i), without)
+		if matcher == nil {
+			valid = false
+			continue
+		}
+		rule.WithoutMatcher(name, matcher)
+	}
+
+	if !valid {
+		return nil
+	}
+	return rule
+}
+
+func neverallowMatcherFromProperties(ctx BaseModuleContext, property string, p neverallowPropertyMatcherProperties) (string, ValueMatcher) {
+	name := String(p.Property)
+	if name == "" {
+		ctx.PropertyErrorf(property+".property", "is required")
+		return "", nil
+	}
+
+	var matchers []ValueMatcher
+	if p.Value != nil {
+		matchers = append(matchers, selectMatcher(*p.Value))
+	}
+	if p.Starts_with != nil {
+		matchers = append(matchers, StartsWith(*p.Starts_with))
+	}
+	if p.Regexp != nil {
+		re, err := regexp.Compile(*p.Regexp)
+		if err != nil {
+			ctx.PropertyErrorf(property+".regexp", "invalid regular expression: %s", err)
+			return "", nil
+		}
+		matchers = append(matchers, &regexMatcher{re})
+	}
+	if Bool(p.Is_set) {
+		matchers = append(matchers, isSetMatcherInstance)
+	}
+	if p.Not_in_list != nil {
+		matchers = append(matchers, NotInList(p.Not_in_list))
+	}
+
+	if len(matchers) != 1 {
+		ctx.PropertyErrorf(property,
+			"exactly one of value, starts_with, regexp, is_set or not_in_list must be specified")
+		return "", nil
+	}
+
+	return name, matchers[0]
+}
@@ -0,0 +1,263 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+var neverallowRulesTests = []struct {
+	name           string
+	fs             MockFS
+	expectedErrors []string
+}{
+	{
+		name: "in and with",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							in: ["vendor/partner"],
+							with: [
+								{
+									property: "include_dirs",
+									starts_with: "external/",
+								},
+							],
+							because: "partner code must not use external include dirs",
+						},
+					],
+				}`),
+			"vendor/partner/Android.bp": []byte(`
+				cc_library {
+					name: "libpartner",
+					include_dirs: ["external/foo"],
+				}`),
+			"other/Android.bp": []byte(`
+				cc_library {
+					name: "libother",
+					include_dirs: ["external/foo"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libpartner": violates neverallow dir:vendor/partner/\* Include_dirs.starts-with\(external/\) ` +
+				`which is restricted because partner code must not use external include dirs`,
+		},
+	},
+	{
+		name: "directories with a common prefix",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							in: ["vendor/partner"],
+							not_in: ["vendor/partner/allowed"],
+							with: [
+								{
+									property: "include_dirs",
+									starts_with: "external/",
+								},
+							],
+							because: "partner code must not use external include dirs",
+						},
+					],
+				}`),
+			"vendor/partner/sub/Android.bp": []byte(`
+				cc_library {
+					name: "libpartner_sub",
+					include_dirs: ["external/foo"],
+				}`),
+			"vendor/partner/allowed/Android.bp": []byte(`
+				cc_library {
+					name: "libpartner_allowed",
+					include_dirs: ["external/foo"],
+				}`),
+			"vendor/partner/allowed2/Android.bp": []byte(`
+				cc_library {
+					name: "libpartner_allowed2",
+					include_dirs: ["external/foo"],
+				}`),
+			"vendor/partner2/Android.bp": []byte(`
+				cc_library {
+					name: "libpartner2",
+					include_dirs: ["external/foo"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libpartner_sub": violates neverallow dir:vendor/partner/\* -dir:vendor/partner/allowed/\* `,
+			`module "libpartner_allowed2": violates neverallow dir:vendor/partner/\* -dir:vendor/partner/allowed/\* `,
+		},
+	},
+	{
+		name: "not_in, module_type and value",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							not_in: ["allowed"],
+							module_type: ["java_library"],
+							with: [
+								{
+									property: "sdk_version",
+									value: "none",
+								},
+							],
+							because: "only allowed projects may build against no sdk",
+						},
+					],
+				}`),
+			"allowed/Android.bp": []byte(`
+				java_library {
+					name: "allowed",
+					sdk_version: "none",
+				}`),
+			"other/Android.bp": []byte(`
+				java_library {
+					name: "not_allowed",
+					sdk_version: "none",
+				}
+				cc_library {
+					name: "other_type",
+					sdk_version: "none",
+				}`),
+		},
+		expectedErrors: []string{
+			`module "not_allowed": violates neverallow -dir:allowed/\* type:java_library Sdk_version=none`,
+		},
+	},
+	{
+		name: "in_direct_deps and without",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							in_direct_deps: ["libinternal"],
+							without: [
+								{
+									property: "vendor_available",
+									value: "true",
+								},
+							],
+							because: "libinternal is internal",
+						},
+					],
+				}`),
+			"internal/Android.bp": []byte(`
+				cc_library {
+					name: "libinternal",
+				}`),
+			"other/Android.bp": []byte(`
+				cc_library {
+					name: "libok",
+					vendor_available: true,
+					static_libs: ["libinternal"],
+				}
+				cc_library {
+					name: "libbad",
+					static_libs: ["libinternal"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libbad": violates neverallow -Vendor_available=true deps:libinternal`,
+		},
+	},
+	{
+		name: "missing because",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							in: ["vendor"],
+						},
+					],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "policy": rules\[0\].because: is required`,
+		},
+	},
+	{
+		name: "ambiguous matcher",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							with: [
+								{
+									property: "include_dirs",
+									value: "foo",
+									starts_with: "foo",
+								},
+							],
+							because: "reasons",
+						},
+					],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "policy": rules\[0\].with\[0\]: exactly one of value, starts_with, regexp, is_set or not_in_list must be specified`,
+		},
+	},
+	{
+		name: "invalid regexp",
+		fs: map[string][]byte{
+			"policy/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							with: [
+								{
+									property: "include_dirs",
+									regexp: "(",
+								},
+							],
+							because: "reasons",
+						},
+					],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "policy": rules\[0\].with\[0\].regexp: invalid regular expression`,
+		},
+	},
+}
+
+func TestNeverallowRules(t *testing.T) {
+	for _, test := range neverallowRulesTests {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForNeverAllowTest,
+				FixtureRegisterWithContext(RegisterNeverallowRulesBuildComponents),
+				PrepareForTestWithNeverallowRules([]Rule{}),
+				test.fs.AddToFixture(),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Policy audit mode.
+//
+// Normally each neverallow or visibility violation is reported as a module error, which stops the
+// build after the mutator that found it. When SOONG_POLICY_AUDIT=true the violations are instead
+// collected and written as a single JSON file, $OUT_DIR/soong/policy_audit.json, so that every
+// violation in the tree can be triaged in one pass, e.g.
+//
+//    SOONG_POLICY_AUDIT=true m policy_audit
+
+func init() {
+	RegisterPolicyAuditBuildComponents(InitRegistrationContext)
+}
+
+// Register the policy audit singleton.
+func RegisterPolicyAuditBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("policy_audit", policyAuditSingletonFactory)
+}
+
+var PrepareForTestWithPolicyAudit = GroupFixturePreparers(
+	FixtureRegisterWithContext(RegisterPolicyAuditBuildComponents),
+	FixtureMergeEnv(map[string]string{
+		envVariablePolicyAudit: "true",
+	}),
+)
+
+const (
+	// Environment variable used to enable policy audit mode.
+	envVariablePolicyAudit = "SOONG_POLICY_AUDIT"
+	policyAuditFileName    = "policy_audit.json"
+
+	policyViolationNeverallow = "neverallow"
+	policyViolationVisibility = "visibility"
+)
+
+// A single neverallow or visibility violation.
+type policyViolation struct {
+	// The qualified name of the module that violates the policy, e.g. //some/dir:module.
+	Module string `json:"module"`
+
+	// The type of the module that violates the policy.
+	ModuleType string `json:"module_type"`
+
+	// Either "neverallow" or "visibility".
+	Kind string `json:"kind"`
+
+	// The string form of the violated rule.
+	Rule string `json:"rule"`
+
+	// Why the rule exists, or why the module violates it.
+	Reason string `json:"reason,omitempty"`
+
+	// The properties of the module that matched the rule, if any.
+	Properties []string `json:"properties,omitempty"`
+
+	// The qualified name of the dependency that caused the violation, if any.
+	Dependency string `json:"dependency,omitempty"`
+}
+
+func policyAuditEnabled(config Config) bool {
+	return config.IsEnvTrue(envVariablePolicyAudit)
+}
+
+var policyViolationsKey = NewOnceKey("policyViolations")
+
+type policyViolations struct {
+	sync.Mutex
+	violations []policyViolation
+}
+
+func policyViolationsForConfig(config Config) *policyViolations {
+	return config.Once(policyViolationsKey, func() interface{} {
+		return &policyViolations{}
+	}).(*policyViolations)
+}
+
+// Reports a policy violation by the current module.
+//
+// If policy audit mode is enabled then the violation is recorded to be written to the policy audit
+// file, otherwise it is reported as a module error using the supplied format and args.
+func reportPolicyViolation(ctx BaseModuleContext, violation policyViolation, format string, args ...interface{}) {
+	if !policyAuditEnabled(ctx.Config()) {
+		ctx.ModuleErrorf(format, args...)
+		return
+	}
+
+	violation.Module = createQualifiedModuleName(ctx).String()
+	violation.ModuleType = ctx.ModuleType()
+
+	violations := policyViolationsForConfig(ctx.Config())
+	violations.Lock()
+	defer violations.Unlock()
+	violations.violations = append(violations.violations, violation)
+}
+
+func policyAuditSingletonFactory() Singleton {
+	return &policyAuditSingleton{}
+}
+
+type policyAuditSingleton struct{}
+
+func (p *policyAuditSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !policyAuditEnabled(ctx.Config()) {
+		return
+	}
+
+	violations := policyViolationsForConfig(ctx.Config())
+	violations.Lock()
+	defer violations.Unlock()
+
+	// The mutators run once per variant so the same violation may have been reported several times.
+	seen := make(map[string]bool)
+	var unique []policyViolation
+	for _, v := range violations.violations {
+		key := fmt.Sprintf("%s\x00%s\x00%s\x00%s", v.Module, v.Kind, v.Rule, v.Dependency)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, v)
+		}
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		a, b := unique[i], unique[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Dependency < b.Dependency
+	})
+
+	// Always write a list, even if it is empty, so that consumers do not have to handle null.
+	if unique == nil {
+		unique = []policyViolation{}
+	}
+
+	content, err := json.MarshalIndent(unique, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal policy violations: %s", err)
+		return
+	}
+
+	outputPath := PathForOutput(ctx, policyAuditFileName)
+	WriteFileRule(ctx, outputPath, string(content))
+	ctx.Phony("policy_audit", outputPath)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func policyAuditContent(t *testing.T, result *TestResult) string {
+	t.Helper()
+	return ContentFromFileRuleForTests(t, result.SingletonForTests("policy_audit").Output("policy_audit.json"))
+}
+
+func TestPolicyAuditNeverallow(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForNeverAllowTest,
+		PrepareForTestWithNeverallowRules([]Rule{
+			NeverAllow().
+				In("vendor").
+				WithMatcher("include_dirs", StartsWith("art/")).
+				Because("art is off limits"),
+			NeverAllow().
+				InDirectDeps("libinternal").
+				Because("libinternal is internal"),
+		}),
+		PrepareForTestWithPolicyAudit,
+		FixtureAddTextFile("vendor/Android.bp", `
+			cc_library {
+				name: "libvendor",
+				include_dirs: ["art/include"],
+				static_libs: ["libinternal"],
+			}
+			cc_library {
+				name: "libinternal",
+			}
+		`),
+	).RunTest(t)
+
+	AssertStringEquals(t, "policy_audit.json", `[
+  {
+    "module": "//vendor:libvendor",
+    "module_type": "cc_library",
+    "kind": "neverallow",
+    "rule": "neverallow deps:libinternal which is restricted because libinternal is internal",
+    "reason": "libinternal is internal",
+    "dependency": "//vendor:libinternal"
+  },
+  {
+    "module": "//vendor:libvendor",
+    "module_type": "cc_library",
+    "kind": "neverallow",
+    "rule": "neverallow dir:vendor/* Include_dirs.starts-with(art/) which is restricted because art is off limits",
+    "reason": "art is off limits",
+    "properties": [
+      "include_dirs"
+    ]
+  }
+]
+`, policyAuditContent(t, result))
+}
+
+func TestPolicyAuditVisibility(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithDefaults,
+		PrepareForTestWithVisibility,
+		PrepareForTestWithPolicyAudit,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+		}),
+		MockFS{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//visibility:private"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+		}.AddToFixture(),
+	).RunTest(t)
+
+	AssertStringEquals(t, "policy_audit.json", `[
+  {
+    "module": "//other:libother",
+    "module_type": "mock_library",
+    "kind": "visibility",
+    "rule": "[//visibility:private]",
+    "reason": "//top:libexample is not visible to this module, you may need to add \"//other\" to its visibility",
+    "dependency": "//top:libexample"
+  }
+]
+`, policyAuditContent(t, result))
+}
+
+func TestPolicyAuditDisabled(t *testing.T) {
+	GroupFixturePreparers(
+		prepareForNeverAllowTest,
+		PrepareForTestWithNeverallowRules([]Rule{
+			NeverAllow().In("vendor").Because("vendor is off limits"),
+		}),
+		FixtureRegisterWithContext(RegisterPolicyAuditBuildComponents),
+		FixtureAddTextFile("vendor/Android.bp", `
+			cc_library {
+				name: "libvendor",
+			}
+		`),
+	).
+		ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`module "libvendor": violates neverallow dir:vendor/\*`)).
+		RunTest(t)
+}
@@ -55,6 +55,7 @@ type RuleBuilder struct {
 	outDir           WritablePath
 	sboxTools        bool
 	sboxInputs       bool
+	sboxHermetic     bool
 	sboxManifestPath WritablePath
 	missingDeps      []string
 }
@@ -191,6 +192,30 @@ func (r *RuleBuilder) SandboxInputs() *RuleBuilder {
 	return r
 }
 
+// SandboxHermetic runs the rule in a hermetic sandbox on Linux hosts, where reading any file from
+// the source tree that was not copied into the sandbox fails.  It also implies SandboxInputs().
+func (r *RuleBuilder) SandboxHermetic() *RuleBuilder {
+	if !r.sbox {
+		panic("SandboxHermetic() must be called after Sbox()")
+	}
+	if len(r.commands) > 0 {
+		panic("SandboxHermetic() may not be called after Command()")
+	}
+	r.sboxTools = true
+	r.sboxInputs = true
+	r.sboxHermetic = true
+	return r
+}
+
+// ruleBuilderModuleName returns the directory and name of the module that is building a rule, or
+// "" if a singleton is building it.
+func ruleBuilderModuleName(ctx BuilderContext) string {
+	if ctx, ok := ctx.(ModuleContext); ok {
+		return ctx.ModuleDir() + ":" + ctx.ModuleName()
+	}
+	return ""
+}
+
 // Install associates an output of the rule with an install location, which can be retrieved later using
 // RuleBuilder.Installs.
 func (r *RuleBuilder) Install(from Path, to string) {
@@ -537,6 +562,11 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			manifest.OutputDepfile = proto.String(depFile.String())
 		}
 
+		// Hermetic sandboxes use Linux namespaces, other hosts fall back to sandboxing inputs.
+		if r.sboxHermetic && r.ctx.Config().BuildOS.Linux() {
+			manifest.Hermetic = proto.Bool(true)
+		}
+
 		// If sandboxing tools is enabled, add copy rules to the manifest to copy each tool
 		// into the sbox directory.
 		if r.sboxTools {
@@ -608,6 +638,26 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			Rel(r.ctx, r.outDir.String(), path.String())
 		}
 
+		// If tracing inputs is enabled, add the declared inputs and tools to the manifest so that
+		// sbox can report the files that the commands read without declaring them.
+		if traceDir := r.ctx.Config().Getenv("SOONG_SBOX_INPUT_TRACE_DIR"); traceDir != "" {
+			trace := &sbox_proto.InputTrace{
+				Module:    proto.String(ruleBuilderModuleName(r.ctx)),
+				ReportDir: proto.String(traceDir),
+			}
+			trace.DeclaredInputs = append(trace.DeclaredInputs, inputs.Strings()...)
+			for _, rspFile := range rspFiles {
+				trace.DeclaredInputs = append(trace.DeclaredInputs, rspFile.file.String())
+			}
+			trace.DeclaredTools = append(trace.DeclaredTools, r.Tools().Strings()...)
+			for _, c := range r.commands {
+				for _, tool := range c.packagedTools {
+					trace.DeclaredTools = append(trace.DeclaredTools, tool.srcPath.String())
+				}
+			}
+			manifest.InputTrace = trace
+		}
+
 		// Add a hash of the list of input files to the manifest so that the textproto file
 		// changes when the list of input files changes and causes the sbox rule that
 		// depends on it to rerun.
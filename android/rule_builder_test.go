@@ -498,9 +498,10 @@ type testRuleBuilderModule struct {
 	properties struct {
 		Srcs []string
 
-		Restat      bool
-		Sbox        bool
-		Sbox_inputs bool
+		Restat        bool
+		Sbox          bool
+		Sbox_inputs   bool
+		Sbox_hermetic bool
 	}
 }
 
@@ -520,6 +521,7 @@ func (t *testRuleBuilderModule) GenerateAndroidBuildActions(ctx ModuleContext) {
 
 	testRuleBuilder_Build(ctx, in, implicit, orderOnly, validation, out, outDep, outDir,
 		manifestPath, t.properties.Restat, t.properties.Sbox, t.properties.Sbox_inputs,
+		t.properties.Sbox_hermetic,
 		rspFile, rspFileContents, rspFile2, rspFileContents2)
 }
 
@@ -544,13 +546,13 @@ func (t *testRuleBuilderSingleton) GenerateBuildActions(ctx SingletonContext) {
 	manifestPath := PathForOutput(ctx, "singleton/sbox.textproto")
 
 	testRuleBuilder_Build(ctx, in, implicit, orderOnly, validation, out, outDep, outDir,
-		manifestPath, true, false, false,
+		manifestPath, true, false, false, false,
 		rspFile, rspFileContents, rspFile2, rspFileContents2)
 }
 
 func testRuleBuilder_Build(ctx BuilderContext, in Paths, implicit, orderOnly, validation Path,
 	out, outDep, outDir, manifestPath WritablePath,
-	restat, sbox, sboxInputs bool,
+	restat, sbox, sboxInputs, sboxHermetic bool,
 	rspFile WritablePath, rspFileContents Paths, rspFile2 WritablePath, rspFileContents2 Paths) {
 
 	rule := NewRuleBuilder(pctx, ctx)
@@ -560,6 +562,9 @@ func testRuleBuilder_Build(ctx BuilderContext, in Paths, implicit, orderOnly, va
 		if sboxInputs {
 			rule.SandboxInputs()
 		}
+		if sboxHermetic {
+			rule.SandboxHermetic()
+		}
 	}
 
 	rule.Command().
@@ -608,6 +613,12 @@ func TestRuleBuilder_Build(t *testing.T) {
 			sbox: true,
 			sbox_inputs: true,
 		}
+		rule_builder_test {
+			name: "foo_sbox_hermetic",
+			srcs: ["in"],
+			sbox: true,
+			sbox_hermetic: true,
+		}
 	`
 
 	result := GroupFixturePreparers(
@@ -616,6 +627,21 @@ func TestRuleBuilder_Build(t *testing.T) {
 		fs.AddToFixture(),
 	).RunTest(t)
 
+	checkManifest := func(t *testing.T, params TestingBuildParams, wantHermetic, wantChdir bool,
+		wantCopies []string) {
+
+		t.Helper()
+		manifest := RuleBuilderSboxProtoForTests(t, params)
+		AssertBoolEquals(t, "manifest.Hermetic", wantHermetic, manifest.GetHermetic())
+		AssertBoolEquals(t, "command.Chdir", wantChdir, manifest.Commands[0].GetChdir())
+
+		var copies []string
+		for _, copy := range manifest.Commands[0].CopyBefore {
+			copies = append(copies, copy.GetFrom())
+		}
+		AssertArrayString(t, "command.CopyBefore", wantCopies, copies)
+	}
+
 	check := func(t *testing.T, params TestingBuildParams, rspFile2Params TestingBuildParams,
 		wantCommand, wantOutput, wantDepfile, wantRspFile, wantRspFile2 string,
 		wantRestat bool, extraImplicits, extraCmdDeps []string) {
@@ -708,6 +734,28 @@ func TestRuleBuilder_Build(t *testing.T) {
 		module := result.ModuleForTests("foo_sbox_inputs", "")
 		check(t, module.Output("gen/foo_sbox_inputs"), module.Output(rspFile2),
 			cmd, outFile, depFile, rspFile, rspFile2, false, []string{manifest}, []string{sbox})
+		checkManifest(t, module.Output("sbox.textproto"), false, true,
+			[]string{"cp", "implicit", "in"})
+	})
+	t.Run("sbox_hermetic", func(t *testing.T) {
+		outDir := "out/soong/.intermediates/foo_sbox_hermetic"
+		outFile := filepath.Join(outDir, "gen/foo_sbox_hermetic")
+		depFile := filepath.Join(outDir, "gen/foo_sbox_hermetic.d")
+		rspFile := filepath.Join(outDir, "rsp")
+		rspFile2 := filepath.Join(outDir, "rsp2")
+		manifest := filepath.Join(outDir, "sbox.textproto")
+		sbox := filepath.Join("out", "soong", "host", result.Config.PrebuiltOS(), "bin/sbox")
+		sandboxPath := shared.TempDirForOutDir("out/soong")
+
+		cmd := `rm -rf ` + outDir + `/gen && ` +
+			sbox + ` --sandbox-path ` + sandboxPath + ` --manifest ` + manifest
+
+		module := result.ModuleForTests("foo_sbox_hermetic", "")
+		check(t, module.Output("gen/foo_sbox_hermetic"), module.Output(rspFile2),
+			cmd, outFile, depFile, rspFile, rspFile2, false, []string{manifest}, []string{sbox})
+		// SandboxHermetic implies SandboxInputs, and only uses namespaces on Linux hosts.
+		checkManifest(t, module.Output("sbox.textproto"), result.Config.BuildOS.Linux(), true,
+			[]string{"cp", "implicit", "in"})
 	})
 	t.Run("singleton", func(t *testing.T) {
 		outFile := filepath.Join("out/soong/singleton/gen/baz")
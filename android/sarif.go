@@ -0,0 +1,98 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// SARIF (https://docs.oasis-open.org/sarif/sarif/v2.1.0/) is the format consumed by code review
+// tools and IDEs for the results of static analyzers.  Modules that run Android Lint, clang-tidy
+// or clippy convert the results to a SARIF file with BuildSarifReport, and the "sarif" goal merges
+// the SARIF files of all modules into out/soong/sarif/static-analysis.sarif.
+
+func init() {
+	RegisterSingletonType("sarif", sarifSingletonFactory)
+}
+
+// SarifReportInfo is provided by modules that export the results of a static analyzer as SARIF.
+type SarifReportInfo struct {
+	// Report is the SARIF file of the module.
+	Report Path
+}
+
+var SarifReportProvider = blueprint.NewProvider(SarifReportInfo{})
+
+// BuildSarifReport adds a rule that converts the output files of a static analyzer to a SARIF
+// file in the module's output directory, and sets SarifReportProvider.  format is the format of
+// the output files understood by sarif_report, one of "lint", "clang-tidy" or "clippy".
+func BuildSarifReport(ctx ModuleContext, format string, outputs Paths) Path {
+	report := PathForModuleOut(ctx, "sarif", format+".sarif")
+	ruleName := "sarif_" + strings.ReplaceAll(format, "-", "_")
+
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("sarif_report").
+		FlagWithArg("-format ", format).
+		FlagWithOutput("-o ", report).
+		FlagWithRspFileInputList("-l ", PathForModuleOut(ctx, "sarif", format+".rsp"), outputs)
+	rule.Build(ruleName, format+" SARIF report")
+
+	ctx.SetProvider(SarifReportProvider, SarifReportInfo{Report: report})
+	return report
+}
+
+func sarifSingletonFactory() Singleton {
+	return &sarifSingleton{}
+}
+
+type sarifSingleton struct {
+	merged Path
+}
+
+func (s *sarifSingleton) GenerateBuildActions(ctx SingletonContext) {
+	var reports Paths
+	VisitBuildableModules(ctx, func(m Module) {
+		if ctx.ModuleHasProvider(m, SarifReportProvider) {
+			info := ctx.ModuleProvider(m, SarifReportProvider).(SarifReportInfo)
+			reports = append(reports, info.Report)
+		}
+	})
+
+	if len(reports) == 0 {
+		return
+	}
+	reports = SortedUniquePaths(reports)
+
+	merged := PathForOutput(ctx, "sarif", "static-analysis.sarif")
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("sarif_report").
+		FlagWithArg("-format ", "sarif").
+		FlagWithOutput("-o ", merged).
+		FlagWithRspFileInputList("-l ", PathForOutput(ctx, "sarif", "static-analysis.rsp"), reports)
+	rule.Build("sarif_merge", "merge SARIF reports")
+
+	s.merged = merged
+	ctx.Phony("sarif", merged)
+}
+
+func (s *sarifSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.merged != nil {
+		ctx.DistForGoal("sarif", s.merged)
+	}
+}
+
+var _ SingletonMakeVarsProvider = (*sarifSingleton)(nil)
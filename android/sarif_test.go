@@ -0,0 +1,91 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+type sarifTestModule struct {
+	ModuleBase
+	properties struct {
+		Tidy_outputs []string
+	}
+}
+
+func (m *sarifTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	if len(m.properties.Tidy_outputs) > 0 {
+		var outputs Paths
+		for _, o := range m.properties.Tidy_outputs {
+			outputs = append(outputs, PathForModuleOut(ctx, o))
+		}
+		BuildSarifReport(ctx, "clang-tidy", outputs)
+	}
+}
+
+func sarifTestModuleFactory() Module {
+	m := &sarifTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func TestSarifReport(t *testing.T) {
+	result := GroupFixturePreparers(
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("sarif_test", sarifTestModuleFactory)
+			ctx.RegisterSingletonType("sarif", sarifSingletonFactory)
+		}),
+		FixtureWithRootAndroidBp(`
+			sarif_test {
+				name: "foo",
+				tidy_outputs: ["a.tidy", "b.tidy"],
+			}
+
+			sarif_test {
+				name: "bar",
+				tidy_outputs: ["c.tidy"],
+			}
+
+			sarif_test {
+				name: "baz",
+			}
+		`),
+	).RunTest(t)
+
+	foo := result.ModuleForTests("foo", "")
+	report := foo.Output("sarif/clang-tidy.sarif")
+	AssertStringDoesContain(t, "foo command", report.RuleParams.Command, "-format clang-tidy")
+	AssertPathsRelativeToTopEquals(t, "foo inputs",
+		[]string{"out/soong/.intermediates/foo/a.tidy", "out/soong/.intermediates/foo/b.tidy"},
+		report.Inputs)
+
+	info := result.ModuleProvider(foo.Module(), SarifReportProvider).(SarifReportInfo)
+	AssertPathRelativeToTopEquals(t, "foo SarifReportInfo.Report",
+		"out/soong/.intermediates/foo/sarif/clang-tidy.sarif", info.Report)
+
+	if result.ModuleForTests("baz", "").MaybeOutput("sarif/clang-tidy.sarif").Rule != nil {
+		t.Errorf("expected baz to have no SARIF report")
+	}
+
+	merged := result.SingletonForTests("sarif").Output("sarif/static-analysis.sarif")
+	AssertStringDoesContain(t, "merge command", merged.RuleParams.Command, "-format sarif")
+	AssertPathsRelativeToTopEquals(t, "merged inputs",
+		[]string{
+			"out/soong/.intermediates/bar/sarif/clang-tidy.sarif",
+			"out/soong/.intermediates/foo/sarif/clang-tidy.sarif",
+		},
+		merged.Inputs)
+}
@@ -439,24 +439,34 @@ func visibilityRuleEnforcer(ctx TopDownMutatorContext) {
 
 	// Visit all the dependencies making sure that this module has access to them all.
 	ctx.VisitDirectDeps(func(dep Module) {
+		depName := ctx.OtherModuleName(dep)
+		depDir := ctx.OtherModuleDir(dep)
+		depQualified := qualifiedModuleName{depDir, depName}
+
 		// Ignore dependencies that have an ExcludeFromVisibilityEnforcementTag
 		tag := ctx.OtherModuleDependencyTag(dep)
 		if _, ok := tag.(ExcludeFromVisibilityEnforcementTag); ok {
+			recordVisibilityQueryDependency(ctx.Config(), depQualified, qualified, visibilityDependencyExempt)
 			return
 		}
 
-		depName := ctx.OtherModuleName(dep)
-		depDir := ctx.OtherModuleDir(dep)
-		depQualified := qualifiedModuleName{depDir, depName}
-
 		// Targets are always visible to other targets in their own package.
 		if depQualified.pkg == qualified.pkg {
+			recordVisibilityQueryDependency(ctx.Config(), depQualified, qualified, visibilityDependencySamePackage)
 			return
 		}
 
 		rule := effectiveVisibilityRules(ctx.Config(), depQualified)
 		if !rule.matches(qualified) {
-			ctx.ModuleErrorf("depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
+			recordVisibilityQueryDependency(ctx.Config(), depQualified, qualified, visibilityDependencyNotAllowed)
+			reportPolicyViolation(ctx, policyViolation{
+				Kind:       policyViolationVisibility,
+				Rule:       rule.String(),
+				Reason:     fmt.Sprintf("%s is not visible to this module, you may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir()),
+				Dependency: depQualified.String(),
+			}, "depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
+		} else {
+			recordVisibilityQueryDependency(ctx.Config(), depQualified, qualified, visibilityDependencyAllowed)
 		}
 	})
 }
@@ -494,16 +504,23 @@ func createQualifiedModuleName(ctx BaseModuleContext) qualifiedModuleName {
 }
 
 func packageDefaultVisibility(config Config, moduleId qualifiedModuleName) compositeRule {
+	rule, _ := packageDefaultVisibilityWithSource(config, moduleId)
+	return rule
+}
+
+// Returns the default visibility of the closest package containing the module that specifies one,
+// along with the id of that package.
+func packageDefaultVisibilityWithSource(config Config, moduleId qualifiedModuleName) (compositeRule, qualifiedModuleName) {
 	moduleToVisibilityRule := moduleToVisibilityRuleMap(config)
 	packageQualifiedId := moduleId.getContainingPackageId()
 	for {
 		value, ok := moduleToVisibilityRule.Load(packageQualifiedId)
 		if ok {
-			return value.(compositeRule)
+			return value.(compositeRule), packageQualifiedId
 		}
 
 		if packageQualifiedId.isRootPackage() {
-			return nil
+			return nil, qualifiedModuleName{}
 		}
 
 		packageQualifiedId = packageQualifiedId.getContainingPackageId()
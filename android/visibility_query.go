@@ -0,0 +1,233 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Visibility query.
+//
+// Setting SOONG_VISIBILITY_QUERY to a comma separated list of module names (e.g. libfoo) or
+// qualified module names (e.g. //frameworks/base:framework) writes the effective visibility rules
+// of each of those modules, after package defaults have been applied, to
+// $OUT_DIR/soong/visibility_query.txt. Every module that actually depends on one of the queried
+// modules is listed along with whether the visibility rules allow that dependency, e.g.
+//
+//    SOONG_VISIBILITY_QUERY=libfoo m visibility_query
+
+func init() {
+	RegisterVisibilityQueryBuildComponents(InitRegistrationContext)
+}
+
+// Register the visibility query singleton.
+func RegisterVisibilityQueryBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("visibility_query", visibilityQuerySingletonFactory)
+}
+
+const (
+	// Environment variable used to specify the modules to query.
+	envVariableVisibilityQuery = "SOONG_VISIBILITY_QUERY"
+	visibilityQueryFileName    = "visibility_query.txt"
+)
+
+// The result of checking a single dependency against the visibility rules of the dependency.
+//
+// The values are ordered so that if different variants of a module produce different results then
+// the larger one is reported.
+type visibilityDependencyStatus int
+
+const (
+	visibilityDependencyExempt visibilityDependencyStatus = iota
+	visibilityDependencySamePackage
+	visibilityDependencyAllowed
+	visibilityDependencyNotAllowed
+)
+
+func (s visibilityDependencyStatus) String() string {
+	switch s {
+	case visibilityDependencyExempt:
+		return "exempt (excluded from visibility enforcement by its dependency tag)"
+	case visibilityDependencySamePackage:
+		return "allowed (same package)"
+	case visibilityDependencyAllowed:
+		return "allowed"
+	case visibilityDependencyNotAllowed:
+		return "NOT ALLOWED"
+	default:
+		panic(fmt.Errorf("unknown visibilityDependencyStatus %d", int(s)))
+	}
+}
+
+type visibilityQuery struct {
+	// The queried module names and qualified module names.
+	modules map[string]bool
+
+	// True if the reverse dependencies of all modules are recorded, not just those of the queried
+	// modules. Used to generate visibility suggestions.
+	all bool
+
+	lock sync.Mutex
+
+	// The status of each dependency on a queried module, keyed by the queried module and then by
+	// the module that depends on it.
+	reverseDeps map[qualifiedModuleName]map[qualifiedModuleName]visibilityDependencyStatus
+}
+
+var visibilityQueryKey = NewOnceKey("visibilityQuery")
+
+// Returns the visibility query, or nil if no modules have been queried and visibility suggestions
+// have not been requested.
+func visibilityQueryForConfig(config Config) *visibilityQuery {
+	return config.Once(visibilityQueryKey, func() interface{} {
+		query := &visibilityQuery{
+			modules:     make(map[string]bool),
+			all:         visibilitySuggestionsEnabled(config),
+			reverseDeps: make(map[qualifiedModuleName]map[qualifiedModuleName]visibilityDependencyStatus),
+		}
+		for _, name := range strings.Split(config.Getenv(envVariableVisibilityQuery), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				query.modules[name] = true
+			}
+		}
+		if len(query.modules) == 0 && !query.all {
+			return (*visibilityQuery)(nil)
+		}
+		return query
+	}).(*visibilityQuery)
+}
+
+// Returns true if the module was explicitly queried.
+func (q *visibilityQuery) matches(module qualifiedModuleName) bool {
+	return q.modules[module.name] || q.modules[module.String()]
+}
+
+// Returns true if the reverse dependencies of the module are recorded.
+func (q *visibilityQuery) records(module qualifiedModuleName) bool {
+	return q.all || q.matches(module)
+}
+
+// Records the result of checking a dependency from dependent onto dep, if dep has been queried.
+func recordVisibilityQueryDependency(config Config, dep, dependent qualifiedModuleName, status visibilityDependencyStatus) {
+	query := visibilityQueryForConfig(config)
+	if query == nil || !query.records(dep) || dep == dependent {
+		return
+	}
+
+	query.lock.Lock()
+	defer query.lock.Unlock()
+
+	reverseDeps := query.reverseDeps[dep]
+	if reverseDeps == nil {
+		reverseDeps = make(map[qualifiedModuleName]visibilityDependencyStatus)
+		query.reverseDeps[dep] = reverseDeps
+	}
+	if existing, ok := reverseDeps[dependent]; !ok || status > existing {
+		reverseDeps[dependent] = status
+	}
+}
+
+func visibilityQuerySingletonFactory() Singleton {
+	return &visibilityQuerySingleton{}
+}
+
+type visibilityQuerySingleton struct{}
+
+func (v *visibilityQuerySingleton) GenerateBuildActions(ctx SingletonContext) {
+	query := visibilityQueryForConfig(ctx.Config())
+	if query == nil || len(query.modules) == 0 {
+		return
+	}
+
+	found := make(map[qualifiedModuleName]bool)
+	foundNames := make(map[string]bool)
+	ctx.VisitAllModules(func(module Module) {
+		qualified := qualifiedModuleName{ctx.ModuleDir(module), ctx.ModuleName(module)}
+		if query.matches(qualified) {
+			found[qualified] = true
+			foundNames[qualified.name] = true
+			foundNames[qualified.String()] = true
+		}
+	})
+
+	modules := make([]qualifiedModuleName, 0, len(found))
+	for qualified := range found {
+		modules = append(modules, qualified)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].String() < modules[j].String()
+	})
+
+	query.lock.Lock()
+	defer query.lock.Unlock()
+
+	sb := &strings.Builder{}
+	for _, name := range SortedStringKeys(query.modules) {
+		if !foundNames[name] {
+			fmt.Fprintf(sb, "%s: no such module\n\n", name)
+		}
+	}
+
+	for _, qualified := range modules {
+		fmt.Fprintf(sb, "%s\n", qualified)
+		rule, source := effectiveVisibilityRulesWithSource(ctx.Config(), qualified)
+		fmt.Fprintf(sb, "  effective visibility (%s): %s\n", source, rule)
+
+		reverseDeps := query.reverseDeps[qualified]
+		if len(reverseDeps) == 0 {
+			fmt.Fprintf(sb, "  no reverse dependencies\n\n")
+			continue
+		}
+
+		dependents := make([]qualifiedModuleName, 0, len(reverseDeps))
+		for dependent := range reverseDeps {
+			dependents = append(dependents, dependent)
+		}
+		sort.Slice(dependents, func(i, j int) bool {
+			return dependents[i].String() < dependents[j].String()
+		})
+
+		fmt.Fprintf(sb, "  reverse dependencies:\n")
+		for _, dependent := range dependents {
+			fmt.Fprintf(sb, "    %s: %s\n", dependent, reverseDeps[dependent])
+		}
+		fmt.Fprintf(sb, "\n")
+	}
+
+	outputPath := PathForOutput(ctx, visibilityQueryFileName)
+	WriteFileRule(ctx, outputPath, strings.TrimRight(sb.String(), "\n"))
+	ctx.Phony("visibility_query", outputPath)
+}
+
+// Returns the effective visibility rules of the module, as effectiveVisibilityRules does, along with
+// a description of where the rules came from.
+func effectiveVisibilityRulesWithSource(config Config, qualified qualifiedModuleName) (compositeRule, string) {
+	if value, ok := moduleToVisibilityRuleMap(config).Load(qualified); ok {
+		rule := value.(compositeRule)
+		if rule == nil {
+			return defaultVisibility, "default"
+		}
+		return rule, "from the module"
+	}
+
+	rule, pkg := packageDefaultVisibilityWithSource(config, qualified)
+	if rule == nil {
+		return defaultVisibility, "default"
+	}
+	return rule, "from the default_visibility of package " + pkg.String()
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestVisibilityQuery(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithDefaults,
+		PrepareForTestWithPackageModule,
+		PrepareForTestWithVisibility,
+		// Report the visibility violation in the policy audit file rather than failing the test.
+		PrepareForTestWithPolicyAudit,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+		}),
+		FixtureRegisterWithContext(RegisterVisibilityQueryBuildComponents),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_VISIBILITY_QUERY": "libexample, //top:libpublic,libmissing",
+		}),
+		MockFS{
+			"top/Blueprints": []byte(`
+				package {
+					default_visibility: ["//visibility:private"],
+				}
+
+				mock_library {
+					name: "libexample",
+				}
+
+				mock_library {
+					name: "libpublic",
+					visibility: ["//visibility:public"],
+				}
+
+				mock_library {
+					name: "libsame",
+					deps: ["libexample"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample", "libpublic"],
+				}`),
+		}.AddToFixture(),
+	).RunTest(t)
+
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("visibility_query").Output("visibility_query.txt"))
+	AssertStringEquals(t, "visibility_query.txt", `libmissing: no such module
+
+//top:libexample
+  effective visibility (from the default_visibility of package //top): [//visibility:private]
+  reverse dependencies:
+    //other:libother: NOT ALLOWED
+    //top:libsame: allowed (same package)
+
+//top:libpublic
+  effective visibility (from the module): [//visibility:public]
+  reverse dependencies:
+    //other:libother: allowed
+`, content)
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Visibility suggestions.
+//
+// When SOONG_VISIBILITY_SUGGESTIONS=true the reverse dependencies of every module are recorded by
+// the visibility enforcer and used to compute the narrowest visibility rules that still allow all of
+// a module's current dependents. Every module whose effective visibility differs from that is
+// written to $OUT_DIR/soong/visibility_suggestions.json, which bpfix can apply to the Android.bp
+// files, e.g.
+//
+//    SOONG_VISIBILITY_SUGGESTIONS=true m visibility_suggestions
+//    bpfix -visibility_suggestions out/soong/visibility_suggestions.json -w frameworks/base
+//
+// The suggestions only account for dependencies in the current product configuration, so they
+// should be reviewed before being submitted. Modules without dependents are only suggested to be
+// private if they are not build targets in their own right, i.e. they are not installed, not
+// dist'ed and not phony modules, as those are usually used from the product configuration rather
+// than by other modules.
+
+func init() {
+	RegisterVisibilitySuggestionsBuildComponents(InitRegistrationContext)
+}
+
+// Register the visibility suggestions singleton.
+func RegisterVisibilitySuggestionsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("visibility_suggestions", visibilitySuggestionsSingletonFactory)
+}
+
+const (
+	// Environment variable used to enable visibility suggestions.
+	envVariableVisibilitySuggestions = "SOONG_VISIBILITY_SUGGESTIONS"
+	visibilitySuggestionsFileName    = "visibility_suggestions.json"
+)
+
+// A suggested visibility for a single module.
+//
+// Must be kept in sync with bpfix.VisibilitySuggestion.
+type visibilitySuggestion struct {
+	// The package containing the module, i.e. the directory of its Android.bp file.
+	Dir string `json:"dir"`
+
+	// The type of the module, which distinguishes a source module from a prebuilt with the same
+	// name in the same package.
+	Type string `json:"type"`
+
+	// The name of the module as it appears in the Android.bp file.
+	Name string `json:"name"`
+
+	// The current effective visibility rules of the module.
+	CurrentVisibility []string `json:"current_visibility"`
+
+	// The narrowest visibility rules that allow all of the module's current dependents.
+	SuggestedVisibility []string `json:"suggested_visibility"`
+
+	// The modules outside the module's package that depend on it.
+	Dependents []string `json:"dependents,omitempty"`
+}
+
+func visibilitySuggestionsEnabled(config Config) bool {
+	return config.IsEnvTrue(envVariableVisibilitySuggestions)
+}
+
+func visibilitySuggestionsSingletonFactory() Singleton {
+	return &visibilitySuggestionsSingleton{}
+}
+
+type visibilitySuggestionsSingleton struct{}
+
+func (v *visibilitySuggestionsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !visibilitySuggestionsEnabled(ctx.Config()) {
+		return
+	}
+
+	query := visibilityQueryForConfig(ctx.Config())
+	query.lock.Lock()
+	defer query.lock.Unlock()
+
+	// Modules that are build targets in their own right, in any variant.
+	targets := make(map[qualifiedModuleName]bool)
+	ctx.VisitAllModules(func(module Module) {
+		if len(module.FilesToInstall()) > 0 || len(module.base().Dists()) > 0 ||
+			ctx.ModuleType(module) == "phony" {
+			targets[qualifiedModuleName{ctx.ModuleDir(module), ctx.ModuleName(module)}] = true
+		}
+	})
+
+	seen := make(map[qualifiedModuleName]bool)
+	suggestions := []visibilitySuggestion{}
+	ctx.VisitAllModules(func(module Module) {
+		// Only modules whose visibility is controlled by a visibility property can be updated,
+		// defaults modules do not have their own visibility.
+		if module.base().primaryVisibilityProperty == nil {
+			return
+		}
+		if _, ok := module.(Defaults); ok {
+			return
+		}
+
+		qualified := qualifiedModuleName{ctx.ModuleDir(module), ctx.ModuleName(module)}
+		if seen[qualified] {
+			return
+		}
+		seen[qualified] = true
+
+		var dependents []string
+		for dependent, status := range query.reverseDeps[qualified] {
+			if status == visibilityDependencyAllowed || status == visibilityDependencyNotAllowed {
+				dependents = append(dependents, dependent.String())
+			}
+		}
+		sort.Strings(dependents)
+
+		current := effectiveVisibilityRules(ctx.Config(), qualified).Strings()
+		suggested := suggestVisibility(qualified.pkg, query.reverseDeps[qualified])
+		if reflect.DeepEqual(current, suggested) {
+			return
+		}
+		if targets[qualified] && len(suggested) == 1 && suggested[0] == (privateRule{}).String() {
+			return
+		}
+
+		suggestions = append(suggestions, visibilitySuggestion{
+			Dir:                 qualified.pkg,
+			Type:                ctx.ModuleType(module),
+			Name:                module.base().BaseModuleName(),
+			CurrentVisibility:   current,
+			SuggestedVisibility: suggested,
+			Dependents:          dependents,
+		})
+	})
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Dir != suggestions[j].Dir {
+			return suggestions[i].Dir < suggestions[j].Dir
+		}
+		if suggestions[i].Name != suggestions[j].Name {
+			return suggestions[i].Name < suggestions[j].Name
+		}
+		return suggestions[i].Type < suggestions[j].Type
+	})
+
+	content, err := json.MarshalIndent(suggestions, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal visibility suggestions: %s", err)
+		return
+	}
+
+	outputPath := PathForOutput(ctx, visibilitySuggestionsFileName)
+	WriteFileRule(ctx, outputPath, string(content))
+	ctx.Phony("visibility_suggestions", outputPath)
+}
+
+// Returns the narrowest visibility rules for a module in pkg that allow all of its reverse
+// dependencies that are subject to visibility enforcement.
+func suggestVisibility(pkg string, reverseDeps map[qualifiedModuleName]visibilityDependencyStatus) []string {
+	rules := make(map[string]bool)
+	for dependent, status := range reverseDeps {
+		if status != visibilityDependencyAllowed && status != visibilityDependencyNotAllowed {
+			continue
+		}
+		if dependent.pkg == pkg {
+			continue
+		}
+
+		// Packages outside //vendor cannot make themselves visible to specific packages inside it.
+		if !isAncestor("vendor", pkg) && !isAllowedFromOutsideVendor(dependent.pkg, "__pkg__") {
+			rules["//vendor:__subpackages__"] = true
+		} else {
+			rules[packageRule{dependent.pkg}.String()] = true
+		}
+	}
+
+	if len(rules) == 0 {
+		return []string{privateRule{}.String()}
+	}
+
+	return SortedStringKeys(rules)
+}
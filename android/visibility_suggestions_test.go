@@ -0,0 +1,250 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestVisibilitySuggestions(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithDefaults,
+		PrepareForTestWithPackageModule,
+		PrepareForTestWithVisibility,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+		}),
+		FixtureRegisterWithContext(RegisterVisibilitySuggestionsBuildComponents),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_VISIBILITY_SUGGESTIONS": "true",
+		}),
+		MockFS{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libpublic",
+				}
+
+				mock_library {
+					name: "libexact",
+					visibility: ["//other"],
+				}
+
+				mock_library {
+					name: "libunused",
+					visibility: ["//visibility:public"],
+				}
+
+				mock_library {
+					name: "libforvendor",
+					visibility: ["//vendor:__subpackages__"],
+					deps: ["libunused"],
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					visibility: ["//visibility:private"],
+					deps: ["libpublic", "libexact"],
+				}`),
+			"other/sub/Blueprints": []byte(`
+				mock_library {
+					name: "libsub",
+					visibility: ["//visibility:private"],
+					deps: ["libpublic"],
+				}`),
+			"vendor/foo/Blueprints": []byte(`
+				mock_library {
+					name: "libvendor",
+					visibility: ["//visibility:private"],
+					deps: ["libforvendor"],
+				}`),
+		}.AddToFixture(),
+	).RunTest(t)
+
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("visibility_suggestions").Output("visibility_suggestions.json"))
+	AssertStringEquals(t, "visibility_suggestions.json", `[
+  {
+    "dir": "top",
+    "type": "mock_library",
+    "name": "libpublic",
+    "current_visibility": [
+      "//visibility:public"
+    ],
+    "suggested_visibility": [
+      "//other",
+      "//other/sub"
+    ],
+    "dependents": [
+      "//other/sub:libsub",
+      "//other:libother"
+    ]
+  },
+  {
+    "dir": "top",
+    "type": "mock_library",
+    "name": "libunused",
+    "current_visibility": [
+      "//visibility:public"
+    ],
+    "suggested_visibility": [
+      "//visibility:private"
+    ]
+  }
+]
+`, content)
+}
+
+func TestVisibilitySuggestionsPrebuilt(t *testing.T) {
+	// A source module and a prebuilt with the same name get separate suggestions.
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithVisibility,
+		FixtureRegisterWithContext(registerTestPrebuiltBuildComponents),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+		}),
+		FixtureRegisterWithContext(RegisterVisibilitySuggestionsBuildComponents),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_VISIBILITY_SUGGESTIONS": "true",
+		}),
+		MockFS{
+			"top/Blueprints": []byte(`
+				source {
+					name: "foo",
+				}
+
+				prebuilt {
+					name: "foo",
+					srcs: ["prebuilt_file"],
+				}`),
+			"top/source_file":   nil,
+			"top/prebuilt_file": nil,
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					visibility: ["//visibility:private"],
+					deps: ["foo"],
+				}`),
+		}.AddToFixture(),
+	).RunTest(t)
+
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("visibility_suggestions").Output("visibility_suggestions.json"))
+	AssertStringEquals(t, "visibility_suggestions.json", `[
+  {
+    "dir": "top",
+    "type": "prebuilt",
+    "name": "foo",
+    "current_visibility": [
+      "//visibility:public"
+    ],
+    "suggested_visibility": [
+      "//visibility:private"
+    ]
+  },
+  {
+    "dir": "top",
+    "type": "source",
+    "name": "foo",
+    "current_visibility": [
+      "//visibility:public"
+    ],
+    "suggested_visibility": [
+      "//other"
+    ],
+    "dependents": [
+      "//other:libother"
+    ]
+  }
+]
+`, content)
+}
+
+type mockInstalledModule struct {
+	ModuleBase
+}
+
+func newMockInstalledModule() Module {
+	m := &mockInstalledModule{}
+	InitAndroidArchModule(m, HostAndDeviceSupported, MultilibCommon)
+	return m
+}
+
+func (m *mockInstalledModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, "out")
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	ctx.InstallFile(PathForModuleInstall(ctx), ctx.ModuleName(), outputFile)
+}
+
+func TestVisibilitySuggestionsTargets(t *testing.T) {
+	// Installed and dist'ed modules are not suggested to be private just because no other module
+	// depends on them, but are still narrowed to their dependents.
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithVisibility,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+			ctx.RegisterModuleType("mock_installed", newMockInstalledModule)
+		}),
+		FixtureRegisterWithContext(RegisterVisibilitySuggestionsBuildComponents),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_VISIBILITY_SUGGESTIONS": "true",
+		}),
+		MockFS{
+			"top/Blueprints": []byte(`
+				mock_installed {
+					name: "installed",
+				}
+
+				mock_installed {
+					name: "installed_used",
+				}
+
+				mock_library {
+					name: "libdist",
+					dist: {
+						targets: ["droid"],
+					},
+				}`),
+			"other/Blueprints": []byte(`
+				mock_library {
+					name: "libother",
+					visibility: ["//visibility:private"],
+					deps: ["installed_used"],
+				}`),
+		}.AddToFixture(),
+	).RunTest(t)
+
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("visibility_suggestions").Output("visibility_suggestions.json"))
+	AssertStringEquals(t, "visibility_suggestions.json", `[
+  {
+    "dir": "top",
+    "type": "mock_installed",
+    "name": "installed_used",
+    "current_visibility": [
+      "//visibility:public"
+    ],
+    "suggested_visibility": [
+      "//other"
+    ],
+    "dependents": [
+      "//other:libother"
+    ]
+  }
+]
+`, content)
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpfix
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/google/blueprint/parser"
+)
+
+// A VisibilitySuggestion is a suggested visibility for a single module, as written by Soong to
+// out/soong/visibility_suggestions.json when SOONG_VISIBILITY_SUGGESTIONS=true.
+type VisibilitySuggestion struct {
+	// The directory of the Android.bp file that defines the module.
+	Dir string `json:"dir"`
+
+	// The type of the module, which distinguishes a source module from a prebuilt with the same
+	// name.
+	Type string `json:"type"`
+
+	// The name of the module.
+	Name string `json:"name"`
+
+	// The visibility rules that should replace the module's current visibility.
+	SuggestedVisibility []string `json:"suggested_visibility"`
+}
+
+// ReadVisibilitySuggestions reads a list of VisibilitySuggestions in JSON format.
+func ReadVisibilitySuggestions(r io.Reader) ([]VisibilitySuggestion, error) {
+	var suggestions []VisibilitySuggestion
+	if err := json.NewDecoder(r).Decode(&suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// AddVisibilitySuggestions returns a FixRequest that also applies the supplied visibility
+// suggestions.
+//
+// The directory of each module is taken from the name of the file being fixed, so bpfix must be run
+// from the root of the source tree.
+func (r FixRequest) AddVisibilitySuggestions(suggestions []VisibilitySuggestion) (result FixRequest) {
+	result.steps = append([]FixStep(nil), r.steps...)
+	result.steps = append(result.steps, FixStep{
+		Name: "applyVisibilitySuggestions",
+		Fix:  applyVisibilitySuggestions(suggestions),
+	})
+	return result
+}
+
+// suggestedModule identifies a module in a directory by its type and name.
+type suggestedModule struct {
+	typ, name string
+}
+
+func applyVisibilitySuggestions(suggestions []VisibilitySuggestion) func(*Fixer) error {
+	byDir := make(map[string]map[suggestedModule][]string)
+	for _, s := range suggestions {
+		dir := filepath.Clean(s.Dir)
+		if byDir[dir] == nil {
+			byDir[dir] = make(map[suggestedModule][]string)
+		}
+		byDir[dir][suggestedModule{s.Type, s.Name}] = s.SuggestedVisibility
+	}
+
+	return func(f *Fixer) error {
+		modules := byDir[filepath.Dir(filepath.Clean(f.tree.Name))]
+		if modules == nil {
+			return nil
+		}
+
+		for _, def := range f.tree.Defs {
+			mod, ok := def.(*parser.Module)
+			if !ok {
+				continue
+			}
+			name, ok := getLiteralStringPropertyValue(mod, "name")
+			if !ok {
+				continue
+			}
+			visibility, ok := modules[suggestedModule{mod.Type, name}]
+			if !ok {
+				continue
+			}
+
+			// Visibility rules from defaults are appended to the module's own rules, so they must be
+			// overridden for the suggested rules to take effect.
+			if _, ok := mod.GetProperty("defaults"); ok {
+				visibility = append([]string{"//visibility:override"}, visibility...)
+			}
+
+			setVisibility(mod, visibility)
+		}
+		return nil
+	}
+}
+
+// setVisibility replaces the value of the module's visibility property, adding the property after
+// the name property if necessary.
+func setVisibility(mod *parser.Module, visibility []string) {
+	list := &parser.List{}
+	for _, v := range visibility {
+		list.Values = append(list.Values, &parser.String{Value: v})
+	}
+
+	if prop, ok := mod.GetProperty("visibility"); ok {
+		prop.Value = list
+		return
+	}
+
+	prop := &parser.Property{
+		Name:  "visibility",
+		Value: list,
+	}
+	i := propertyIndex(mod.Properties, "name") + 1
+	mod.Properties = append(mod.Properties, nil)
+	copy(mod.Properties[i+1:], mod.Properties[i:])
+	mod.Properties[i] = prop
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bpfix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint/parser"
+)
+
+func TestApplyVisibilitySuggestions(t *testing.T) {
+	suggestions, err := ReadVisibilitySuggestions(strings.NewReader(`[
+		{
+			"dir": "frameworks/foo",
+			"type": "cc_library",
+			"name": "libpublic",
+			"current_visibility": ["//visibility:public"],
+			"suggested_visibility": ["//other", "//other/sub"]
+		},
+		{
+			"dir": "frameworks/foo",
+			"type": "cc_library",
+			"name": "libunused",
+			"suggested_visibility": ["//visibility:private"]
+		},
+		{
+			"dir": "frameworks/foo",
+			"type": "cc_library",
+			"name": "libdefaults",
+			"suggested_visibility": ["//other"]
+		},
+		{
+			"dir": "frameworks/foo",
+			"type": "cc_prebuilt_library_shared",
+			"name": "libpair",
+			"suggested_visibility": ["//visibility:private"]
+		},
+		{
+			"dir": "frameworks/bar",
+			"type": "cc_library",
+			"name": "libelsewhere",
+			"suggested_visibility": ["//visibility:private"]
+		}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := `
+		cc_library {
+			name: "libpublic",
+			srcs: ["a.cpp"],
+		}
+
+		cc_library {
+			name: "libunused",
+			visibility: ["//visibility:public"],
+			srcs: ["b.cpp"],
+		}
+
+		cc_library {
+			name: "libdefaults",
+			defaults: ["foo_defaults"],
+		}
+
+		cc_library {
+			name: "libelsewhere",
+		}
+
+		cc_library {
+			name: "libpair",
+		}
+
+		cc_prebuilt_library_shared {
+			name: "libpair",
+		}
+	`
+
+	expected := `
+		cc_library {
+			name: "libpublic",
+			visibility: [
+				"//other",
+				"//other/sub",
+			],
+			srcs: ["a.cpp"],
+		}
+
+		cc_library {
+			name: "libunused",
+			visibility: ["//visibility:private"],
+			srcs: ["b.cpp"],
+		}
+
+		cc_library {
+			name: "libdefaults",
+			visibility: [
+				"//visibility:override",
+				"//other",
+			],
+			defaults: ["foo_defaults"],
+		}
+
+		cc_library {
+			name: "libelsewhere",
+		}
+
+		cc_library {
+			name: "libpair",
+		}
+
+		cc_prebuilt_library_shared {
+			name: "libpair",
+		}
+	`
+
+	in, err = Reformat(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err = Reformat(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, errs := parser.Parse("frameworks/foo/Android.bp", bytes.NewBufferString(in), parser.NewScope(nil))
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	fixed, err := NewFixer(tree).Fix(NewFixRequest().AddVisibilitySuggestions(suggestions))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := parser.Print(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(out); got != expected {
+		t.Errorf("output didn't match:\ninput:\n%s\n\nexpected:\n%s\ngot:\n%s\n", in, expected, got)
+	}
+}
@@ -38,6 +38,10 @@ var (
 	list   = flag.Bool("l", false, "list files whose formatting differs from bpfmt's")
 	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
 	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	// only apply the visibility suggestions written by Soong when SOONG_VISIBILITY_SUGGESTIONS=true
+	visibilitySuggestions = flag.String("visibility_suggestions", "",
+		"apply the visibility suggestions in the given file instead of the standard fixes, must be run from the root of the source tree")
 )
 
 var (
@@ -132,6 +136,14 @@ func Run() {
 	flag.Parse()
 
 	fixRequest := bpfix.NewFixRequest().AddAll()
+	if *visibilitySuggestions != "" {
+		suggestions, err := readVisibilitySuggestions(*visibilitySuggestions)
+		if err != nil {
+			report(err)
+			return
+		}
+		fixRequest = bpfix.NewFixRequest().AddVisibilitySuggestions(suggestions)
+	}
 
 	if flag.NArg() == 0 {
 		if *write {
@@ -160,6 +172,19 @@ func Run() {
 	}
 }
 
+func readVisibilitySuggestions(filename string) ([]bpfix.VisibilitySuggestion, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	suggestions, err := bpfix.ReadVisibilitySuggestions(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %s", filename, err)
+	}
+	return suggestions, nil
+}
+
 func diff(b1, b2 []byte) (data []byte, err error) {
 	f1, err := ioutil.TempFile("", "bpfix")
 	if err != nil {
@@ -195,10 +195,13 @@ var (
 		},
 		"clangBin", "format")
 
-	// Rule for invoking clang-tidy (a clang-based linter).
+	// Rule for invoking clang-tidy (a clang-based linter).  The output of clang-tidy is printed and
+	// also saved in $out when it succeeds, to be converted to a SARIF report.
 	clangTidy, clangTidyRE = pctx.RemoteStaticRules("clangTidy",
 		blueprint.RuleParams{
-			Command:     "rm -f $out && $reTemplate${config.ClangBin}/clang-tidy $tidyFlags $in -- $cFlags && touch $out",
+			Command: "rm -f $out && $reTemplate${config.ClangBin}/clang-tidy $tidyFlags $in -- $cFlags > $out.tmp 2>&1; " +
+				"status=$$?; cat $out.tmp; " +
+				"if [ $$status -eq 0 ]; then mv $out.tmp $out; else rm -f $out.tmp; fi; exit $$status",
 			CommandDeps: []string{"${config.ClangBin}/clang-tidy"},
 		},
 		&remoteexec.REParams{
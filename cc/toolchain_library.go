@@ -102,7 +102,7 @@ func (library *toolchainLibraryDecorator) link(ctx ModuleContext,
 		outputFile = strippedPath
 	}
 
-	depSet := android.NewDepSetBuilder(android.TOPOLOGICAL).Direct(outputFile).Build()
+	depSet := android.NewDepSetBuilder[android.Path](android.TOPOLOGICAL).Direct(outputFile).Build()
 	ctx.SetProvider(StaticLibraryInfoProvider, StaticLibraryInfo{
 		StaticLibrary: outputFile,
 
@@ -16,6 +16,10 @@
 // that can be used by ninja. Ninja doesn't support multiple output files (even
 // though it doesn't care what the output file is, or whether it matches what is
 // expected).
+//
+// It can also rewrite absolute paths to be relative to the source tree, validate
+// that the inputs exist and are under a set of allowed roots, and print the
+// difference between two dependency files.
 package main
 
 import (
@@ -25,46 +29,96 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"android/soong/makedeps"
 )
 
+type multipleStringArg []string
+
+func (m *multipleStringArg) String() string {
+	return strings.Join(*m, " ")
+}
+
+func (m *multipleStringArg) Set(s string) error {
+	*m = append(*m, s)
+	return nil
+}
+
 func main() {
+	var allowedRoots multipleStringArg
+
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-o <output>] <depfile.d> [<depfile.d>...]", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [-o <output>] <depfile.d> [<depfile.d>...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -diff <old.d> <new.d>\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	output := flag.String("o", "", "Optional output file (defaults to rewriting source if necessary)")
+	relativeTo := flag.String("relative-to", "", "Rewrite absolute paths under this directory to be relative to it")
+	dropOutside := flag.Bool("drop-outside", false, "With -relative-to, drop absolute inputs outside of the directory instead of failing")
+	validate := flag.Bool("validate", false, "Fail if any input doesn't exist or is not under one of the -allowed-root directories")
+	flag.Var(&allowedRoots, "allowed-root", "Directory that inputs must be under, may be repeated (implies -validate)")
+	diff := flag.Bool("diff", false, "Print the difference between two dependency files, and exit with 1 if they differ")
 	flag.Parse()
 
 	if flag.NArg() < 1 {
 		log.Fatal("Expected at least one input file as an argument")
 	}
 
-	var mergedDeps *makedeps.Deps
-	var firstInput []byte
-
-	for i, arg := range flag.Args() {
-		input, err := ioutil.ReadFile(arg)
-		if err != nil {
-			log.Fatalf("Error opening %q: %v", arg, err)
+	if *diff {
+		if flag.NArg() != 2 {
+			log.Fatal("-diff expects exactly two input files")
+		}
+		old, _ := parseDepFile(flag.Arg(0))
+		new, _ := parseDepFile(flag.Arg(1))
+		if d := makedeps.Diff(old, new); !d.Empty() {
+			fmt.Print(d.String())
+			os.Exit(1)
 		}
+		return
+	}
 
-		deps, err := makedeps.Parse(arg, bytes.NewBuffer(append([]byte(nil), input...)))
+	var root string
+	if *relativeTo != "" {
+		var err error
+		root, err = filepath.Abs(*relativeTo)
 		if err != nil {
-			log.Fatalf("Failed to parse: %v", err)
+			log.Fatal(err)
 		}
+	}
+
+	var allDeps []*makedeps.Deps
+	var firstInput []byte
 
+	for i, arg := range flag.Args() {
+		deps, input := parseDepFile(arg)
 		if i == 0 {
-			mergedDeps = deps
 			firstInput = input
-		} else {
-			mergedDeps.Inputs = append(mergedDeps.Inputs, deps.Inputs...)
 		}
+
+		// Relativize before merging so that inputs that were listed with both an absolute
+		// and a relative path are deduplicated.
+		if root != "" {
+			if outside := deps.Relativize(root); len(outside) > 0 {
+				if !*dropOutside {
+					log.Fatalf("Absolute inputs of %q outside of %q: %s", arg, root, strings.Join(outside, " "))
+				}
+				deps.RemoveInputs(outside)
+			}
+		}
+		allDeps = append(allDeps, deps)
 	}
 
-	new := mergedDeps.Print()
+	mergedDeps := makedeps.Merge(allDeps...)
 
+	if *validate || len(allowedRoots) > 0 {
+		if err := mergedDeps.Validate(allowedRoots); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	new := mergedDeps.Print()
 	if *output == "" || *output == flag.Arg(0) {
 		if !bytes.Equal(firstInput, new) {
 			err := ioutil.WriteFile(flag.Arg(0), new, 0666)
@@ -79,3 +133,17 @@ func main() {
 		}
 	}
 }
+
+// parseDepFile returns the parsed contents of a dependency file, and its raw contents.
+func parseDepFile(path string) (*makedeps.Deps, []byte) {
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Error opening %q: %v", path, err)
+	}
+
+	deps, err := makedeps.Parse(path, bytes.NewBuffer(append([]byte(nil), input...)))
+	if err != nil {
+		log.Fatalf("Failed to parse: %v", err)
+	}
+	return deps, input
+}
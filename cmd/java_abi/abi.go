@@ -0,0 +1,295 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The ABI of a class is the part of it that other code can be compiled against: the class itself
+// unless it is private, local or anonymous, and its non-private fields and methods, with their
+// signatures, annotations, constant values and declared exceptions.  Package private classes and
+// members are part of the ABI, like in the header jars produced by turbine, because dependents in
+// the same package use them and inline their constants.  Method bodies, private members,
+// synthetic members, debug information and the order of members don't affect code compiled
+// against the class, so they are not part of the ABI.
+
+var (
+	classAccessNames = []accessName{
+		{accPublic, "public"}, {accPrivate, "private"}, {accProtected, "protected"},
+		{accStatic, "static"}, {accFinal, "final"}, {accInterface, "interface"},
+		{accAbstract, "abstract"}, {accAnnotation, "annotation"}, {accEnum, "enum"},
+	}
+	fieldAccessNames = []accessName{
+		{accPublic, "public"}, {accProtected, "protected"}, {accStatic, "static"},
+		{accFinal, "final"}, {accEnum, "enum"},
+	}
+	methodAccessNames = []accessName{
+		{accPublic, "public"}, {accProtected, "protected"}, {accStatic, "static"},
+		{accFinal, "final"}, {accAbstract, "abstract"}, {accVarargs, "varargs"},
+	}
+)
+
+type accessName struct {
+	flag uint16
+	name string
+}
+
+func formatAccess(access uint16, names []accessName) string {
+	var s []string
+	for _, n := range names {
+		if access&n.flag != 0 {
+			s = append(s, n.name)
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// withAccess appends the names of the access flags to a line, if there are any.
+func withAccess(line string, access uint16, names []accessName) string {
+	if s := formatAccess(access, names); s != "" {
+		return line + " " + s
+	}
+	return line
+}
+
+// ABI returns the lines that describe the ABI of the class, or nil if the class can't be
+// referenced by other classes.
+func (c *classFile) ABI() ([]string, error) {
+	access := c.access
+	for _, inner := range c.innerClasses() {
+		if inner.name == c.name {
+			if !inner.member {
+				// Local and anonymous classes can't be named outside of the method that declares
+				// them.
+				return nil, nil
+			}
+			// The access flags of a nested class are only recorded in the InnerClasses attribute.
+			access = inner.access | (c.access & (accInterface | accAbstract | accAnnotation | accEnum))
+		}
+	}
+
+	if access&(accPrivate|accSynthetic) != 0 {
+		return nil, nil
+	}
+
+	lines := []string{withAccess("class "+c.name, access, classAccessNames)}
+	if c.super != "" {
+		lines = append(lines, "  extends "+c.super)
+	}
+	for _, iface := range c.interfaces {
+		lines = append(lines, "  implements "+iface)
+	}
+	attributes, err := c.formatAttributes(c.attributes, "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.name, err)
+	}
+	lines = append(lines, attributes...)
+
+	for _, kind := range []struct {
+		name    string
+		members []member
+		access  []accessName
+	}{
+		{"field", c.fields, fieldAccessNames},
+		{"method", c.methods, methodAccessNames},
+	} {
+		members := visibleMembers(kind.members)
+		for _, m := range members {
+			lines = append(lines, withAccess(fmt.Sprintf("  %s %s %s", kind.name, m.name, m.descriptor),
+				m.access, kind.access))
+			attributes, err := c.formatAttributes(m.attributes, "    ")
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s%s: %w", c.name, m.name, m.descriptor, err)
+			}
+			lines = append(lines, attributes...)
+		}
+	}
+
+	return lines, nil
+}
+
+// visibleMembers returns the members that are neither private nor synthetic, sorted by name and
+// descriptor.
+func visibleMembers(members []member) []member {
+	var visible []member
+	for _, m := range members {
+		if m.access&(accPrivate|accSynthetic) == 0 {
+			visible = append(visible, m)
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool {
+		if visible[i].name != visible[j].name {
+			return visible[i].name < visible[j].name
+		}
+		return visible[i].descriptor < visible[j].descriptor
+	})
+	return visible
+}
+
+type innerClass struct {
+	name   string
+	access uint16
+	// member is false for local and anonymous classes, which have no outer class.
+	member bool
+}
+
+func (c *classFile) innerClasses() []innerClass {
+	var inners []innerClass
+	for _, a := range c.attributes {
+		if a.name != "InnerClasses" {
+			continue
+		}
+		r := attributeReader(a)
+		for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+			name := c.className(r.u2())
+			outer := r.u2()
+			r.u2() // inner_name_index
+			inners = append(inners, innerClass{name: name, access: r.u2(), member: outer != 0})
+		}
+	}
+	return inners
+}
+
+// formatAttributes returns the lines for the attributes of a class or member that are part of the
+// ABI.
+func (c *classFile) formatAttributes(attributes []attribute, indent string) ([]string, error) {
+	var lines []string
+	for _, a := range attributes {
+		r := attributeReader(a)
+		switch a.name {
+		case "Signature":
+			lines = append(lines, indent+"signature "+c.utf8(r.u2()))
+		case "ConstantValue":
+			lines = append(lines, indent+"value "+c.formatConstant(r.u2()))
+		case "Exceptions":
+			for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+				lines = append(lines, indent+"throws "+c.className(r.u2()))
+			}
+		case "Deprecated":
+			lines = append(lines, indent+"deprecated")
+		case "AnnotationDefault":
+			lines = append(lines, indent+"default "+c.formatElementValue(r))
+		case "RuntimeVisibleAnnotations", "RuntimeInvisibleAnnotations":
+			for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+				lines = append(lines, indent+"annotation "+c.formatAnnotation(r))
+			}
+		case "RuntimeVisibleParameterAnnotations", "RuntimeInvisibleParameterAnnotations":
+			for p, np := 0, int(r.u1()); p < np && r.err == nil; p++ {
+				for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+					lines = append(lines, fmt.Sprintf("%sparameter %d annotation %s", indent, p, c.formatAnnotation(r)))
+				}
+			}
+		case "RuntimeVisibleTypeAnnotations", "RuntimeInvisibleTypeAnnotations":
+			for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+				target := formatTypeAnnotationTarget(r)
+				lines = append(lines, indent+"type annotation "+target+" "+c.formatAnnotation(r))
+			}
+		case "PermittedSubclasses":
+			for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+				lines = append(lines, indent+"permits "+c.className(r.u2()))
+			}
+		case "Record":
+			for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+				name, descriptor := c.utf8(r.u2()), c.utf8(r.u2())
+				lines = append(lines, indent+"record component "+name+" "+descriptor)
+				attributes, err := c.formatAttributes(c.readAttributes(r), indent+"  ")
+				if err != nil {
+					return nil, err
+				}
+				lines = append(lines, attributes...)
+			}
+		default:
+			// Code, debug information, InnerClasses, EnclosingMethod, NestHost, NestMembers,
+			// MethodParameters and unknown attributes are not part of the ABI.
+			continue
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s attribute: %w", a.name, r.err)
+		}
+	}
+	return lines, nil
+}
+
+// formatConstant formats a constant used as the value of a field or an annotation element.
+func (c *classFile) formatConstant(index uint16) string {
+	if int(index) >= len(c.constants) {
+		return "?"
+	}
+	switch k := c.constants[index]; k.tag {
+	case constantUtf8, constantString:
+		return strconv.Quote(k.value)
+	default:
+		return k.value
+	}
+}
+
+func (c *classFile) formatAnnotation(r *classReader) string {
+	typ := c.utf8(r.u2())
+	var elements []string
+	for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+		name := c.utf8(r.u2())
+		elements = append(elements, name+"="+c.formatElementValue(r))
+	}
+	return typ + "(" + strings.Join(elements, ", ") + ")"
+}
+
+func (c *classFile) formatElementValue(r *classReader) string {
+	switch tag := r.u1(); tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's':
+		return string(tag) + ":" + c.formatConstant(r.u2())
+	case 'e':
+		typ := c.utf8(r.u2())
+		return typ + "." + c.utf8(r.u2())
+	case 'c':
+		return "class:" + c.utf8(r.u2())
+	case '@':
+		return "@" + c.formatAnnotation(r)
+	case '[':
+		var values []string
+		for i, n := 0, int(r.u2()); i < n && r.err == nil; i++ {
+			values = append(values, c.formatElementValue(r))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown element value tag %q", tag)
+		}
+		return ""
+	}
+}
+
+// formatTypeAnnotationTarget formats the target_info and type_path of a type annotation on a
+// class, field or method.  Targets inside of code don't appear in these attributes.
+func formatTypeAnnotationTarget(r *classReader) string {
+	targetType := r.u1()
+	var info []byte
+	switch targetType {
+	case 0x00, 0x01, 0x16:
+		info = r.bytes(1)
+	case 0x10, 0x11, 0x12, 0x17:
+		info = r.bytes(2)
+	case 0x13, 0x14, 0x15:
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected type annotation target 0x%02x", targetType)
+		}
+	}
+	path := r.bytes(2 * int(r.u1()))
+	return fmt.Sprintf("%02x:%x:%x", targetType, info, path)
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// testClass returns a class with public, protected, package private, private and synthetic
+// members.  constant is the value of a public constant, body is the contents of the Code
+// attributes and poolEntriesBefore are unused constants at the start of the constant pool.
+func testClass(constant int32, body []byte, poolEntriesBefore ...string) []byte {
+	b := &classBuilder{
+		access:            accPublic | accSuper,
+		name:              "com/example/Foo",
+		super:             "java/lang/Object",
+		interfaces:        []string{"java/io/Serializable"},
+		poolEntriesBefore: poolEntriesBefore,
+	}
+	annotation := func(typ string, element string, value uint16) []byte {
+		var data []byte
+		data = append(data, u2(1)...) // num_annotations
+		data = append(data, u2(int(b.utf8(typ)))...)
+		data = append(data, u2(1)...) // num_element_value_pairs
+		data = append(data, u2(int(b.utf8(element)))...)
+		data = append(data, 's')
+		data = append(data, u2(int(value))...)
+		return data
+	}
+
+	b.classAttributes = []testAttribute{
+		{"RuntimeVisibleAnnotations", annotation("Lcom/example/Ann;", "value", b.utf8("foo"))},
+		{"SourceFile", u2(int(b.utf8("Foo.java")))},
+	}
+	b.fields = []testMember{
+		{accPublic | accStatic | accFinal, "CONSTANT", "I",
+			[]testAttribute{{"ConstantValue", u2(int(b.integer(constant)))}}},
+		{accPrivate, "secret", "Ljava/lang/String;", nil},
+		{accProtected, "name", "Ljava/lang/String;", nil},
+	}
+	b.methods = []testMember{
+		{accPublic | accSynchronized, "run", "()V", []testAttribute{
+			{"Code", body},
+			{"Exceptions", append(u2(1), u2(int(b.class("java/io/IOException")))...)},
+		}},
+		{accPublic, "<init>", "()V", []testAttribute{{"Code", body}}},
+		{0, "helper", "()V", []testAttribute{{"Code", body}}},
+		{accPublic | accSynthetic | accBridge, "access$000", "()V", nil},
+		{accProtected, "greeting", "()Ljava/lang/String;", []testAttribute{
+			{"Signature", u2(int(b.utf8("()Ljava/lang/String;")))},
+			{"RuntimeInvisibleAnnotations", annotation("Lcom/example/Nullable;", "reason", b.utf8("test"))},
+		}},
+	}
+	return b.bytes()
+}
+
+func classABI(t *testing.T, data []byte) string {
+	t.Helper()
+	class, err := readClassFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, err := class.ABI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestABI(t *testing.T) {
+	got := classABI(t, testClass(1, []byte{1, 2, 3}))
+	want := strings.Join([]string{
+		"class com/example/Foo public",
+		"  extends java/lang/Object",
+		"  implements java/io/Serializable",
+		`  annotation Lcom/example/Ann;(value=s:"foo")`,
+		"  field CONSTANT I public static final",
+		"    value 1",
+		"  field name Ljava/lang/String; protected",
+		"  method <init> ()V public",
+		"  method greeting ()Ljava/lang/String; protected",
+		"    signature ()Ljava/lang/String;",
+		`    annotation Lcom/example/Nullable;(reason=s:"test")`,
+		"  method helper ()V",
+		"  method run ()V public",
+		"    throws java/io/IOException",
+	}, "\n")
+	if got != want {
+		t.Errorf("incorrect ABI\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestABIChanges(t *testing.T) {
+	abi := classABI(t, testClass(1, []byte{1, 2, 3}))
+
+	// Method bodies and the layout of the constant pool are not part of the ABI.
+	if g := classABI(t, testClass(1, []byte{4, 5, 6, 7}, "unused", "constants")); g != abi {
+		t.Errorf("ABI changed with the implementation\nwant:\n%s\ngot:\n%s", abi, g)
+	}
+
+	// Values of constants are inlined into dependents.
+	if g := classABI(t, testClass(2, []byte{1, 2, 3})); g == abi {
+		t.Errorf("ABI didn't change with the value of a constant")
+	}
+}
+
+func TestABIPackagePrivate(t *testing.T) {
+	// Package private classes, fields and constants are used by dependents in the same package.
+	b := &classBuilder{name: "com/example/Internal", super: "java/lang/Object"}
+	b.fields = []testMember{
+		{accStatic | accFinal, "LIMIT", "I",
+			[]testAttribute{{"ConstantValue", u2(int(b.integer(10)))}}},
+	}
+	want := strings.Join([]string{
+		"class com/example/Internal",
+		"  extends java/lang/Object",
+		"  field LIMIT I static final",
+		"    value 10",
+	}, "\n")
+	if g := classABI(t, b.bytes()); g != want {
+		t.Errorf("incorrect ABI for a package private class\nwant:\n%s\ngot:\n%s", want, g)
+	}
+}
+
+// nestedClass returns a class nested in com/example/Foo with the given access flags in its
+// InnerClasses attribute.  outer is false for local and anonymous classes.
+func nestedClass(name string, access uint16, outer bool) []byte {
+	nested := &classBuilder{access: accSuper, name: name, super: "java/lang/Object"}
+	var innerClasses []byte
+	innerClasses = append(innerClasses, u2(1)...)
+	innerClasses = append(innerClasses, u2(int(nested.class(name)))...)
+	if outer {
+		innerClasses = append(innerClasses, u2(int(nested.class("com/example/Foo")))...)
+		innerClasses = append(innerClasses, u2(int(nested.utf8("Nested")))...)
+	} else {
+		innerClasses = append(innerClasses, u2(0)...)
+		innerClasses = append(innerClasses, u2(0)...)
+	}
+	innerClasses = append(innerClasses, u2(int(access))...)
+	nested.classAttributes = []testAttribute{{"InnerClasses", innerClasses}}
+	return nested.bytes()
+}
+
+func TestABINotVisible(t *testing.T) {
+	if g := classABI(t, nestedClass("com/example/Foo$Nested", accPrivate|accStatic, true)); g != "" {
+		t.Errorf("want no ABI for a private nested class, got:\n%s", g)
+	}
+	if g := classABI(t, nestedClass("com/example/Foo$1", 0, false)); g != "" {
+		t.Errorf("want no ABI for an anonymous class, got:\n%s", g)
+	}
+
+	// The access flags of nested classes come from the InnerClasses attribute.
+	want := "class com/example/Foo$Nested protected static\n  extends java/lang/Object"
+	if g := classABI(t, nestedClass("com/example/Foo$Nested", accProtected|accStatic, true)); g != want {
+		t.Errorf("incorrect ABI for a nested class\nwant:\n%s\ngot:\n%s", want, g)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+)
+
+// A minimal reader for the class file format described in chapter 4 of the Java Virtual Machine
+// Specification.  It only keeps the parts of a class that are visible to code compiled against
+// it: the constant pool entries that they reference, the access flags, names, descriptors and the
+// attributes of the class and its fields and methods.  Code attributes are skipped.
+
+const classMagic = 0xCAFEBABE
+
+// Constant pool tags.
+const (
+	constantUtf8               = 1
+	constantInteger            = 3
+	constantFloat              = 4
+	constantLong               = 5
+	constantDouble             = 6
+	constantClass              = 7
+	constantString             = 8
+	constantFieldref           = 9
+	constantMethodref          = 10
+	constantInterfaceMethodref = 11
+	constantNameAndType        = 12
+	constantMethodHandle       = 15
+	constantMethodType         = 16
+	constantDynamic            = 17
+	constantInvokeDynamic      = 18
+	constantModule             = 19
+	constantPackage            = 20
+)
+
+// Access flags.
+const (
+	accPublic       = 0x0001
+	accPrivate      = 0x0002
+	accProtected    = 0x0004
+	accStatic       = 0x0008
+	accFinal        = 0x0010
+	accSuper        = 0x0020
+	accSynchronized = 0x0020
+	accVolatile     = 0x0040
+	accBridge       = 0x0040
+	accTransient    = 0x0080
+	accVarargs      = 0x0080
+	accNative       = 0x0100
+	accInterface    = 0x0200
+	accAbstract     = 0x0400
+	accStrict       = 0x0800
+	accSynthetic    = 0x1000
+	accAnnotation   = 0x2000
+	accEnum         = 0x4000
+)
+
+type constant struct {
+	tag byte
+	// The value of Utf8, Integer, Float, Long, Double, String and Class constants, formatted as
+	// a string.
+	value string
+}
+
+// A classFile is the part of a class that is visible to code compiled against it.
+type classFile struct {
+	access     uint16
+	name       string
+	super      string
+	interfaces []string
+	fields     []member
+	methods    []member
+	attributes []attribute
+
+	constants []constant
+}
+
+// A member is a field or a method.
+type member struct {
+	access     uint16
+	name       string
+	descriptor string
+	attributes []attribute
+}
+
+type attribute struct {
+	name string
+	data []byte
+}
+
+type classReader struct {
+	data []byte
+	err  error
+}
+
+var errTruncated = errors.New("truncated class file")
+
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = errTruncated
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *classReader) u1() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *classReader) u2() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *classReader) u4() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// readClassFile parses a class file.
+func readClassFile(data []byte) (*classFile, error) {
+	r := &classReader{data: data}
+	if r.u4() != classMagic {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, fmt.Errorf("not a class file")
+	}
+	r.u2() // minor_version
+	r.u2() // major_version
+
+	c := &classFile{}
+	if err := c.readConstantPool(r); err != nil {
+		return nil, err
+	}
+
+	c.access = r.u2()
+	c.name = c.className(r.u2())
+	c.super = c.className(r.u2())
+	for i, n := 0, int(r.u2()); i < n; i++ {
+		c.interfaces = append(c.interfaces, c.className(r.u2()))
+	}
+	c.fields = c.readMembers(r)
+	c.methods = c.readMembers(r)
+	c.attributes = c.readAttributes(r)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return c, nil
+}
+
+func (c *classFile) readConstantPool(r *classReader) error {
+	count := int(r.u2())
+	c.constants = make([]constant, count)
+	for i := 1; i < count; i++ {
+		tag := r.u1()
+		c.constants[i].tag = tag
+		switch tag {
+		case constantUtf8:
+			c.constants[i].value = decodeModifiedUtf8(r.bytes(int(r.u2())))
+		case constantInteger:
+			c.constants[i].value = strconv.FormatInt(int64(int32(r.u4())), 10)
+		case constantFloat:
+			f := math.Float32frombits(r.u4())
+			c.constants[i].value = strconv.FormatFloat(float64(f), 'g', -1, 32)
+		case constantLong:
+			hi, lo := r.u4(), r.u4()
+			c.constants[i].value = strconv.FormatInt(int64(uint64(hi)<<32|uint64(lo)), 10)
+			i++
+		case constantDouble:
+			hi, lo := r.u4(), r.u4()
+			d := math.Float64frombits(uint64(hi)<<32 | uint64(lo))
+			c.constants[i].value = strconv.FormatFloat(d, 'g', -1, 64)
+			i++
+		case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+			// The index of a Utf8 constant, which may come later in the pool.
+			c.constants[i].value = strconv.Itoa(int(r.u2()))
+		case constantFieldref, constantMethodref, constantInterfaceMethodref, constantNameAndType,
+			constantDynamic, constantInvokeDynamic:
+			r.bytes(4)
+		case constantMethodHandle:
+			r.bytes(3)
+		default:
+			if r.err != nil {
+				return r.err
+			}
+			return fmt.Errorf("unknown constant pool tag %d at index %d", tag, i)
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+
+	// Resolve the constants that refer to Utf8 constants.
+	for i := range c.constants {
+		switch c.constants[i].tag {
+		case constantClass, constantString, constantMethodType, constantModule, constantPackage:
+			index, _ := strconv.Atoi(c.constants[i].value)
+			c.constants[i].value = c.utf8(uint16(index))
+		}
+	}
+	return nil
+}
+
+// utf8 returns the string value of a Utf8 constant, or "" if index is not a Utf8 constant.
+func (c *classFile) utf8(index uint16) string {
+	if int(index) < len(c.constants) && c.constants[index].tag == constantUtf8 {
+		return c.constants[index].value
+	}
+	return ""
+}
+
+// className returns the name of a Class constant, or "" if index is not a Class constant.
+func (c *classFile) className(index uint16) string {
+	if int(index) < len(c.constants) && c.constants[index].tag == constantClass {
+		return c.constants[index].value
+	}
+	return ""
+}
+
+func (c *classFile) readMembers(r *classReader) []member {
+	count := int(r.u2())
+	members := make([]member, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		members = append(members, member{
+			access:     r.u2(),
+			name:       c.utf8(r.u2()),
+			descriptor: c.utf8(r.u2()),
+			attributes: c.readAttributes(r),
+		})
+	}
+	return members
+}
+
+func (c *classFile) readAttributes(r *classReader) []attribute {
+	count := int(r.u2())
+	attributes := make([]attribute, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		name := c.utf8(r.u2())
+		data := r.bytes(int(r.u4()))
+		attributes = append(attributes, attribute{name: name, data: data})
+	}
+	return attributes
+}
+
+// decodeModifiedUtf8 decodes the modified UTF-8 encoding of class files, which encodes the null
+// character and supplementary characters differently than standard UTF-8.
+func decodeModifiedUtf8(b []byte) string {
+	var units []uint16
+	for i := 0; i < len(b); {
+		switch {
+		case b[i]&0x80 == 0:
+			units = append(units, uint16(b[i]))
+			i++
+		case b[i]&0xe0 == 0xc0 && i+1 < len(b):
+			units = append(units, uint16(b[i]&0x1f)<<6|uint16(b[i+1]&0x3f))
+			i += 2
+		case b[i]&0xf0 == 0xe0 && i+2 < len(b):
+			units = append(units, uint16(b[i]&0x0f)<<12|uint16(b[i+1]&0x3f)<<6|uint16(b[i+2]&0x3f))
+			i += 3
+		default:
+			// Invalid encoding, keep the byte so that different inputs stay different.
+			units = append(units, uint16(b[i]))
+			i++
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// attributeReader returns a reader for the contents of an attribute.
+func attributeReader(a attribute) *classReader {
+	return &classReader{data: a.data}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// classBuilder writes class files for tests.
+type classBuilder struct {
+	pool      bytes.Buffer
+	poolCount uint16
+	indexes   map[string]uint16
+
+	access            uint16
+	name, super       string
+	interfaces        []string
+	fields, methods   []testMember
+	classAttributes   []testAttribute
+	poolEntriesBefore []string
+}
+
+type testMember struct {
+	access           uint16
+	name, descriptor string
+	attributes       []testAttribute
+}
+
+type testAttribute struct {
+	name string
+	data []byte
+}
+
+func u2(v int) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
+
+func (b *classBuilder) constant(key string, write func()) uint16 {
+	if b.indexes == nil {
+		b.indexes = make(map[string]uint16)
+		b.poolCount = 1
+	}
+	if index, ok := b.indexes[key]; ok {
+		return index
+	}
+	write()
+	index := b.poolCount
+	b.indexes[key] = index
+	b.poolCount++
+	return index
+}
+
+func (b *classBuilder) utf8(s string) uint16 {
+	return b.constant("utf8:"+s, func() {
+		b.pool.WriteByte(constantUtf8)
+		b.pool.Write(u2(len(s)))
+		b.pool.WriteString(s)
+	})
+}
+
+func (b *classBuilder) class(name string) uint16 {
+	nameIndex := b.utf8(name)
+	return b.constant("class:"+name, func() {
+		b.pool.WriteByte(constantClass)
+		b.pool.Write(u2(int(nameIndex)))
+	})
+}
+
+func (b *classBuilder) integer(v int32) uint16 {
+	return b.constant(fmt.Sprintf("int:%d", v), func() {
+		b.pool.WriteByte(constantInteger)
+		binary.Write(&b.pool, binary.BigEndian, v)
+	})
+}
+
+func (b *classBuilder) string(s string) uint16 {
+	utf8Index := b.utf8(s)
+	return b.constant("string:"+s, func() {
+		b.pool.WriteByte(constantString)
+		b.pool.Write(u2(int(utf8Index)))
+	})
+}
+
+func (b *classBuilder) bytes() []byte {
+	// Add the unrelated constants first, they shift the indexes of the other constants.
+	for _, s := range b.poolEntriesBefore {
+		b.utf8(s)
+	}
+
+	var body bytes.Buffer
+	body.Write(u2(int(b.access)))
+	body.Write(u2(int(b.class(b.name))))
+	body.Write(u2(int(b.class(b.super))))
+	body.Write(u2(len(b.interfaces)))
+	for _, iface := range b.interfaces {
+		body.Write(u2(int(b.class(iface))))
+	}
+	writeAttributes := func(attributes []testAttribute) {
+		body.Write(u2(len(attributes)))
+		for _, a := range attributes {
+			body.Write(u2(int(b.utf8(a.name))))
+			binary.Write(&body, binary.BigEndian, uint32(len(a.data)))
+			body.Write(a.data)
+		}
+	}
+	for _, members := range [][]testMember{b.fields, b.methods} {
+		body.Write(u2(len(members)))
+		for _, m := range members {
+			body.Write(u2(int(m.access)))
+			body.Write(u2(int(b.utf8(m.name))))
+			body.Write(u2(int(b.utf8(m.descriptor))))
+			writeAttributes(m.attributes)
+		}
+	}
+	writeAttributes(b.classAttributes)
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, uint32(classMagic))
+	out.Write(u2(0))
+	out.Write(u2(52))
+	out.Write(u2(int(b.poolCount)))
+	out.Write(b.pool.Bytes())
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func TestReadClassFileErrors(t *testing.T) {
+	data := testClass(1, nil)
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not a class", []byte("PK\x03\x04"), "not a class file"},
+		{"truncated", data[:len(data)-3], "truncated class file"},
+		{"empty", nil, "truncated class file"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := readClassFile(tc.data)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("want error %q, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestDecodeModifiedUtf8(t *testing.T) {
+	// The null character is encoded in two bytes, supplementary characters as surrogate pairs.
+	in := []byte{'a', 0xc0, 0x80, 0xed, 0xa0, 0xbd, 0xed, 0xb8, 0x80}
+	if g, w := decodeModifiedUtf8(in), "a\x00\U0001F600"; g != w {
+		t.Errorf("want %q, got %q", w, g)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// java_abi writes the ABI of the classes in a jar: the non-private classes, fields and methods
+// with their signatures, annotations and constant values, in a normalized order.  The output only
+// changes when code compiled against the jar may need to be recompiled, so the build can use it
+// with restat to avoid recompiling the dependents of a library when only the implementation of
+// the library changed.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"android/soong/third_party/zip"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: java_abi -o <output> <input jar>")
+		flag.PrintDefaults()
+	}
+	output := flag.String("o", "", "output file")
+	flag.Parse()
+
+	if *output == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	r, err := zip.OpenReader(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	buf := &bytes.Buffer{}
+	if err := writeJarABI(&r.Reader, buf); err != nil {
+		log.Fatalf("%s: %s", flag.Arg(0), err)
+	}
+
+	// Leave the output untouched if the ABI didn't change so that the build can use restat.
+	if existing, err := ioutil.ReadFile(*output); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return
+	}
+	if err := ioutil.WriteFile(*output, buf.Bytes(), 0666); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeJarABI writes the ABI of the classes in a jar, sorted by class name.
+func writeJarABI(r *zip.Reader, w io.Writer) error {
+	files := make(map[string]*zip.File)
+	var names []string
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, ".class") || strings.HasSuffix(f.Name, "module-info.class") {
+			continue
+		}
+		// Like the classpath, use the first class with a name.
+		if _, exists := files[f.Name]; !exists {
+			files[f.Name] = f
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rc, err := files[name].Open()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		class, err := readClassFile(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		lines, err := class.ABI()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"android/soong/third_party/zip"
+)
+
+func TestWriteJarABI(t *testing.T) {
+	class := func(name string, access uint16) []byte {
+		b := &classBuilder{access: access, name: name, super: "java/lang/Object"}
+		return b.bytes()
+	}
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{"com/example/B.class", class("com/example/B", accPublic)},
+		{"com/example/C.class", class("com/example/C", 0)},
+		{"com/example/Hidden.class", class("com/example/Hidden", accSynthetic)},
+		{"META-INF/MANIFEST.MF", []byte("Manifest-Version: 1.0\n")},
+		{"com/example/A.class", class("com/example/A", accPublic|accFinal)},
+		{"module-info.class", []byte("not parsed")},
+		// Only the first class with a name is used, like on a classpath.
+		{"com/example/A.class", class("com/example/A", accPublic)},
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(e.data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := writeJarABI(r, out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"class com/example/A public final",
+		"  extends java/lang/Object",
+		"class com/example/B public",
+		"  extends java/lang/Object",
+		"class com/example/C",
+		"  extends java/lang/Object",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("incorrect ABI\nwant:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// A ConflictReport lists the files that were found in more than one input zip.  Duplicate
+// directories are not reported.
+type ConflictReport struct {
+	Conflicts []*Conflict `json:"conflicts"`
+
+	byPath map[string]*Conflict
+}
+
+// A Conflict is a file that was found in more than one input zip.
+type Conflict struct {
+	Path string `json:"path"`
+
+	// Identical is true if all of the entries have the same CRC and size.
+	Identical bool `json:"identical"`
+
+	// Winner is the source of the entry that was written to the output zip, which is always the
+	// first one.
+	Winner string `json:"winner"`
+
+	Entries []ConflictEntry `json:"entries"`
+}
+
+// A ConflictEntry is one of the entries for the path of a Conflict, in the order they were found.
+type ConflictEntry struct {
+	Source string `json:"source"`
+	CRC32  string `json:"crc32"`
+	Size   uint64 `json:"size"`
+}
+
+func newConflictReport() *ConflictReport {
+	return &ConflictReport{byPath: make(map[string]*Conflict)}
+}
+
+// add records that entry was found for path after existing was already added.
+func (r *ConflictReport) add(path string, existing, entry ZipEntryContents) {
+	conflict := r.byPath[path]
+	if conflict == nil {
+		conflict = &Conflict{
+			Path:      path,
+			Identical: true,
+			Winner:    conflictSource(existing),
+			Entries:   []ConflictEntry{newConflictEntry(existing)},
+		}
+		r.byPath[path] = conflict
+		r.Conflicts = append(r.Conflicts, conflict)
+	}
+	conflict.Entries = append(conflict.Entries, newConflictEntry(entry))
+	if entry.CRC32() != existing.CRC32() || entry.Size() != existing.Size() {
+		conflict.Identical = false
+	}
+}
+
+func newConflictEntry(entry ZipEntryContents) ConflictEntry {
+	return ConflictEntry{
+		Source: conflictSource(entry),
+		CRC32:  fmt.Sprintf("%08x", entry.CRC32()),
+		Size:   entry.Size(),
+	}
+}
+
+// conflictSource returns the name of the input zip that an entry came from.
+func conflictSource(entry ZipEntryContents) string {
+	if ze, ok := entry.(*ZipEntryFromZip); ok {
+		return ze.inputZip.Name()
+	}
+	return entry.String()
+}
+
+func (r *ConflictReport) sorted() []*Conflict {
+	conflicts := append([]*Conflict(nil), r.Conflicts...)
+	sort.SliceStable(conflicts, func(i, j int) bool { return conflicts[i].Path < conflicts[j].Path })
+	return conflicts
+}
+
+// WriteJSON writes the report as JSON, sorted by path.
+func (r *ConflictReport) WriteJSON(w io.Writer) error {
+	conflicts := r.sorted()
+	if conflicts == nil {
+		conflicts = []*Conflict{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Conflicts []*Conflict `json:"conflicts"`
+	}{conflicts}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteTable writes the report as a table with a row for each entry, sorted by path.
+func (r *ConflictReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tIDENTICAL\tSOURCE\tCRC32\tSIZE\tWINNER")
+	for _, conflict := range r.sorted() {
+		for i, entry := range conflict.Entries {
+			path, identical, winner := "", "", ""
+			if i == 0 {
+				path = conflict.Path
+				identical = "no"
+				if conflict.Identical {
+					identical = "yes"
+				}
+				winner = "*"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", path, identical, entry.Source, entry.CRC32, entry.Size, winner)
+		}
+	}
+	return tw.Flush()
+}
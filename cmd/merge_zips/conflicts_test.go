@@ -0,0 +1,141 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"android/soong/third_party/zip"
+)
+
+func crc(data []byte) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+}
+
+func TestConflictReport(t *testing.T) {
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{bDir, bc, a}},
+		&testInputZip{name: "in1", entries: []testZipEntry{bDir, bc, a2}},
+		&testInputZip{name: "in2", entries: []testZipEntry{a3, ba}},
+	}
+
+	conflicts := newConflictReport()
+	writer := zip.NewWriter(&bytes.Buffer{})
+	err := mergeZips(inputZips, writer, "", "",
+		false, false, false, false, true,
+		nil, nil, nil, conflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	json := &bytes.Buffer{}
+	if err := conflicts.WriteJSON(json); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{
+  "conflicts": [
+    {
+      "path": "a",
+      "identical": false,
+      "winner": "in0",
+      "entries": [
+        {
+          "source": "in0",
+          "crc32": "` + crc(a.data) + `",
+          "size": 3
+        },
+        {
+          "source": "in1",
+          "crc32": "` + crc(a2.data) + `",
+          "size": 4
+        },
+        {
+          "source": "in2",
+          "crc32": "` + crc(a3.data) + `",
+          "size": 4
+        }
+      ]
+    },
+    {
+      "path": "b/c",
+      "identical": true,
+      "winner": "in0",
+      "entries": [
+        {
+          "source": "in0",
+          "crc32": "` + crc(bc.data) + `",
+          "size": 3
+        },
+        {
+          "source": "in1",
+          "crc32": "` + crc(bc.data) + `",
+          "size": 3
+        }
+      ]
+    }
+  ]
+}
+`
+	if json.String() != wantJSON {
+		t.Errorf("incorrect JSON report\nwant:\n%s\ngot:\n%s", wantJSON, json.String())
+	}
+
+	table := &bytes.Buffer{}
+	if err := conflicts.WriteTable(table); err != nil {
+		t.Fatal(err)
+	}
+	wantTable := strings.Join([]string{
+		"PATH  IDENTICAL  SOURCE  CRC32     SIZE  WINNER",
+		"a     no         in0     " + crc(a.data) + "  3     *",
+		"                 in1     " + crc(a2.data) + "  4     ",
+		"                 in2     " + crc(a3.data) + "  4     ",
+		"b/c   yes        in0     " + crc(bc.data) + "  3     *",
+		"                 in1     " + crc(bc.data) + "  3     ",
+		"",
+	}, "\n")
+	if table.String() != wantTable {
+		t.Errorf("incorrect table report\nwant:\n%s\ngot:\n%s", wantTable, table.String())
+	}
+}
+
+func TestConflictReportDuplicateError(t *testing.T) {
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{a}},
+		&testInputZip{name: "in1", entries: []testZipEntry{a2}},
+	}
+
+	// The conflict that causes the error is in the report.
+	conflicts := newConflictReport()
+	writer := zip.NewWriter(&bytes.Buffer{})
+	err := mergeZips(inputZips, writer, "", "",
+		false, false, false, false, false,
+		nil, nil, nil, conflicts)
+	writer.Close()
+	if err == nil || !strings.Contains(err.Error(), "Duplicate path") {
+		t.Fatalf("want duplicate path error, got %v", err)
+	}
+
+	if len(conflicts.Conflicts) != 1 {
+		t.Fatalf("want 1 conflict, got %d", len(conflicts.Conflicts))
+	}
+	if c := conflicts.Conflicts[0]; c.Path != "a" || c.Identical || len(c.Entries) != 2 {
+		t.Errorf("incorrect conflict %+v", *c)
+	}
+}
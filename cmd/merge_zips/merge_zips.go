@@ -147,6 +147,7 @@ type OutputZip struct {
 	excludeDirs      []string
 	excludeFiles     []string
 	sourceByDest     map[string]ZipEntryContents
+	conflicts        *ConflictReport
 }
 
 func NewOutputZip(outputWriter *zip.Writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates bool) *OutputZip {
@@ -292,6 +293,10 @@ func (oz *OutputZip) copyEntry(inputZip InputZip, index int) error {
 			entry.name, existingEntry, entry)
 	}
 
+	if oz.conflicts != nil && !entry.IsDir() {
+		oz.conflicts.add(entry.name, existingEntry, entry)
+	}
+
 	if oz.ignoreDuplicates ||
 		// Skip manifest and module info files that are not from the first input file
 		(oz.emulateJar && entry.name == jar.ManifestFile || entry.name == jar.ModuleInfoClass) ||
@@ -533,9 +538,10 @@ func (miz *ManagedInputZip) Entries() []*zip.File {
 // Actual processing.
 func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string,
 	sortEntries, emulateJar, emulatePar, stripDirEntries, ignoreDuplicates bool,
-	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool) error {
+	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool, conflicts *ConflictReport) error {
 
 	out := NewOutputZip(writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates)
+	out.conflicts = conflicts
 	out.setExcludeFiles(excludeFiles)
 	out.setExcludeDirs(excludeDirs)
 	if manifest != "" {
@@ -627,6 +633,8 @@ var (
 	pyMain           = flag.String("pm", "", "__main__.py file to insert in par")
 	prefix           = flag.String("prefix", "", "A file to prefix to the zip file")
 	ignoreDuplicates = flag.Bool("ignore-duplicates", false, "take each entry from the first zip it exists in and don't warn")
+	conflictsJSON    = flag.String("conflicts-json", "", "write a JSON report of the files found in more than one input zip to this file")
+	conflictsTable   = flag.String("conflicts-table", "", "write a table of the files found in more than one input zip to this file")
 )
 
 func init() {
@@ -752,10 +760,39 @@ func main() {
 	for i, input := range inputs {
 		inputZips[i] = inputZipsManager.Manage(&FileInputZip{name: input})
 	}
+	var conflicts *ConflictReport
+	if *conflictsJSON != "" || *conflictsTable != "" {
+		conflicts = newConflictReport()
+	}
 	err = mergeZips(inputZips, writer, *manifest, *pyMain, *sortEntries, *emulateJar, *emulatePar,
 		*stripDirEntries, *ignoreDuplicates, []string(excludeFiles), []string(excludeDirs),
-		map[string]bool(zipsToNotStrip))
+		map[string]bool(zipsToNotStrip), conflicts)
+
+	// Write the conflict reports even if merging failed, they explain duplicate path errors.
+	if *conflictsJSON != "" {
+		if reportErr := writeConflictReport(*conflictsJSON, conflicts.WriteJSON); reportErr != nil {
+			log.Fatal(reportErr)
+		}
+	}
+	if *conflictsTable != "" {
+		if reportErr := writeConflictReport(*conflictsTable, conflicts.WriteTable); reportErr != nil {
+			log.Fatal(reportErr)
+		}
+	}
+
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+func writeConflictReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,226 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+// A mappings zip contains the mapping files and the merged keep rules of the modules of a build,
+// indexed by the build ID of each mapping file in index.json:
+//
+//	index.json
+//	<module>/<id>/proguard_dictionary
+//	<module>/<id>/proguard_configuration.txt
+
+const indexFile = "index.json"
+
+// An Index is the contents of index.json.
+type Index struct {
+	Mappings []IndexEntry `json:"mappings"`
+}
+
+// An IndexEntry is the mapping file of a module.
+type IndexEntry struct {
+	// ID is the build ID of the mapping file, which is the pg_map_id from its header or the first
+	// 16 hex digits of its SHA-256 if it doesn't have one.
+	ID     string `json:"id"`
+	Module string `json:"module"`
+
+	// Mapping and KeepRules are the paths of the mapping file and the merged keep rules in the zip.
+	Mapping   string `json:"mapping"`
+	KeepRules string `json:"keep_rules,omitempty"`
+}
+
+// An indexInput is a line of the list file passed to -build-index: the name of a module, the path
+// of its mapping file and optionally the path of its merged keep rules.
+type indexInput struct {
+	module, mapping, keepRules string
+}
+
+func readIndexInputs(r io.Reader) ([]indexInput, error) {
+	var inputs []indexInput
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		switch len(fields) {
+		case 0:
+			continue
+		case 2:
+			inputs = append(inputs, indexInput{module: fields[0], mapping: fields[1]})
+		case 3:
+			inputs = append(inputs, indexInput{module: fields[0], mapping: fields[1], keepRules: fields[2]})
+		default:
+			return nil, fmt.Errorf("line %d: expected <module> <mapping> [<keep rules>], got %q",
+				lineNum, scanner.Text())
+		}
+	}
+	return inputs, scanner.Err()
+}
+
+// mappingID returns the build ID of a mapping file.
+func mappingID(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			// The header ends at the first class.
+			break
+		}
+		if match := mapIDLine.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// writeIndex writes a mappings zip for the inputs.  Modules with more than one variant that have
+// identical mapping files are only written once.
+func writeIndex(inputs []indexInput, readFile func(string) ([]byte, error), w io.Writer) error {
+	index := Index{Mappings: []IndexEntry{}}
+	files := make(map[string][]byte)
+	for _, input := range inputs {
+		data, err := readFile(input.mapping)
+		if err != nil {
+			return err
+		}
+		entry := IndexEntry{ID: mappingID(data), Module: input.module}
+		dir := path.Join(entry.Module, entry.ID)
+		entry.Mapping = path.Join(dir, "proguard_dictionary")
+		if _, exists := files[entry.Mapping]; exists {
+			continue
+		}
+		files[entry.Mapping] = data
+
+		if input.keepRules != "" {
+			keepRules, err := readFile(input.keepRules)
+			if err != nil {
+				return err
+			}
+			entry.KeepRules = path.Join(dir, "proguard_configuration.txt")
+			files[entry.KeepRules] = keepRules
+		}
+		index.Mappings = append(index.Mappings, entry)
+	}
+	sort.Slice(index.Mappings, func(i, j int) bool {
+		a, b := index.Mappings[i], index.Mappings[j]
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.ID < b.ID
+	})
+
+	indexJSON, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	write := func(name string, data []byte) error {
+		fh := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		fh.SetModTime(jar.DefaultTime)
+		fh.SetMode(0644)
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+
+	if err := write(indexFile, append(indexJSON, '\n')); err != nil {
+		return err
+	}
+	for _, entry := range index.Mappings {
+		if err := write(entry.Mapping, files[entry.Mapping]); err != nil {
+			return err
+		}
+		if entry.KeepRules != "" {
+			if err := write(entry.KeepRules, files[entry.KeepRules]); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// readIndex reads index.json from a mappings zip.
+func readIndex(r *zip.Reader) (*Index, error) {
+	data, err := readZipFile(r, indexFile)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("%s: %w", indexFile, err)
+	}
+	return index, nil
+}
+
+// find returns the entry of the index with a build ID or for a module.  Either may be empty, but
+// the entry must be unique.
+func (index *Index) find(id, module string) (*IndexEntry, error) {
+	var matches []*IndexEntry
+	for i := range index.Mappings {
+		entry := &index.Mappings[i]
+		if (id == "" || entry.ID == id) && (module == "" || entry.Module == module) {
+			matches = append(matches, entry)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no mapping for build ID %q and module %q", id, module)
+	case 1:
+		return matches[0], nil
+	default:
+		var found []string
+		for _, entry := range matches {
+			found = append(found, entry.Module+" "+entry.ID)
+		}
+		return nil, fmt.Errorf("%d mappings match build ID %q and module %q, use -id or -module to choose one of:\n  %s",
+			len(matches), id, module, strings.Join(found, "\n  "))
+	}
+}
+
+func readZipFile(r *zip.Reader, name string) ([]byte, error) {
+	for _, f := range r.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return ioutil.ReadAll(rc)
+		}
+	}
+	return nil, fmt.Errorf("missing %s", name)
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"android/soong/third_party/zip"
+)
+
+func TestReadIndexInputs(t *testing.T) {
+	inputs, err := readIndexInputs(strings.NewReader("Foo out/Foo/proguard_dictionary out/Foo/proguard_configuration.txt\n\nBar out/Bar/proguard_dictionary\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []indexInput{
+		{"Foo", "out/Foo/proguard_dictionary", "out/Foo/proguard_configuration.txt"},
+		{"Bar", "out/Bar/proguard_dictionary", ""},
+	}
+	if fmt.Sprint(inputs) != fmt.Sprint(want) {
+		t.Errorf("want inputs %v, got %v", want, inputs)
+	}
+
+	if _, err := readIndexInputs(strings.NewReader("Foo\n")); err == nil {
+		t.Errorf("want error for a line without a mapping")
+	}
+}
+
+func TestMappingID(t *testing.T) {
+	if g, w := mappingID([]byte(testMapping)), "5b46dfa"; g != w {
+		t.Errorf("want pg_map_id %q, got %q", w, g)
+	}
+
+	// The pg_map_id is only read from the header.
+	noHeader := []byte("com.example.Foo -> a.a:\n# pg_map_id: 1234567\n")
+	if g := mappingID(noHeader); len(g) != 16 || g == "1234567" {
+		t.Errorf("want a hash for a mapping without a pg_map_id, got %q", g)
+	}
+	if mappingID(noHeader) != mappingID(append([]byte(nil), noHeader...)) {
+		t.Errorf("hashes of identical mappings differ")
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	other := "com.example.Baz -> a.a:\n    void run() -> a\n"
+	files := map[string][]byte{
+		"Foo/dict":   []byte(testMapping),
+		"Foo/config": []byte("-keep class com.example.Foo\n"),
+		"Baz/dict":   []byte(other),
+	}
+	readFile := func(name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return data, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	inputs := []indexInput{
+		{"Foo", "Foo/dict", "Foo/config"},
+		{"Baz", "Baz/dict", ""},
+		// A second variant with the same mapping is only written once.
+		{"Foo", "Foo/dict", "Foo/config"},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeIndex(inputs, readFile, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	bazID := mappingID([]byte(other))
+	wantNames := []string{
+		"index.json",
+		"Baz/" + bazID + "/proguard_dictionary",
+		"Foo/5b46dfa/proguard_dictionary",
+		"Foo/5b46dfa/proguard_configuration.txt",
+	}
+	if fmt.Sprint(names) != fmt.Sprint(wantNames) {
+		t.Errorf("want entries %v, got %v", wantNames, names)
+	}
+
+	index, err := readIndex(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndex := []IndexEntry{
+		{ID: bazID, Module: "Baz", Mapping: "Baz/" + bazID + "/proguard_dictionary"},
+		{ID: "5b46dfa", Module: "Foo", Mapping: "Foo/5b46dfa/proguard_dictionary",
+			KeepRules: "Foo/5b46dfa/proguard_configuration.txt"},
+	}
+	if fmt.Sprint(index.Mappings) != fmt.Sprint(wantIndex) {
+		t.Errorf("want index %v, got %v", wantIndex, index.Mappings)
+	}
+
+	entry, err := index.find("5b46dfa", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := readZipFile(r, entry.Mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testMapping {
+		t.Errorf("incorrect mapping file in zip")
+	}
+
+	if entry, err := index.find("", "Baz"); err != nil || entry.ID != bazID {
+		t.Errorf("want entry for module Baz, got %v, %v", entry, err)
+	}
+	if _, err := index.find("", ""); err == nil || !strings.Contains(err.Error(), "2 mappings match") {
+		t.Errorf("want ambiguous mapping error, got %v", err)
+	}
+	if _, err := index.find("missing", ""); err == nil || !strings.Contains(err.Error(), "no mapping") {
+		t.Errorf("want missing mapping error, got %v", err)
+	}
+}
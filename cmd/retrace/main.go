@@ -0,0 +1,140 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// retrace symbolizes stack traces of code that was obfuscated and optimized by R8, using either a
+// mapping file or the mappings zip of a build, which indexes the mapping files of all modules by
+// build ID.  It also writes the mappings zip for the build.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"android/soong/third_party/zip"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: retrace -index <mappings zip> [-id <build id>] [-module <module>] [<stack trace>]")
+		fmt.Fprintln(os.Stderr, "       retrace -index <mappings zip> -list")
+		fmt.Fprintln(os.Stderr, "       retrace -mapping <mapping file> [<stack trace>]")
+		fmt.Fprintln(os.Stderr, "       retrace -build-index -o <mappings zip> -l <list file>")
+		fmt.Fprintln(os.Stderr, "The stack trace is read from stdin if no file is given.")
+		flag.PrintDefaults()
+	}
+
+	indexZip := flag.String("index", "", "mappings zip of a build")
+	id := flag.String("id", "", "build ID of the mapping file in the mappings zip")
+	module := flag.String("module", "", "module of the mapping file in the mappings zip")
+	list := flag.Bool("list", false, "list the mapping files in the mappings zip")
+	mappingFile := flag.String("mapping", "", "mapping file")
+
+	buildIndex := flag.Bool("build-index", false, "write a mappings zip")
+	output := flag.String("o", "", "mappings zip to write with -build-index")
+	inputList := flag.String("l", "", "file listing a module, a mapping file and optionally keep rules per line, for -build-index")
+	flag.Parse()
+
+	if *buildIndex {
+		if *output == "" || *inputList == "" || flag.NArg() != 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if err := writeIndexFile(*output, *inputList); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if (*indexZip == "") == (*mappingFile == "") || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var mapping *Mapping
+	if *mappingFile != "" {
+		f, err := os.Open(*mappingFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mapping, err = ParseMapping(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: %s", *mappingFile, err)
+		}
+	} else {
+		r, err := zip.OpenReader(*indexZip)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer r.Close()
+		index, err := readIndex(&r.Reader)
+		if err != nil {
+			log.Fatalf("%s: %s", *indexZip, err)
+		}
+		if *list {
+			for _, entry := range index.Mappings {
+				fmt.Printf("%s %s\n", entry.Module, entry.ID)
+			}
+			return
+		}
+		entry, err := index.find(*id, *module)
+		if err != nil {
+			log.Fatalf("%s: %s", *indexZip, err)
+		}
+		data, err := readZipFile(&r.Reader, entry.Mapping)
+		if err != nil {
+			log.Fatalf("%s: %s", *indexZip, err)
+		}
+		mapping, err = ParseMapping(bytes.NewReader(data))
+		if err != nil {
+			log.Fatalf("%s: %s: %s", *indexZip, entry.Mapping, err)
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	if err := Retrace(mapping, in, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeIndexFile(output, inputList string) error {
+	f, err := os.Open(inputList)
+	if err != nil {
+		return err
+	}
+	inputs, err := readIndexInputs(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", inputList, err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeIndex(inputs, ioutil.ReadFile, buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, buf.Bytes(), 0666)
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Mapping is an R8 or ProGuard mapping file, which maps the obfuscated names of classes, fields
+// and methods and the line numbers of the optimized code back to the original ones:
+//
+//	# pg_map_id: 5b46dfa
+//	com.example.Foo -> a.a:
+//	# {"id":"sourceFile","fileName":"Foo.kt"}
+//	    int count -> a
+//	    1:4:void run(java.lang.String):20:23 -> b
+//	    5:5:void com.example.Bar.helper():30:30 -> b
+//	    5:5:void run(java.lang.String):24 -> b
+//
+// Consecutive method lines with the same obfuscated name and line range describe methods that
+// were inlined into the obfuscated method, starting with the innermost one.
+type Mapping struct {
+	// ID is the pg_map_id from the header of the mapping file, if there is one.
+	ID string
+
+	classes    map[string]*mappedClass
+	byOriginal map[string]*mappedClass
+}
+
+type mappedClass struct {
+	original   string
+	obfuscated string
+
+	// sourceFile is the original source file of the class if the mapping file records it.
+	sourceFile string
+
+	methods map[string][]*mappedMethod
+}
+
+type mappedMethod struct {
+	// class is the original class of the method if it was inlined from another class, otherwise
+	// it is empty.
+	class string
+	name  string
+
+	// startLine and endLine are the range of obfuscated line numbers of the method, or 0 if the
+	// mapping has no line numbers.
+	startLine, endLine int
+
+	// originalStartLine and originalEndLine are the original line numbers of the range, or 0 if
+	// they are the same as the obfuscated line numbers.
+	originalStartLine, originalEndLine int
+}
+
+var (
+	classLine  = regexp.MustCompile(`^(\S+) -> (\S+):$`)
+	methodLine = regexp.MustCompile(`^\s+(?:(\d+):(\d+):)?\S+ ([^\s(]+)\([^)]*\)(?::(\d+)(?::(\d+))?)? -> (\S+)$`)
+	fieldLine  = regexp.MustCompile(`^\s+\S+ \S+ -> \S+$`)
+	mapIDLine  = regexp.MustCompile(`^#\s*pg_map_id:\s*(\S+)`)
+)
+
+// ParseMapping parses an R8 or ProGuard mapping file.
+func ParseMapping(r io.Reader) (*Mapping, error) {
+	m := &Mapping{
+		classes:    make(map[string]*mappedClass),
+		byOriginal: make(map[string]*mappedClass),
+	}
+
+	var class *mappedClass
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			if match := mapIDLine.FindStringSubmatch(trimmed); match != nil && class == nil {
+				m.ID = match[1]
+			} else if class != nil {
+				class.parseMetadata(strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			}
+			continue
+		}
+
+		if match := classLine.FindStringSubmatch(line); match != nil {
+			class = &mappedClass{
+				original:   match[1],
+				obfuscated: match[2],
+				methods:    make(map[string][]*mappedMethod),
+			}
+			m.classes[class.obfuscated] = class
+			m.byOriginal[class.original] = class
+			continue
+		}
+
+		if class == nil {
+			return nil, fmt.Errorf("line %d: member outside of a class: %q", lineNum, line)
+		}
+
+		if match := methodLine.FindStringSubmatch(line); match != nil {
+			method := &mappedMethod{
+				name:              match[3],
+				startLine:         atoi(match[1]),
+				endLine:           atoi(match[2]),
+				originalStartLine: atoi(match[4]),
+				originalEndLine:   atoi(match[5]),
+			}
+			if i := strings.LastIndex(method.name, "."); i >= 0 {
+				method.class, method.name = method.name[:i], method.name[i+1:]
+			}
+			class.methods[match[6]] = append(class.methods[match[6]], method)
+		} else if !fieldLine.MatchString(line) {
+			// Fields don't appear in stack traces, they are only checked.
+			return nil, fmt.Errorf("line %d: unrecognized mapping: %q", lineNum, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseMetadata records the JSON metadata that R8 writes in comments after a class line.
+func (c *mappedClass) parseMetadata(comment string) {
+	if !strings.HasPrefix(comment, "{") {
+		return
+	}
+	var metadata struct {
+		ID       string `json:"id"`
+		FileName string `json:"fileName"`
+	}
+	if err := json.Unmarshal([]byte(comment), &metadata); err != nil {
+		// Metadata that can't be parsed only loses precision, don't fail the whole mapping.
+		return
+	}
+	if metadata.ID == "sourceFile" && metadata.FileName != "" {
+		c.sourceFile = metadata.FileName
+	}
+}
+
+func atoi(s string) int {
+	if s == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// OriginalClass returns the original name of an obfuscated class, or the name itself if it isn't
+// in the mapping.
+func (m *Mapping) OriginalClass(obfuscated string) string {
+	if class := m.classes[obfuscated]; class != nil {
+		return class.original
+	}
+	return obfuscated
+}
+
+// sourceFile returns the source file of an original class, using the name of the outermost class
+// if the mapping doesn't record it.
+func (m *Mapping) sourceFile(original string) string {
+	if class := m.byOriginal[original]; class != nil && class.sourceFile != "" {
+		return class.sourceFile
+	}
+	name := original[strings.LastIndex(original, ".")+1:]
+	if i := strings.Index(name, "$"); i > 0 {
+		name = name[:i]
+	}
+	return name + ".java"
+}
+
+// hasLines returns true if the method has a range of obfuscated line numbers.
+func (method *mappedMethod) hasLines() bool {
+	return method.startLine != 0 || method.endLine != 0
+}
+
+// originalLine returns the original line number for an obfuscated line number in the range of
+// the method.
+func (method *mappedMethod) originalLine(line int) int {
+	if !method.hasLines() || method.originalStartLine == 0 {
+		return line
+	}
+	if method.originalEndLine-method.originalStartLine == method.endLine-method.startLine {
+		return method.originalStartLine + line - method.startLine
+	}
+	// The range maps to a single original line, which is the case for the call sites of inlined
+	// methods.
+	return method.originalStartLine
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMapping = `# compiler: R8
+# pg_map_id: 5b46dfa
+com.example.Foo -> a.a:
+# {"id":"sourceFile","fileName":"Foo.kt"}
+    int count -> a
+    1:4:void run(java.lang.String):20:23 -> b
+    5:5:void com.example.Bar.helper():30:30 -> b
+    5:5:void run(java.lang.String):24 -> b
+    void first() -> c
+    void second(int) -> c
+com.example.Bar -> a.b:
+    1:1:void helper():30:30 -> a
+com.example.Foo$Inner -> a.c:
+    void call() -> a
+`
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := m.ID, "5b46dfa"; g != w {
+		t.Errorf("want ID %q, got %q", w, g)
+	}
+	if g, w := m.OriginalClass("a.a"), "com.example.Foo"; g != w {
+		t.Errorf("want class %q, got %q", w, g)
+	}
+	if g, w := m.OriginalClass("z.z"), "z.z"; g != w {
+		t.Errorf("want unmapped class %q, got %q", w, g)
+	}
+
+	foo := m.classes["a.a"]
+	if g, w := len(foo.methods["b"]), 3; g != w {
+		t.Fatalf("want %d methods named b, got %d", w, g)
+	}
+	inlined := foo.methods["b"][1]
+	if inlined.class != "com.example.Bar" || inlined.name != "helper" {
+		t.Errorf("want inlined method com.example.Bar.helper, got %s.%s", inlined.class, inlined.name)
+	}
+
+	for _, tc := range []struct {
+		class, want string
+	}{
+		{"com.example.Foo", "Foo.kt"},
+		{"com.example.Bar", "Bar.java"},
+		{"com.example.Foo$Inner", "Foo.java"},
+	} {
+		if g := m.sourceFile(tc.class); g != tc.want {
+			t.Errorf("%s: want source file %q, got %q", tc.class, tc.want, g)
+		}
+	}
+}
+
+func TestParseMappingError(t *testing.T) {
+	for _, tc := range []struct {
+		name, mapping, wantErr string
+	}{
+		{"member outside class", "    void run() -> a\n", "line 1: member outside of a class"},
+		{"unrecognized", "com.example.Foo -> a.a:\n    what is this\n", "line 2: unrecognized mapping"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMapping(strings.NewReader(tc.mapping))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("want error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOriginalLine(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		method mappedMethod
+		line   int
+		want   int
+	}{
+		{"range", mappedMethod{startLine: 1, endLine: 4, originalStartLine: 20, originalEndLine: 23}, 3, 22},
+		{"single original line", mappedMethod{startLine: 5, endLine: 5, originalStartLine: 24}, 5, 24},
+		{"collapsed range", mappedMethod{startLine: 1, endLine: 4, originalStartLine: 20, originalEndLine: 20}, 3, 20},
+		{"no original lines", mappedMethod{startLine: 1, endLine: 4}, 3, 3},
+		{"no lines", mappedMethod{}, 7, 7},
+	} {
+		if g := tc.method.originalLine(tc.line); g != tc.want {
+			t.Errorf("%s: want line %d, got %d", tc.name, tc.want, g)
+		}
+	}
+}
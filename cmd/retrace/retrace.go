@@ -0,0 +1,150 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// frameRegexp matches a frame of a Java stack trace, for example "	at a.a.b(SourceFile:12)".
+	frameRegexp = regexp.MustCompile(`^(\s*at (?:\S+/)?)([^\s(/]+)\.([^\s.(/]+)\(([^)]*)\)(.*)$`)
+
+	// exceptionRegexp matches the line of a stack trace that starts with the class of an exception,
+	// for example "Caused by: a.b: message".
+	exceptionRegexp = regexp.MustCompile(`^(\s*(?:Caused by: |Suppressed: |Exception in thread "[^"]*" )?)([\w$]+(?:\.[\w$]+)+)(:.*)?$`)
+)
+
+// Retrace copies the stack traces read from r to w, replacing the obfuscated classes, methods and
+// line numbers with the original ones from the mapping.  A frame of an obfuscated method that
+// inlined other methods is replaced with a frame for each of them.  A frame that matches more
+// than one original method is followed by the alternatives prefixed with "<OR>".
+func Retrace(m *Mapping, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	bw := bufio.NewWriter(w)
+	for scanner.Scan() {
+		for _, line := range m.retraceLine(scanner.Text()) {
+			fmt.Fprintln(bw, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (m *Mapping) retraceLine(line string) []string {
+	if match := frameRegexp.FindStringSubmatch(line); match != nil {
+		return m.retraceFrame(match[1], match[2], match[3], match[4], match[5])
+	}
+	if match := exceptionRegexp.FindStringSubmatch(line); match != nil {
+		return []string{match[1] + m.OriginalClass(match[2]) + match[3]}
+	}
+	return []string{line}
+}
+
+func (m *Mapping) retraceFrame(prefix, className, methodName, source, suffix string) []string {
+	frame := func(class, method, source string) string {
+		return prefix + class + "." + method + "(" + source + ")" + suffix
+	}
+
+	class := m.classes[className]
+	if class == nil {
+		return []string{frame(className, methodName, source)}
+	}
+
+	file, line := source, 0
+	if i := strings.LastIndex(source, ":"); i >= 0 {
+		if n, err := strconv.Atoi(source[i+1:]); err == nil {
+			file, line = source[:i], n
+		}
+	}
+
+	methods := class.lookupMethods(methodName, line)
+	if len(methods.methods) == 0 {
+		return []string{frame(class.original, methodName, source)}
+	}
+
+	var lines []string
+	for i, method := range methods.methods {
+		originalClass := class.original
+		if method.class != "" {
+			originalClass = method.class
+		}
+
+		originalSource := source
+		if file != "Native Method" {
+			originalSource = m.sourceFile(originalClass)
+			if line > 0 {
+				originalSource += ":" + strconv.Itoa(method.originalLine(line))
+			}
+		}
+
+		retraced := frame(originalClass, method.name, originalSource)
+		if i > 0 && methods.ambiguous {
+			retraced = strings.Replace(retraced, "at ", "<OR> at ", 1)
+		}
+		lines = append(lines, retraced)
+	}
+	return lines
+}
+
+// methodLookup is the result of looking up an obfuscated method.  If ambiguous is false the
+// methods are an inlining chain starting with the innermost method, otherwise they are the
+// alternatives for the obfuscated method.
+type methodLookup struct {
+	methods   []*mappedMethod
+	ambiguous bool
+}
+
+// lookupMethods returns the original methods for an obfuscated method at an obfuscated line
+// number, or at an unknown line if line is 0.
+func (c *mappedClass) lookupMethods(obfuscated string, line int) methodLookup {
+	candidates := c.methods[obfuscated]
+
+	var chain []*mappedMethod
+	if line > 0 {
+		for _, method := range candidates {
+			if method.hasLines() && method.startLine <= line && line <= method.endLine {
+				chain = append(chain, method)
+			}
+		}
+	}
+	if len(chain) > 0 {
+		return methodLookup{methods: chain}
+	}
+
+	// Without a matching line range the frame could be any of the methods with the obfuscated
+	// name.
+	var alternatives []*mappedMethod
+	seen := make(map[string]bool)
+	for _, method := range candidates {
+		if line > 0 && method.hasLines() {
+			continue
+		}
+		key := method.class + "." + method.name
+		if !seen[key] {
+			seen[key] = true
+			alternatives = append(alternatives, method)
+		}
+	}
+	return methodLookup{methods: alternatives, ambiguous: len(alternatives) > 1}
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRetrace(t *testing.T) {
+	m, err := ParseMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := strings.Join([]string{
+		`Exception in thread "main" java.lang.IllegalStateException: a.a is broken`,
+		`	at a.a.b(SourceFile:3)`,
+		`	at a.a.b(SourceFile:5)`,
+		`	at app//a.b.a(SourceFile:1)`,
+		`	at a.a.c(Unknown Source)`,
+		`	at a.c.a(SourceFile)`,
+		`	at a.a.d(SourceFile:9)`,
+		`	at java.lang.Thread.run(Thread.java:920)`,
+		`Caused by: a.c: message`,
+		`	... 3 more`,
+		``,
+	}, "\n")
+
+	want := strings.Join([]string{
+		`Exception in thread "main" java.lang.IllegalStateException: a.a is broken`,
+		`	at com.example.Foo.run(Foo.kt:22)`,
+		`	at com.example.Bar.helper(Bar.java:30)`,
+		`	at com.example.Foo.run(Foo.kt:24)`,
+		`	at app//com.example.Bar.helper(Bar.java:30)`,
+		`	at com.example.Foo.first(Foo.kt)`,
+		`	<OR> at com.example.Foo.second(Foo.kt)`,
+		`	at com.example.Foo$Inner.call(Foo.java)`,
+		`	at com.example.Foo.d(SourceFile:9)`,
+		`	at java.lang.Thread.run(Thread.java:920)`,
+		`Caused by: com.example.Foo$Inner: message`,
+		`	... 3 more`,
+		``,
+	}, "\n")
+
+	out := &bytes.Buffer{}
+	if err := Retrace(m, strings.NewReader(in), out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("incorrect retraced stack trace\nwant:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// rustcHeader matches the first line of a diagnostic printed by rustc or clippy, for example
+	// "warning: unneeded `return` statement" or "error[E0425]: cannot find value `x`".
+	rustcHeader = regexp.MustCompile(`^(warning|error)(?:\[(\w+)\])?: (.*)$`)
+
+	// rustcLocation matches the primary location of a diagnostic, for example
+	// "  --> src/lib.rs:3:5".
+	rustcLocation = regexp.MustCompile(`^\s*--> (.+):(\d+):(\d+)$`)
+
+	// rustcLintAttribute and rustcLintFlag match the notes that name the lint of a diagnostic,
+	// for example "= note: `#[warn(clippy::needless_return)]` on by default" or
+	// "= note: `-D clippy::needless-return` implied by `-D warnings`".
+	rustcLintAttribute = regexp.MustCompile("`#\\[(?:warn|deny|forbid)\\(([\\w:]+)\\)\\]`")
+	rustcLintFlag      = regexp.MustCompile("`-[WDF] ([\\w:-]+)`")
+
+	// clippyHelp matches the link to the documentation of a clippy lint.
+	clippyHelp = regexp.MustCompile(`rust-clippy/[^#\s]*#(\w+)`)
+)
+
+// ParseClippy converts the human readable output of clippy to a SARIF run.  Diagnostics without
+// a location, like the summary at the end of the output, are ignored.
+func ParseClippy(r io.Reader) (*Run, error) {
+	run := &Run{Tool: Tool{Driver: Driver{
+		Name:           "clippy",
+		InformationURI: "https://rust-lang.github.io/rust-clippy/",
+	}}}
+
+	seen := make(map[string]bool)
+	var last *Result
+	var lintFromFlag, lintFromHelp string
+	flush := func() {
+		if last != nil && last.Locations != nil {
+			if last.RuleID == "" {
+				last.RuleID = lintFromFlag
+			}
+			if last.RuleID == "" {
+				last.RuleID = lintFromHelp
+			}
+			run.addResult(last, seen)
+		}
+		last = nil
+		lintFromFlag, lintFromHelp = "", ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := rustcHeader.FindStringSubmatch(line); match != nil {
+			flush()
+			last = &Result{
+				RuleID:  match[2],
+				Level:   match[1],
+				Message: Message{Text: match[3]},
+			}
+			continue
+		}
+		if last == nil {
+			continue
+		}
+
+		if match := rustcLocation.FindStringSubmatch(line); match != nil {
+			if last.Locations == nil {
+				lineNumber, _ := strconv.Atoi(match[2])
+				column, _ := strconv.Atoi(match[3])
+				last.Locations = []Location{newLocation(match[1], lineNumber, column)}
+			}
+		} else if match := rustcLintAttribute.FindStringSubmatch(line); match != nil {
+			if last.RuleID == "" {
+				last.RuleID = match[1]
+			}
+		} else if match := rustcLintFlag.FindStringSubmatch(line); match != nil {
+			if lintFromFlag == "" {
+				lintFromFlag = strings.ReplaceAll(match[1], "-", "_")
+			}
+		} else if match := clippyHelp.FindStringSubmatch(line); match != nil {
+			if lintFromHelp == "" {
+				lintFromHelp = "clippy::" + match[1]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	run.addRules()
+	return run, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseClippy(t *testing.T) {
+	output := strings.Join([]string{
+		"warning: unneeded `return` statement",
+		" --> src/lib.rs:3:5",
+		"  |",
+		"3 |     return x;",
+		"  |     ^^^^^^^^^ help: remove `return`: `x`",
+		"  |",
+		"  = note: `#[warn(clippy::needless_return)]` on by default",
+		"  = help: for further information visit https://rust-lang.github.io/rust-clippy/master/index.html#needless_return",
+		"",
+		"error: this comparison involving the minimum or maximum element for this type contains a case that is always true or always false",
+		"  --> src/main.rs:10:8",
+		"   |",
+		"10 |     if x >= 0 {",
+		"   |        ^^^^^^",
+		"   |",
+		"   = note: `-D clippy::absurd-extreme-comparisons` implied by `-D warnings`",
+		"",
+		"error: approximate value of `f{32, 64}::consts::PI` found",
+		"  --> src/main.rs:12:13",
+		"   |",
+		"   = help: for further information visit https://rust-lang.github.io/rust-clippy/master/index.html#approx_constant",
+		"",
+		"error[E0425]: cannot find value `y` in this scope",
+		" --> src/main.rs:2:13",
+		"",
+		"warning: 1 warning emitted",
+		"error: aborting due to 3 previous errors",
+		"",
+	}, "\n")
+
+	run, err := ParseClippy(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Result{
+		{
+			RuleID:    "clippy::needless_return",
+			Level:     levelWarning,
+			Message:   Message{Text: "unneeded `return` statement"},
+			Locations: []Location{newLocation("src/lib.rs", 3, 5)},
+		},
+		{
+			RuleID:    "clippy::absurd_extreme_comparisons",
+			Level:     levelError,
+			Message:   Message{Text: "this comparison involving the minimum or maximum element for this type contains a case that is always true or always false"},
+			Locations: []Location{newLocation("src/main.rs", 10, 8)},
+		},
+		{
+			RuleID:    "clippy::approx_constant",
+			Level:     levelError,
+			Message:   Message{Text: "approximate value of `f{32, 64}::consts::PI` found"},
+			Locations: []Location{newLocation("src/main.rs", 12, 13)},
+		},
+		{
+			RuleID:    "E0425",
+			Level:     levelError,
+			Message:   Message{Text: "cannot find value `y` in this scope"},
+			Locations: []Location{newLocation("src/main.rs", 2, 13)},
+		},
+	}
+	if !reflect.DeepEqual(run.Results, want) {
+		t.Errorf("incorrect results\nwant: %s\ngot:  %s", dumpResults(want), dumpResults(run.Results))
+	}
+}
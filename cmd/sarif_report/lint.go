@@ -0,0 +1,103 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// The XML report written by Android Lint with --xml.
+type lintIssues struct {
+	Issues []lintIssue `xml:"issue"`
+}
+
+type lintIssue struct {
+	ID        string         `xml:"id,attr"`
+	Severity  string         `xml:"severity,attr"`
+	Message   string         `xml:"message,attr"`
+	Summary   string         `xml:"summary,attr"`
+	URL       string         `xml:"url,attr"`
+	Locations []lintLocation `xml:"location"`
+}
+
+type lintLocation struct {
+	File    string `xml:"file,attr"`
+	Line    string `xml:"line,attr"`
+	Column  string `xml:"column,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// lintLevels maps the severities of Android Lint to SARIF levels.
+var lintLevels = map[string]string{
+	"Fatal":       levelError,
+	"Error":       levelError,
+	"Warning":     levelWarning,
+	"Information": levelNote,
+	"Ignore":      levelNone,
+}
+
+// ParseLint converts an XML report of Android Lint to a SARIF run.  The first location of an
+// issue is the location of the result, the others are related locations.
+func ParseLint(r io.Reader) (*Run, error) {
+	var issues lintIssues
+	if err := xml.NewDecoder(r).Decode(&issues); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	run := &Run{Tool: Tool{Driver: Driver{
+		Name:           "Android Lint",
+		InformationURI: "https://developer.android.com/studio/write/lint",
+	}}}
+	rules := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, issue := range issues.Issues {
+		level := lintLevels[issue.Severity]
+		if level == "" {
+			level = levelWarning
+		}
+		result := &Result{
+			RuleID:  issue.ID,
+			Level:   level,
+			Message: Message{Text: issue.Message},
+		}
+		for i, l := range issue.Locations {
+			line, _ := strconv.Atoi(l.Line)
+			column, _ := strconv.Atoi(l.Column)
+			loc := newLocation(l.File, line, column)
+			if i == 0 {
+				result.Locations = append(result.Locations, loc)
+			} else {
+				if l.Message != "" {
+					loc.Message = &Message{Text: l.Message}
+				}
+				result.RelatedLocations = append(result.RelatedLocations, loc)
+			}
+		}
+		run.addResult(result, seen)
+
+		if !rules[issue.ID] {
+			rules[issue.ID] = true
+			rule := Rule{ID: issue.ID, HelpURI: issue.URL}
+			if issue.Summary != "" {
+				rule.ShortDescription = &Message{Text: issue.Summary}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+	}
+	run.addRules()
+	return run, nil
+}
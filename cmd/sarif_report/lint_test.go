@@ -0,0 +1,100 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLint(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<issues format="6" by="lint 7.0.0">
+    <issue
+        id="NewApi"
+        severity="Error"
+        message="Call requires API level 30"
+        category="Correctness"
+        summary="Calling new methods on older versions"
+        url="https://developer.android.com/reference/android/NewApi">
+        <location
+            file="frameworks/foo/Foo.java"
+            line="12"
+            column="5"/>
+        <location
+            file="frameworks/foo/Bar.java"
+            line="3"
+            column="1"
+            message="Declared here"/>
+    </issue>
+    <issue
+        id="UnusedResources"
+        severity="Warning"
+        message="The resource R.string.x appears to be unused">
+        <location
+            file="frameworks/foo/res/values/strings.xml"/>
+    </issue>
+</issues>
+`
+	run, err := ParseLint(strings.NewReader(report))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*Result{
+		{
+			RuleID:    "NewApi",
+			Level:     levelError,
+			Message:   Message{Text: "Call requires API level 30"},
+			Locations: []Location{newLocation("frameworks/foo/Foo.java", 12, 5)},
+			RelatedLocations: []Location{{
+				PhysicalLocation: newLocation("frameworks/foo/Bar.java", 3, 1).PhysicalLocation,
+				Message:          &Message{Text: "Declared here"},
+			}},
+		},
+		{
+			RuleID:    "UnusedResources",
+			Level:     levelWarning,
+			Message:   Message{Text: "The resource R.string.x appears to be unused"},
+			Locations: []Location{newLocation("frameworks/foo/res/values/strings.xml", 0, 0)},
+		},
+	}
+	if !reflect.DeepEqual(run.Results, want) {
+		t.Errorf("incorrect results\nwant: %s\ngot:  %s", dumpResults(want), dumpResults(run.Results))
+	}
+
+	wantRules := []Rule{
+		{
+			ID:               "NewApi",
+			ShortDescription: &Message{Text: "Calling new methods on older versions"},
+			HelpURI:          "https://developer.android.com/reference/android/NewApi",
+		},
+		{ID: "UnusedResources"},
+	}
+	if !reflect.DeepEqual(run.Tool.Driver.Rules, wantRules) {
+		t.Errorf("incorrect rules\nwant: %+v\ngot:  %+v", wantRules, run.Tool.Driver.Rules)
+	}
+}
+
+func TestParseLintEmpty(t *testing.T) {
+	run, err := ParseLint(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Results) != 0 {
+		t.Errorf("want no results, got %d", len(run.Results))
+	}
+}
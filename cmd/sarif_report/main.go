@@ -0,0 +1,116 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sarif_report converts the results of static analyzers to SARIF 2.1.0 files, and merges SARIF
+// files into a single file with one run per analyzer.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"android/soong/response"
+)
+
+// parsers maps the values of -format to the functions that convert the output of an analyzer.
+var parsers = map[string]func(io.Reader) (*Run, error){
+	"lint":       ParseLint,
+	"clang-tidy": ParseClangTidy,
+	"clippy":     ParseClippy,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s -format lint|clang-tidy|clippy|sarif -o <output> [-l <file list>] <inputs>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	format := flag.String("format", "", "Format of the inputs, or sarif to merge SARIF files")
+	output := flag.String("o", "", "Output SARIF file")
+	list := flag.String("l", "", "Response file with a list of inputs")
+	flag.Parse()
+
+	if *output == "" {
+		log.Fatal("-o is required")
+	}
+	if _, ok := parsers[*format]; !ok && *format != "sarif" {
+		log.Fatalf("unknown -format %q", *format)
+	}
+
+	inputs := flag.Args()
+	if *list != "" {
+		f, err := os.Open(*list)
+		if err != nil {
+			log.Fatal(err)
+		}
+		listed, err := response.ReadRspFile(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to read %s: %s", *list, err)
+		}
+		inputs = append(inputs, listed...)
+	}
+
+	sarif, err := convert(*format, inputs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := sarif.Write(f); err != nil {
+		f.Close()
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// convert reads the inputs in the given format and merges them into a single log.
+func convert(format string, inputs []string) (*Log, error) {
+	var logs []*Log
+	for _, input := range inputs {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		l, err := read(format, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", input, err)
+		}
+		logs = append(logs, l)
+	}
+	return Merge(logs...), nil
+}
+
+func read(format string, r io.Reader) (*Log, error) {
+	if format == "sarif" {
+		return ReadLog(r)
+	}
+	parse := parsers[format]
+	if parse == nil {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	run, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return newLog(run), nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// The subset of the SARIF 2.1.0 format (https://docs.oasis-open.org/sarif/sarif/v2.1.0/) that
+// is needed to export the results of static analyzers.
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	// srcRoot is the uriBaseId of the locations of results, the root of the source tree.
+	srcRoot = "%SRCROOT%"
+)
+
+// A Log is the top level object of a SARIF file.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []*Run `json:"runs"`
+}
+
+// A Run is the set of results from a single analyzer.
+type Run struct {
+	Tool    Tool      `json:"tool"`
+	Results []*Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// A Rule describes a check of an analyzer.
+type Rule struct {
+	ID               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+	HelpURI          string   `json:"helpUri,omitempty"`
+}
+
+// A Result is a single finding of an analyzer.
+type Result struct {
+	RuleID           string     `json:"ruleId,omitempty"`
+	Level            string     `json:"level"`
+	Message          Message    `json:"message"`
+	Locations        []Location `json:"locations,omitempty"`
+	RelatedLocations []Location `json:"relatedLocations,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+	Message          *Message         `json:"message,omitempty"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// The levels of results.
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+	levelNote    = "note"
+	levelNone    = "none"
+)
+
+func newLog(runs ...*Run) *Log {
+	return &Log{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    runs,
+	}
+}
+
+// newLocation returns the location of a line and column in a file, which are ignored if they are 0.
+func newLocation(file string, line, column int) Location {
+	loc := Location{
+		PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: file},
+		},
+	}
+	if !isAbs(file) {
+		loc.PhysicalLocation.ArtifactLocation.URIBaseID = srcRoot
+	}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &Region{StartLine: line, StartColumn: column}
+	}
+	return loc
+}
+
+func isAbs(file string) bool {
+	return len(file) > 0 && file[0] == '/'
+}
+
+// addResult adds a result to the run, unless it is identical to a result that is already in the
+// run.  Analyzers that check header files report the same findings for every file that includes
+// them.
+func (r *Run) addResult(result *Result, seen map[string]bool) {
+	key, _ := json.Marshal(result)
+	if seen[string(key)] {
+		return
+	}
+	seen[string(key)] = true
+	r.Results = append(r.Results, result)
+}
+
+// addRules adds a rule without a description for every rule ID of the results that isn't
+// already in the run, and sorts the rules by ID.
+func (r *Run) addRules() {
+	known := make(map[string]bool)
+	for _, rule := range r.Tool.Driver.Rules {
+		known[rule.ID] = true
+	}
+	for _, result := range r.Results {
+		if result.RuleID != "" && !known[result.RuleID] {
+			known[result.RuleID] = true
+			r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, Rule{ID: result.RuleID})
+		}
+	}
+	sort.SliceStable(r.Tool.Driver.Rules, func(i, j int) bool {
+		return r.Tool.Driver.Rules[i].ID < r.Tool.Driver.Rules[j].ID
+	})
+}
+
+// Merge merges the runs of several logs into one log with a single run for each analyzer, sorted
+// by the name of the analyzer.  The results of each analyzer are kept in the order of the logs,
+// without duplicates.
+func Merge(logs ...*Log) *Log {
+	byName := make(map[string]*Run)
+	seen := make(map[string]map[string]bool)
+	var names []string
+	for _, log := range logs {
+		for _, run := range log.Runs {
+			name := run.Tool.Driver.Name
+			merged := byName[name]
+			if merged == nil {
+				merged = &Run{Tool: Tool{Driver: Driver{
+					Name:           name,
+					InformationURI: run.Tool.Driver.InformationURI,
+				}}}
+				byName[name] = merged
+				seen[name] = make(map[string]bool)
+				names = append(names, name)
+			}
+			merged.mergeRules(run.Tool.Driver.Rules)
+			for _, result := range run.Results {
+				merged.addResult(result, seen[name])
+			}
+		}
+	}
+
+	sort.Strings(names)
+	merged := newLog()
+	merged.Runs = []*Run{}
+	for _, name := range names {
+		run := byName[name]
+		run.addRules()
+		merged.Runs = append(merged.Runs, run)
+	}
+	return merged
+}
+
+// mergeRules adds the rules that aren't already in the run.
+func (r *Run) mergeRules(rules []Rule) {
+	for _, rule := range rules {
+		found := false
+		for _, existing := range r.Tool.Driver.Rules {
+			if existing.ID == rule.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule)
+		}
+	}
+}
+
+// ReadLog reads a SARIF file.
+func ReadLog(r io.Reader) (*Log, error) {
+	log := &Log{}
+	if err := json.NewDecoder(r).Decode(log); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// Write writes the log as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	if l.Runs == nil {
+		l.Runs = []*Run{}
+	}
+	for _, run := range l.Runs {
+		if run.Results == nil {
+			run.Results = []*Result{}
+		}
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
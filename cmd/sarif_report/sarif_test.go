@@ -0,0 +1,108 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func dumpResults(results []*Result) string {
+	data, _ := json.Marshal(results)
+	return string(data)
+}
+
+func TestMerge(t *testing.T) {
+	result := func(rule, file string) *Result {
+		return &Result{
+			RuleID:    rule,
+			Level:     levelWarning,
+			Message:   Message{Text: rule},
+			Locations: []Location{newLocation(file, 1, 1)},
+		}
+	}
+	run := func(tool string, rules []Rule, results ...*Result) *Run {
+		return &Run{Tool: Tool{Driver: Driver{Name: tool, Rules: rules}}, Results: results}
+	}
+
+	described := Rule{ID: "a", ShortDescription: &Message{Text: "rule a"}}
+	logs := []*Log{
+		newLog(run("lint", []Rule{described}, result("a", "x.java"))),
+		newLog(run("clippy", nil, result("c", "x.rs"))),
+		newLog(run("lint", []Rule{{ID: "a"}}, result("b", "y.java"), result("a", "x.java"), result("a", "z.java"))),
+	}
+
+	merged := Merge(logs...)
+
+	var tools []string
+	for _, r := range merged.Runs {
+		tools = append(tools, r.Tool.Driver.Name)
+	}
+	if g, w := tools, []string{"clippy", "lint"}; !reflect.DeepEqual(g, w) {
+		t.Fatalf("want runs %q, got %q", w, g)
+	}
+
+	lint := merged.Runs[1]
+	wantResults := []*Result{result("a", "x.java"), result("b", "y.java"), result("a", "z.java")}
+	if !reflect.DeepEqual(lint.Results, wantResults) {
+		t.Errorf("incorrect results\nwant: %s\ngot:  %s", dumpResults(wantResults), dumpResults(lint.Results))
+	}
+	wantRules := []Rule{described, {ID: "b"}}
+	if !reflect.DeepEqual(lint.Tool.Driver.Rules, wantRules) {
+		t.Errorf("incorrect rules\nwant: %+v\ngot:  %+v", wantRules, lint.Tool.Driver.Rules)
+	}
+}
+
+func TestWriteAndReadLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Merge().Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": []
+}
+`
+	if buf.String() != want {
+		t.Errorf("incorrect empty log\nwant:\n%s\ngot:\n%s", want, buf.String())
+	}
+
+	run := &Run{Tool: Tool{Driver: Driver{Name: "clang-tidy"}}}
+	run.Results = []*Result{{
+		RuleID:    "modernize-use-nullptr",
+		Level:     levelWarning,
+		Message:   Message{Text: "use nullptr"},
+		Locations: []Location{newLocation("a.cpp", 2, 3)},
+	}}
+	buf.Reset()
+	if err := newLog(run).Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"uriBaseId": "%SRCROOT%"`) {
+		t.Errorf("expected relative locations to use %%SRCROOT%%, got:\n%s", buf.String())
+	}
+
+	read, err := ReadLog(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, newLog(run)) {
+		t.Errorf("log differs after reading it back")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// clangTidyDiagnostic matches the diagnostics printed by clang-tidy, for example
+// "foo.cpp:12:5: warning: use nullptr [modernize-use-nullptr]".
+var clangTidyDiagnostic = regexp.MustCompile(`^(.+?):(\d+):(\d+): (warning|error|note): (.*?)(?: \[([^\]]+)\])?$`)
+
+// ParseClangTidy converts the output of clang-tidy to a SARIF run.  Notes are added as related
+// locations of the previous warning or error.
+func ParseClangTidy(r io.Reader) (*Run, error) {
+	run := &Run{Tool: Tool{Driver: Driver{
+		Name:           "clang-tidy",
+		InformationURI: "https://clang.llvm.org/extra/clang-tidy/",
+	}}}
+
+	seen := make(map[string]bool)
+	var last *Result
+	flush := func() {
+		if last != nil {
+			run.addResult(last, seen)
+			last = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		match := clangTidyDiagnostic.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		file, severity, message := match[1], match[4], match[5]
+		line, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		loc := newLocation(file, line, column)
+
+		if severity == "note" {
+			if last != nil {
+				loc.Message = &Message{Text: message}
+				last.RelatedLocations = append(last.RelatedLocations, loc)
+			}
+			continue
+		}
+
+		flush()
+		last = &Result{
+			RuleID:    clangTidyCheck(match[6]),
+			Level:     severity,
+			Message:   Message{Text: message},
+			Locations: []Location{loc},
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	run.addRules()
+	return run, nil
+}
+
+// clangTidyCheck returns the name of the check from the list of names at the end of a diagnostic,
+// which also contains -warnings-as-errors when the warning was turned into an error.
+func clangTidyCheck(names string) string {
+	for _, name := range strings.Split(names, ",") {
+		if name != "" && !strings.HasPrefix(name, "-") {
+			return name
+		}
+	}
+	return ""
+}
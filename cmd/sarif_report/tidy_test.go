@@ -0,0 +1,82 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseClangTidy(t *testing.T) {
+	output := strings.Join([]string{
+		"2 warnings generated.",
+		"foo/a.cpp:12:5: warning: use nullptr [modernize-use-nullptr]",
+		"    int *p = 0;",
+		"             ^",
+		"             nullptr",
+		"foo/a.h:3:1: error: do not use 'else' after 'return' [readability-else-after-return,-warnings-as-errors]",
+		"foo/a.h:1:1: note: previous return is here",
+		"foo/a.h:3:1: error: do not use 'else' after 'return' [readability-else-after-return,-warnings-as-errors]",
+		"foo/a.h:1:1: note: previous return is here",
+		"/abs/b.cpp:1:2: warning: unused variable 'x' [clang-diagnostic-unused-variable]",
+		"Suppressed 10 warnings (10 in non-user code).",
+		"",
+	}, "\n")
+
+	run, err := ParseClangTidy(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note := newLocation("foo/a.h", 1, 1)
+	note.Message = &Message{Text: "previous return is here"}
+	want := []*Result{
+		{
+			RuleID:    "modernize-use-nullptr",
+			Level:     levelWarning,
+			Message:   Message{Text: "use nullptr"},
+			Locations: []Location{newLocation("foo/a.cpp", 12, 5)},
+		},
+		{
+			RuleID:           "readability-else-after-return",
+			Level:            levelError,
+			Message:          Message{Text: "do not use 'else' after 'return'"},
+			Locations:        []Location{newLocation("foo/a.h", 3, 1)},
+			RelatedLocations: []Location{note},
+		},
+		{
+			RuleID:  "clang-diagnostic-unused-variable",
+			Level:   levelWarning,
+			Message: Message{Text: "unused variable 'x'"},
+			Locations: []Location{{PhysicalLocation: PhysicalLocation{
+				ArtifactLocation: ArtifactLocation{URI: "/abs/b.cpp"},
+				Region:           &Region{StartLine: 1, StartColumn: 2},
+			}}},
+		},
+	}
+	if !reflect.DeepEqual(run.Results, want) {
+		t.Errorf("incorrect results\nwant: %s\ngot:  %s", dumpResults(want), dumpResults(run.Results))
+	}
+
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID)
+	}
+	wantRules := []string{"clang-diagnostic-unused-variable", "modernize-use-nullptr", "readability-else-after-return"}
+	if !reflect.DeepEqual(rules, wantRules) {
+		t.Errorf("incorrect rules\nwant: %q\ngot:  %q", wantRules, rules)
+	}
+}
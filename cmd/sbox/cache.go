@@ -0,0 +1,401 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+	"android/soong/response"
+)
+
+// The action cache stores the outputs of commands that run with chdir, keyed by a hash of the
+// command, its input_hash and the contents of the files that its copy_before rules and rsp files
+// copy into the sandbox directory, which are its declared inputs and tools.  The key doesn't
+// include any paths outside the sandbox directory, so the cache can be shared between out
+// directories on the same machine.  Commands that read files outside the sandbox directory that
+// are not declared inputs can get stale results from the cache.
+//
+// Each entry is a directory in entries/ that contains the outputs of the command in the order of
+// its copy_after rules, its depfile if it has one and the combined stdout and stderr of the
+// command, which is printed again when the entry is used.  The modification time of the entry is
+// updated when it is used, and the least recently used entries are removed when the cache grows
+// above its maximum size.  The hashes of input files are stored in digests/ so that files that
+// haven't changed don't need to be hashed again.
+
+const (
+	// actionCacheVersion is part of every key, changing it invalidates all entries.
+	actionCacheVersion = "3"
+
+	defaultActionCacheMaxSize = 5 << 30
+
+	// Trimming the cache requires reading every entry, do it at most once per interval.
+	actionCacheTrimInterval = time.Minute
+
+	actionCacheDepFile = "deps.d"
+	actionCacheOutput  = "output"
+
+	// The hash of a file is only reused if the file wasn't modified for this long before it was
+	// hashed.
+	actionCacheDigestMinAge = 2 * time.Second
+)
+
+type actionCache struct {
+	dir     string
+	maxSize int64
+}
+
+// newActionCache returns the action cache in dir, or nil if dir is empty.  maxSize is the maximum
+// size of the cache in bytes, optionally followed by K, M or G, or empty to use the default.
+func newActionCache(dir, maxSize string) (*actionCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	size := int64(defaultActionCacheMaxSize)
+	if maxSize != "" {
+		var err error
+		size, err = parseSize(maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid action cache size %q: %w", maxSize, err)
+		}
+	}
+	return &actionCache{dir: dir, maxSize: size}, nil
+}
+
+// parseSize parses a number of bytes optionally followed by K, M or G.
+func parseSize(s string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("size must not be negative")
+	}
+	return n * multiplier, nil
+}
+
+// key returns the key of a command.  The names of the declared inputs are covered by the
+// input_hash and the copy_before rules, but not their contents, so the contents of every file that
+// the command copies into the sandbox, including the rsp files and the files they list, are
+// hashed too.
+func (c *actionCache) key(command *sbox_proto.Command) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "version %q\n", actionCacheVersion)
+	fmt.Fprintf(h, "command %q\n", command.GetCommand())
+	fmt.Fprintf(h, "input_hash %q\n", command.GetInputHash())
+
+	for _, copyPair := range command.CopyBefore {
+		fileHash, executable, err := c.fileHash(copyPair.GetFrom())
+		if err != nil {
+			return "", err
+		}
+		executable = executable || copyPair.GetExecutable()
+		fmt.Fprintf(h, "input %q %v %s\n", copyPair.GetTo(), executable, fileHash)
+	}
+
+	for _, rspFile := range command.RspFiles {
+		f, err := os.Open(rspFile.GetFile())
+		if err != nil {
+			return "", err
+		}
+		files, err := response.ReadRspFile(f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "rsp %q\n", applyPathMappings(rspFile.PathMappings, rspFile.GetFile()))
+		for _, from := range files {
+			fileHash, executable, err := c.fileHash(from)
+			if err != nil {
+				return "", err
+			}
+			to := applyPathMappings(rspFile.PathMappings, from)
+			fmt.Fprintf(h, "rsp_input %q %v %s\n", to, executable, fileHash)
+		}
+	}
+
+	for _, copyPair := range command.CopyAfter {
+		fmt.Fprintf(h, "output %q\n", copyPair.GetFrom())
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileHash returns the hash of the contents of a file and whether it is executable.  Hashing
+// every input of every command is slow, so the hash is stored in the cache together with the
+// size, modification time and inode of the file, and reused while they don't change.
+func (c *actionCache) fileHash(path string) (hash string, executable bool, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false, err
+	}
+	executable = info.Mode()&0111 != 0
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false, err
+	}
+	pathHash := sha256.Sum256([]byte(abs))
+	digestKey := hex.EncodeToString(pathHash[:])
+	digestFile := filepath.Join(c.dir, "digests", digestKey[:2], digestKey)
+
+	var ino uint64
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		ino = uint64(stat.Ino)
+	}
+	stamp := fmt.Sprintf("%d %d %d", info.Size(), info.ModTime().UnixNano(), ino)
+
+	if data, err := ioutil.ReadFile(digestFile); err == nil {
+		if fields := strings.Fields(string(data)); len(fields) == 4 &&
+			strings.Join(fields[:3], " ") == stamp {
+			return fields[3], executable, nil
+		}
+	}
+
+	hash, err = hashFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	// A file that was modified very recently could be modified again without changing its
+	// modification time, only reuse the hashes of files that have been stable for a while.
+	if time.Since(info.ModTime()) > actionCacheDigestMinAge {
+		// Failing to store the hash only makes the next lookup slower.
+		if err := os.MkdirAll(filepath.Dir(digestFile), 0777); err == nil {
+			writeFileAtomic(digestFile, stamp+" "+hash+"\n")
+		}
+	}
+
+	return hash, executable, nil
+}
+
+// writeFileAtomic writes a file through a temporary file so that other sbox processes never see
+// a partial file.
+func writeFileAtomic(path, contents string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *actionCache) entryDir(key string) string {
+	return filepath.Join(c.dir, "entries", key[:2], key)
+}
+
+// restore copies the outputs of the entry for key to the destinations of the copy_after rules in
+// outputs, and its depfile to depFile if it is not empty.  It returns the combined stdout and
+// stderr of the command, and false if there is no entry for key or it could not be restored, in
+// which case the outputs may have been partially written.
+func (c *actionCache) restore(key string, outputs []*sbox_proto.Copy, depFile string) ([]byte, bool) {
+	entry := c.entryDir(key)
+	output, err := ioutil.ReadFile(filepath.Join(entry, actionCacheOutput))
+	if err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	for i, copyPair := range outputs {
+		to := copyPair.GetTo()
+		if err := copyOneFile(filepath.Join(entry, strconv.Itoa(i)), to, false, false); err != nil {
+			return nil, false
+		}
+		// Give the outputs new timestamps like moveFiles does.
+		if err := os.Chtimes(to, now, now); err != nil {
+			return nil, false
+		}
+	}
+	if depFile != "" {
+		if err := copyOneFile(filepath.Join(entry, actionCacheDepFile), depFile, false, false); err != nil {
+			return nil, false
+		}
+	}
+
+	// Mark the entry as recently used.
+	os.Chtimes(entry, now, now)
+	return output, true
+}
+
+// store adds an entry for key with the outputs of the copy_after rules in outputs from
+// sandboxDir, depFile if it is not empty and the combined stdout and stderr of the command in
+// output.  It then trims the cache if it is due.
+func (c *actionCache) store(key string, outputs []*sbox_proto.Copy, sandboxDir, depFile string,
+	output []byte) error {
+	entry := c.entryDir(key)
+	if _, err := os.Stat(entry); err == nil {
+		return nil
+	}
+
+	// Write the entry into a temporary directory and move it into place when it is complete, so
+	// that other sbox processes never see a partial entry.
+	tmpDir := filepath.Join(c.dir, "tmp")
+	if err := os.MkdirAll(tmpDir, 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(tmpDir, "entry")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	for i, copyPair := range outputs {
+		err := copyOneFile(joinPath(sandboxDir, copyPair.GetFrom()), filepath.Join(tmp, strconv.Itoa(i)), false, false)
+		if err != nil {
+			return err
+		}
+	}
+	if depFile != "" {
+		if err := copyOneFile(depFile, filepath.Join(tmp, actionCacheDepFile), false, false); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, actionCacheOutput), output, 0666); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entry), 0777); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, entry); err != nil {
+		if _, statErr := os.Stat(entry); statErr == nil {
+			// Another sbox process stored the same entry.
+			return nil
+		}
+		return err
+	}
+
+	return c.maybeTrim()
+}
+
+// maybeTrim trims the cache unless it was trimmed recently or another sbox process is trimming it.
+func (c *actionCache) maybeTrim() error {
+	stamp := filepath.Join(c.dir, "last_trim")
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < actionCacheTrimInterval {
+		return nil
+	}
+
+	lock, err := os.OpenFile(filepath.Join(c.dir, "lock"), os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+		return nil
+	} else if err != nil {
+		return os.NewSyscallError("flock", err)
+	}
+
+	if err := ioutil.WriteFile(stamp, nil, 0666); err != nil {
+		return err
+	}
+	return c.trim()
+}
+
+// trim removes the least recently used entries until the cache is no larger than its maximum size.
+func (c *actionCache) trim() error {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var entries []entry
+	var total int64
+
+	prefixes, err := ioutil.ReadDir(filepath.Join(c.dir, "entries"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		prefixDir := filepath.Join(c.dir, "entries", prefix.Name())
+		keys, err := ioutil.ReadDir(prefixDir)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			path := filepath.Join(prefixDir, key.Name())
+			files, err := ioutil.ReadDir(path)
+			if err != nil {
+				// The entry may have been removed by another sbox process.
+				continue
+			}
+			e := entry{path: path, modTime: key.ModTime()}
+			for _, file := range files {
+				e.size += file.Size()
+			}
+			entries = append(entries, e)
+			total += e.size
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.maxSize {
+			break
+		}
+		if err := os.RemoveAll(e.path); err != nil {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "10K", want: 10 << 10},
+		{in: "10M", want: 10 << 20},
+		{in: "10G", want: 10 << 30},
+		{in: "G", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "10T", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := parseSize(tc.in)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestActionCache(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cache, err := newActionCache(filepath.Join(dir, "cache"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The command counts how many times it ran in a file outside the sandbox.
+	runs := filepath.Join(dir, "runs")
+	command := &sbox_proto.Command{
+		Chdir: proto.Bool(true),
+		Command: proto.String("cat src/in.txt $(cat rsp) > out/out.txt && echo run >> " + runs +
+			" && echo warning >&2"),
+		CopyBefore: []*sbox_proto.Copy{
+			{From: proto.String("src/in.txt"), To: proto.String("src/in.txt")},
+		},
+		RspFiles: []*sbox_proto.RspFile{
+			{File: proto.String("rsp")},
+		},
+		CopyAfter: []*sbox_proto.Copy{
+			{From: proto.String("out/out.txt"), To: proto.String("gen/out.txt")},
+		},
+	}
+	// run runs the command and returns what it printed.
+	run := func() string {
+		t.Helper()
+		if err := os.RemoveAll("sbox"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll("gen"); err != nil {
+			t.Fatal(err)
+		}
+		stdout, err := ioutil.TempFile(dir, "stdout")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdout.Close()
+		realStdout := os.Stdout
+		os.Stdout = stdout
+		_, _, err = runCommand(command, "sbox", false, nil, cache)
+		os.Stdout = realStdout
+		if err != nil {
+			t.Fatal(err)
+		}
+		return readTestFile(t, stdout.Name())
+	}
+
+	writeTestFile(t, "rsp", "src/rsp_in.txt\n")
+	writeTestFile(t, "src/rsp_in.txt", "")
+	writeTestFile(t, "src/in.txt", "one\n")
+	run()
+	// The output of the command is printed again when its outputs are restored from the cache.
+	if g, w := run(), "warning\n"; g != w {
+		t.Errorf("expected the cached command to print %q, got %q", w, g)
+	}
+	if g, w := readTestFile(t, "gen/out.txt"), "one\n"; g != w {
+		t.Errorf("expected output %q, got %q", w, g)
+	}
+	if g, w := readTestFile(t, runs), "run\n"; g != w {
+		t.Errorf("expected the command to run once, got %q", g)
+	}
+
+	// Changing an input reruns the command.
+	writeTestFile(t, "src/in.txt", "two\n")
+	run()
+	if g, w := readTestFile(t, "gen/out.txt"), "two\n"; g != w {
+		t.Errorf("expected output %q, got %q", w, g)
+	}
+	if g, w := readTestFile(t, runs), "run\nrun\n"; g != w {
+		t.Errorf("expected the command to run twice, got %q", g)
+	}
+
+	// Changing back to the first input uses the first entry.
+	writeTestFile(t, "src/in.txt", "one\n")
+	run()
+	if g, w := readTestFile(t, "gen/out.txt"), "one\n"; g != w {
+		t.Errorf("expected output %q, got %q", w, g)
+	}
+	if g, w := readTestFile(t, runs), "run\nrun\n"; g != w {
+		t.Errorf("expected the command to run twice, got %q", g)
+	}
+
+	// Changing a file listed in an rsp file reruns the command.
+	writeTestFile(t, "src/rsp_in.txt", "rsp\n")
+	run()
+	if g, w := readTestFile(t, "gen/out.txt"), "one\nrsp\n"; g != w {
+		t.Errorf("expected output %q, got %q", w, g)
+	}
+	if g, w := readTestFile(t, runs), "run\nrun\nrun\n"; g != w {
+		t.Errorf("expected the command to run three times, got %q", g)
+	}
+}
+
+func TestActionCacheFileHash(t *testing.T) {
+	dir := t.TempDir()
+	cache := &actionCache{dir: filepath.Join(dir, "cache")}
+
+	oldFile := filepath.Join(dir, "old.txt")
+	newFile := filepath.Join(dir, "new.txt")
+	writeTestFile(t, oldFile, "old\n")
+	writeTestFile(t, newFile, "new\n")
+	oldTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(oldFile, oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := hashFile(oldFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		got, _, err := cache.fileHash(oldFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("expected hash %s, got %s", want, got)
+		}
+	}
+	if _, _, err := cache.fileHash(newFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the hash of the file that wasn't modified recently is stored.
+	digests, err := filepath.Glob(filepath.Join(cache.dir, "digests", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 1 {
+		t.Fatalf("expected one stored hash, got %q", digests)
+	}
+
+	// Modifying the file invalidates the stored hash.
+	writeTestFile(t, oldFile, "modified\n")
+	want, err = hashFile(oldFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := cache.fileHash(oldFile); err != nil {
+		t.Fatal(err)
+	} else if got != want {
+		t.Errorf("expected hash %s, got %s", want, got)
+	}
+}
+
+func TestActionCacheTrim(t *testing.T) {
+	dir := t.TempDir()
+	cache := &actionCache{dir: filepath.Join(dir, "cache"), maxSize: 10}
+	outputs := []*sbox_proto.Copy{
+		{From: proto.String("out.txt"), To: proto.String(filepath.Join(dir, "out.txt"))},
+	}
+
+	keys := []string{"aaaa", "bbbb", "cccc"}
+	for i, key := range keys {
+		sandboxDir := filepath.Join(dir, key)
+		writeTestFile(t, filepath.Join(sandboxDir, "out.txt"), "12345\n")
+		if err := cache.store(key, outputs, sandboxDir, "", nil); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(time.Duration(i-len(keys)) * time.Hour)
+		if err := os.Chtimes(cache.entryDir(key), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Using an entry makes it the most recently used one.
+	if _, ok := cache.restore("aaaa", outputs, ""); !ok {
+		t.Fatalf("expected a cache hit")
+	}
+
+	if err := cache.trim(); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"aaaa": true, "bbbb": false, "cccc": false} {
+		_, err := os.Stat(cache.entryDir(key))
+		if got := err == nil; got != want {
+			t.Errorf("expected entry %s to exist: %v, got %v", key, want, got)
+		}
+	}
+}
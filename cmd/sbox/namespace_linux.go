@@ -0,0 +1,298 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// A hermetic sandbox is set up by reexecuting sbox in new user and mount namespaces, where it is
+// root and can mount filesystems.  It opens the source tree and the output directory, mounts an
+// empty tmpfs over each of them, and bind mounts the sandbox directory, the inputs and the $PATH
+// directories back into them from the opened directories.  It then runs the command in another user namespace that maps the original
+// user, so that the command runs without any capabilities and can't undo the mounts.
+
+// namespaceChildArg0 is the argv[0] that sbox is reexecuted with to set up a hermetic sandbox.
+const namespaceChildArg0 = "sbox-namespace-child"
+
+// namespaceSpec is passed from sbox to its reexecuted child through a file on fd 3.
+type namespaceSpec struct {
+	// The directories that are hidden by empty tmpfs mounts: the source tree, and the output
+	// directory if it is outside of the source tree.
+	HiddenDirs []string
+
+	// The files and directories to mount, in order.
+	Mounts []namespaceMount
+
+	// The working directory and command line of the command.
+	Dir     string
+	Command string
+
+	// The user and group to run the command as.
+	Uid int
+	Gid int
+}
+
+// hermeticCommand returns a command that runs rawCommand in a hermetic sandbox in which the sandbox
+// directory and the given mounts are visible.  The working directory of the command must be set
+// to sandboxDir.  outDir is the output directory, which is hidden along with the source tree.
+func hermeticCommand(rawCommand, sandboxDir, outDir string, inputs []namespaceMount) (*exec.Cmd, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return filepath.Clean(path)
+		}
+		return filepath.Join(wd, path)
+	}
+
+	spec := namespaceSpec{
+		HiddenDirs: hiddenDirs(wd, abs(outDir)),
+		Dir:        abs(sandboxDir),
+		Command:    rawCommand,
+		Uid:        os.Getuid(),
+		Gid:        os.Getgid(),
+	}
+
+	spec.Mounts = append(spec.Mounts, namespaceMount{From: spec.Dir, To: spec.Dir, Writable: true})
+	for _, input := range inputs {
+		spec.Mounts = append(spec.Mounts, namespaceMount{From: abs(input.From), To: abs(input.To)})
+	}
+	pathMounts, err := pathDirMounts(os.Getenv("PATH"), spec.HiddenDirs)
+	if err != nil {
+		return nil, err
+	}
+	spec.Mounts = append(spec.Mounts, pathMounts...)
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	// Pass the spec as an unlinked temporary file, it can be larger than a pipe buffer.
+	specFile, err := ioutil.TempFile("", "sbox-namespace")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(specFile.Name())
+	if _, err := specFile.Write(data); err != nil {
+		specFile.Close()
+		return nil, err
+	}
+	if _, err := specFile.Seek(0, 0); err != nil {
+		specFile.Close()
+		return nil, err
+	}
+
+	cmd := exec.Command("/proc/self/exe")
+	cmd.Args = []string{namespaceChildArg0}
+	cmd.ExtraFiles = []*os.File{specFile}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: spec.Uid, Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: spec.Gid, Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+	}
+	return cmd, nil
+}
+
+// hiddenDirs returns the directories to hide in a hermetic sandbox: the source tree, and the
+// output directory if it is not inside of the source tree.
+func hiddenDirs(srcDir, outDir string) []string {
+	if within(outDir, srcDir) {
+		return []string{srcDir}
+	} else if within(srcDir, outDir) {
+		return []string{outDir}
+	}
+	return []string{srcDir, outDir}
+}
+
+// within returns true if path is dir or is inside of it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+// pathDirMounts returns read-only mounts for the directories in pathEnv that are inside of one of
+// hiddenDirs.  Each directory is mounted with the files next to it whose names start with its name,
+// which is where soong_ui keeps the path interposer that the directory links to.
+func pathDirMounts(pathEnv string, hiddenDirs []string) ([]namespaceMount, error) {
+	var mounts []namespaceMount
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if !filepath.IsAbs(dir) || !hiddenBy(dir, hiddenDirs) {
+			continue
+		}
+		parent, name := filepath.Split(filepath.Clean(dir))
+		entries, err := ioutil.ReadDir(parent)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(parent, entry.Name())
+			if strings.HasPrefix(entry.Name(), name) && !seen[path] {
+				seen[path] = true
+				mounts = append(mounts, namespaceMount{From: path, To: path})
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// hiddenBy returns true if path is strictly inside of one of hiddenDirs.
+func hiddenBy(path string, hiddenDirs []string) bool {
+	for _, dir := range hiddenDirs {
+		if strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// runNamespaceChild sets up a hermetic sandbox and runs the command in it.  It returns the exit
+// code of the command.
+func runNamespaceChild() int {
+	specFile := os.NewFile(3, "spec")
+	data, err := ioutil.ReadAll(specFile)
+	specFile.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sbox: failed to read hermetic sandbox spec:", err)
+		return 1
+	}
+	var spec namespaceSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox: failed to parse hermetic sandbox spec:", err)
+		return 1
+	}
+
+	if err := setupNamespace(spec); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox: failed to set up hermetic sandbox:", err)
+		return 1
+	}
+
+	cmd := exec.Command("bash", "-c", spec.Command)
+	cmd.Dir = spec.Dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: spec.Uid, HostID: 0, Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: spec.Gid, HostID: 0, Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+	}
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		status := exitErr.Sys().(syscall.WaitStatus)
+		if status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return status.ExitStatus()
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "sbox:", err)
+		return 1
+	}
+	return 0
+}
+
+// setupNamespace hides spec.HiddenDirs and mounts spec.Mounts.
+func setupNamespace(spec namespaceSpec) error {
+	// Keep the mounts from propagating out of the namespace.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return &os.PathError{Op: "mount", Path: "/", Err: err}
+	}
+
+	// Keep references to the hidden directories to mount files from them after they are hidden.
+	hiddenFds := make([]int, len(spec.HiddenDirs))
+	for i, dir := range spec.HiddenDirs {
+		fd, err := syscall.Open(dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			return &os.PathError{Op: "open", Path: dir, Err: err}
+		}
+		defer syscall.Close(fd)
+		hiddenFds[i] = fd
+	}
+
+	for _, dir := range spec.HiddenDirs {
+		if err := syscall.Mount("tmpfs", dir, "tmpfs", 0, "mode=0755"); err != nil {
+			return &os.PathError{Op: "mount", Path: dir, Err: err}
+		}
+	}
+
+	for _, m := range spec.Mounts {
+		from := m.From
+		for i, dir := range spec.HiddenDirs {
+			if within(from, dir) {
+				rel, _ := filepath.Rel(dir, from)
+				from = filepath.Join(fmt.Sprintf("/proc/self/fd/%d", hiddenFds[i]), rel)
+				break
+			}
+		}
+
+		info, err := os.Stat(from)
+		if err != nil {
+			return err
+		}
+		if err := createMountPoint(m.To, info.IsDir()); err != nil {
+			return err
+		}
+		if err := syscall.Mount(from, m.To, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return &os.PathError{Op: "mount", Path: m.To, Err: err}
+		}
+		if !m.Writable {
+			flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_RDONLY | syscall.MS_REC)
+			if err := syscall.Mount("", m.To, "", flags, ""); err != nil {
+				return &os.PathError{Op: "remount", Path: m.To, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// createMountPoint creates an empty file or directory to mount on if path doesn't exist.
+func createMountPoint(path string, isDir bool) error {
+	if _, err := os.Lstat(path); err == nil {
+		return nil
+	}
+	if isDir {
+		return os.MkdirAll(path, 0777)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
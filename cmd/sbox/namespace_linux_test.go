@@ -0,0 +1,189 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMain(m *testing.M) {
+	// Hermetic sandboxes and traced commands reexecute the test binary.
+	if os.Args[0] == namespaceChildArg0 {
+		os.Exit(runNamespaceChild())
+	} else if os.Args[0] == traceChildArg0 {
+		os.Exit(runTraceChild())
+	}
+	os.Exit(m.Run())
+}
+
+func TestHermeticSandbox(t *testing.T) {
+	if err := exec.Command("unshare", "--user", "--mount", "true").Run(); err != nil {
+		t.Skip("user namespaces are not available:", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for file, contents := range map[string]string{
+		"src/declared.txt":   "declared\n",
+		"src/undeclared.txt": "undeclared\n",
+		"src/rsp_input.txt":  "rsp\n",
+		"out/inputs.rsp":     "src/rsp_input.txt\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	command := &sbox_proto.Command{
+		Chdir: proto.Bool(true),
+		Command: proto.String("cat src/declared.txt src/rsp_input.txt > out/result.txt && " +
+			"if cat ../../src/undeclared.txt ../../src/declared.txt 2>/dev/null; then exit 1; fi && " +
+			"if (echo modified > src/declared.txt) 2>/dev/null; then exit 1; fi"),
+		CopyBefore: []*sbox_proto.Copy{
+			{From: proto.String("src/declared.txt"), To: proto.String("src/declared.txt")},
+		},
+		RspFiles: []*sbox_proto.RspFile{
+			{File: proto.String("out/inputs.rsp")},
+		},
+		CopyAfter: []*sbox_proto.Copy{
+			{From: proto.String("out/result.txt"), To: proto.String("out/result.txt")},
+		},
+	}
+
+	if _, _, err := runCommand(command, "out/sbox", true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ioutil.ReadFile("out/result.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(result), "declared\nrsp\n"; g != w {
+		t.Errorf("expected result %q, got %q", w, g)
+	}
+	declared, err := ioutil.ReadFile("src/declared.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(declared), "declared\n"; g != w {
+		t.Errorf("expected input to be unmodified %q, got %q", w, g)
+	}
+}
+
+func TestHermeticSandboxRequiresChdir(t *testing.T) {
+	command := &sbox_proto.Command{
+		Command: proto.String("true"),
+	}
+	if _, _, err := runCommand(command, t.TempDir(), true, nil, nil); err == nil {
+		t.Errorf("expected an error for a hermetic command without chdir")
+	}
+}
+
+func TestHermeticSandboxOutDirOutsideSourceTree(t *testing.T) {
+	if err := exec.Command("unshare", "--user", "--mount", "true").Run(); err != nil {
+		t.Skip("user namespaces are not available:", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	top := filepath.Join(dir, "top")
+	outDir := filepath.Join(dir, "out", "soong")
+	for file, contents := range map[string]string{
+		filepath.Join(outDir, "declared.txt"):   "declared\n",
+		filepath.Join(outDir, "undeclared.txt"): "undeclared\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(top, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(top); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// The output directory is derived from --sandbox-path.
+	defer func(old string) { sandboxesRoot = old }(sandboxesRoot)
+	sandboxesRoot = filepath.Join(outDir, ".temp")
+
+	undeclared := filepath.Join(outDir, "undeclared.txt")
+	command := &sbox_proto.Command{
+		Chdir: proto.Bool(true),
+		Command: proto.String("cat declared.txt > result.txt && " +
+			"if cat " + undeclared + " 2>/dev/null; then exit 1; fi"),
+		CopyBefore: []*sbox_proto.Copy{
+			{From: proto.String(filepath.Join(outDir, "declared.txt")), To: proto.String("declared.txt")},
+		},
+		CopyAfter: []*sbox_proto.Copy{
+			{From: proto.String("result.txt"), To: proto.String("result.txt")},
+		},
+	}
+
+	if _, _, err := runCommand(command, filepath.Join(sandboxesRoot, "sbox", "test"), true, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ioutil.ReadFile("result.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(result), "declared\n"; g != w {
+		t.Errorf("expected result %q, got %q", w, g)
+	}
+}
+
+func TestHiddenDirs(t *testing.T) {
+	for _, test := range []struct {
+		src, out string
+		want     []string
+	}{
+		{"/top", "/top/out/soong", []string{"/top"}},
+		{"/top", "/out/soong", []string{"/top", "/out/soong"}},
+		{"/top", "/topout/soong", []string{"/top", "/topout/soong"}},
+		{"/out/soong/top", "/out/soong", []string{"/out/soong"}},
+	} {
+		if g := hiddenDirs(test.src, test.out); !reflect.DeepEqual(g, test.want) {
+			t.Errorf("hiddenDirs(%q, %q): want %q, got %q", test.src, test.out, test.want, g)
+		}
+	}
+}
@@ -71,6 +71,14 @@ func usageViolation(violation string) {
 }
 
 func main() {
+	if os.Args[0] == namespaceChildArg0 {
+		// sbox reexecuted itself to run a command in a hermetic sandbox.
+		os.Exit(runNamespaceChild())
+	} else if os.Args[0] == traceChildArg0 {
+		// sbox reexecuted itself to trace the files read by a command.
+		os.Exit(runTraceChild())
+	}
+
 	flag.Usage = func() {
 		usageViolation("")
 	}
@@ -155,16 +163,28 @@ func run() error {
 		}
 	}()
 
+	cache, err := newActionCache(os.Getenv("SBOX_CACHE_DIR"), os.Getenv("SBOX_CACHE_MAX_SIZE"))
+	if err != nil {
+		return err
+	}
+
 	// If there is more than one command in the manifest use a separate directory for each one.
 	useSubDir := len(manifest.Commands) > 1
 	var commandDepFiles []string
 
+	// Hermetic sandboxes don't need to be traced, reading undeclared inputs fails in them.
+	trace := manifest.GetInputTrace()
+	if manifest.GetHermetic() {
+		trace = nil
+	}
+	var undeclared []string
+
 	for i, command := range manifest.Commands {
 		localTempDir := tempDir
 		if useSubDir {
 			localTempDir = filepath.Join(localTempDir, strconv.Itoa(i))
 		}
-		depFile, err := runCommand(command, localTempDir)
+		depFile, commandUndeclared, err := runCommand(command, localTempDir, manifest.GetHermetic(), trace, cache)
 		if err != nil {
 			// Running the command failed, keep the temporary output directory around in
 			// case a user wants to inspect it for debugging purposes.  Soong will delete
@@ -175,6 +195,7 @@ func run() error {
 		if depFile != "" {
 			commandDepFiles = append(commandDepFiles, depFile)
 		}
+		undeclared = append(undeclared, commandUndeclared...)
 	}
 
 	outputDepFile := manifest.GetOutputDepfile()
@@ -191,6 +212,13 @@ func run() error {
 		}
 	}
 
+	if trace != nil {
+		err = writeInputTraceReport(trace, manifestFile, undeclared)
+		if err != nil {
+			return fmt.Errorf("failed writing input trace report: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -211,12 +239,30 @@ func readManifest(file string) (*sbox_proto.Manifest, error) {
 	return &manifest, nil
 }
 
+// namespaceMount describes a file or directory that is bind mounted into a hermetic sandbox.
+type namespaceMount struct {
+	// The absolute path of the file or directory outside the sandbox.
+	From string
+	// The absolute path that the file or directory is mounted at.
+	To string
+	// If false, the mount is read-only.
+	Writable bool
+}
+
 // runCommand runs a single command from a manifest.  If the command references the
-// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.
-func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, err error) {
+// __SBOX_DEPFILE__ placeholder it returns the name of the depfile that was used.  If hermetic
+// is true the command is run in a hermetic sandbox, see the hermetic field of the Manifest.  If
+// trace is not nil it also returns the files that the command read but are not allowed by trace.
+// If cache is not nil the outputs of commands that run with chdir are restored from and stored
+// in it.
+func runCommand(command *sbox_proto.Command, tempDir string, hermetic bool,
+	trace *sbox_proto.InputTrace, cache *actionCache) (depFile string, undeclared []string, err error) {
 	rawCommand := command.GetCommand()
 	if rawCommand == "" {
-		return "", fmt.Errorf("command is required")
+		return "", nil, fmt.Errorf("command is required")
+	}
+	if hermetic && !command.GetChdir() {
+		return "", nil, fmt.Errorf("hermetic sandboxes require chdir")
 	}
 
 	pathToTempDirInSbox := tempDir
@@ -226,21 +272,13 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 
 	err = os.MkdirAll(tempDir, 0777)
 	if err != nil {
-		return "", fmt.Errorf("failed to create %q: %w", tempDir, err)
-	}
-
-	// Copy in any files specified by the manifest.
-	err = copyFiles(command.CopyBefore, "", tempDir, false)
-	if err != nil {
-		return "", err
-	}
-	err = copyRspFiles(command.RspFiles, tempDir, pathToTempDirInSbox)
-	if err != nil {
-		return "", err
+		return "", nil, fmt.Errorf("failed to create %q: %w", tempDir, err)
 	}
 
+	var sandboxDepFile string
 	if strings.Contains(rawCommand, depFilePlaceholder) {
 		depFile = filepath.Join(pathToTempDirInSbox, "deps.d")
+		sandboxDepFile = filepath.Join(tempDir, "deps.d")
 		rawCommand = strings.Replace(rawCommand, depFilePlaceholder, depFile, -1)
 	}
 
@@ -248,30 +286,98 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 		rawCommand = strings.Replace(rawCommand, sandboxDirPlaceholder, pathToTempDirInSbox, -1)
 	}
 
+	// Commands that run with chdir only use the files in the sandbox directory, so their outputs
+	// can be cached.  The key only depends on the manifest and the files it names, so a hit
+	// doesn't need to copy the inputs.  Traced commands always run to report the files that they
+	// read.
+	var cacheKey string
+	if cache != nil && command.GetChdir() && trace == nil {
+		cacheKey, err = cache.key(command)
+		if err != nil {
+			return "", nil, err
+		}
+		if output, ok := cache.restore(cacheKey, command.CopyAfter, sandboxDepFile); ok {
+			// Print the output of the command again, like ninja does for commands that run.
+			os.Stdout.Write(output)
+			return depFile, nil, nil
+		}
+	}
+
+	// Copy in any files specified by the manifest.  A hermetic sandbox mounts them instead, except
+	// for the ones that need to be made executable.
+	var mounts []namespaceMount
+	copyInput := func(from, to string) error {
+		return copyOneFile(from, to, false, false)
+	}
+	if hermetic {
+		copyInput = func(from, to string) error {
+			mounts = append(mounts, namespaceMount{From: from, To: to})
+			return nil
+		}
+		for _, copyPair := range command.CopyBefore {
+			if copyPair.GetExecutable() {
+				err = copyFiles([]*sbox_proto.Copy{copyPair}, "", tempDir, false)
+			} else {
+				err = copyInput(copyPair.GetFrom(), joinPath(tempDir, copyPair.GetTo()))
+			}
+			if err != nil {
+				return "", nil, err
+			}
+		}
+	} else {
+		err = copyFiles(command.CopyBefore, "", tempDir, false)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	err = copyRspFiles(command.RspFiles, tempDir, pathToTempDirInSbox, copyInput)
+	if err != nil {
+		return "", nil, err
+	}
+
 	// Emulate ninja's behavior of creating the directories for any output files before
 	// running the command.
 	err = makeOutputDirs(command.CopyAfter, tempDir)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	cmd := exec.Command("bash", "-c", rawCommand)
-	buf := &bytes.Buffer{}
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = buf
-	cmd.Stderr = buf
-
 	if command.GetChdir() {
-		cmd.Dir = tempDir
 		path := os.Getenv("PATH")
 		absPath, err := makeAbsPathEnv(path)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		err = os.Setenv("PATH", absPath)
 		if err != nil {
-			return "", fmt.Errorf("Failed to update PATH: %w", err)
+			return "", nil, fmt.Errorf("Failed to update PATH: %w", err)
+		}
+	}
+
+	var cmd *exec.Cmd
+	var traceFile *os.File
+	if hermetic {
+		// The sandboxes are kept in the output directory, which must be hidden too when it is
+		// outside of the source tree.
+		cmd, err = hermeticCommand(rawCommand, tempDir, filepath.Dir(sandboxesRoot), mounts)
+		if err != nil {
+			return "", nil, err
+		}
+	} else if trace != nil {
+		cmd, traceFile, err = tracedCommand(rawCommand)
+		if err != nil {
+			return "", nil, err
 		}
+		defer traceFile.Close()
+	} else {
+		cmd = exec.Command("bash", "-c", rawCommand)
+	}
+	buf := &bytes.Buffer{}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = buf
+	cmd.Stderr = buf
+	if command.GetChdir() {
+		cmd.Dir = tempDir
 	}
 	err = cmd.Run()
 
@@ -292,13 +398,29 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 				"The failing command line was:\n"+
 				"%s\n",
 			tempDir, rawCommand)
+		if hermetic {
+			fmt.Fprintf(os.Stderr,
+				"The sandbox was hermetic, only the declared inputs and tools were visible in the\n"+
+					"source tree.\n")
+		}
 	}
 
 	// Write the command's combined stdout/stderr.
 	os.Stdout.Write(buf.Bytes())
 
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+
+	if traceFile != nil {
+		tracedFiles, err := readTrace(traceFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read the files read by the command: %w", err)
+		}
+		undeclared, err = undeclaredInputs(tracedFiles, trace, tempDir)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
 	missingOutputErrors := validateOutputFiles(command.CopyAfter, tempDir)
@@ -330,12 +452,17 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 			}
 		}
 
-		return "", errors.New(errorMessage)
+		return "", nil, errors.New(errorMessage)
 	}
+	if cacheKey != "" {
+		// Failing to store the outputs only makes the cache less effective.
+		cache.store(cacheKey, command.CopyAfter, tempDir, sandboxDepFile, buf.Bytes())
+	}
+
 	// the created files match the declared files; now move them
 	err = moveFiles(command.CopyAfter, tempDir, "")
 
-	return depFile, nil
+	return depFile, undeclared, nil
 }
 
 // makeOutputDirs creates directories in the sandbox dir for every file that has a rule to be copied
@@ -447,10 +574,11 @@ func copyOneFile(from string, to string, forceExecutable, allowFromNotExists boo
 }
 
 // copyRspFiles copies rsp files into the sandbox with path mappings, and also copies the files
-// listed into the sandbox.
-func copyRspFiles(rspFiles []*sbox_proto.RspFile, toDir, toDirInSandbox string) error {
+// listed into the sandbox using copyInput.
+func copyRspFiles(rspFiles []*sbox_proto.RspFile, toDir, toDirInSandbox string,
+	copyInput func(from, to string) error) error {
 	for _, rspFile := range rspFiles {
-		err := copyOneRspFile(rspFile, toDir, toDirInSandbox)
+		err := copyOneRspFile(rspFile, toDir, toDirInSandbox, copyInput)
 		if err != nil {
 			return err
 		}
@@ -459,8 +587,9 @@ func copyRspFiles(rspFiles []*sbox_proto.RspFile, toDir, toDirInSandbox string)
 }
 
 // copyOneRspFiles copies an rsp file into the sandbox with path mappings, and also copies the files
-// listed into the sandbox.
-func copyOneRspFile(rspFile *sbox_proto.RspFile, toDir, toDirInSandbox string) error {
+// listed into the sandbox using copyInput.
+func copyOneRspFile(rspFile *sbox_proto.RspFile, toDir, toDirInSandbox string,
+	copyInput func(from, to string) error) error {
 	in, err := os.Open(rspFile.GetFile())
 	if err != nil {
 		return err
@@ -478,7 +607,7 @@ func copyOneRspFile(rspFile *sbox_proto.RspFile, toDir, toDirInSandbox string) e
 		to := applyPathMappings(rspFile.PathMappings, from)
 
 		// Copy the file into the sandbox.
-		err := copyOneFile(from, joinPath(toDir, to), false, false)
+		err := copyInput(from, joinPath(toDir, to))
 		if err != nil {
 			return err
 		}
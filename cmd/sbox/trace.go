@@ -0,0 +1,157 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// undeclaredInputs returns the files in tracedFiles that are in the $PWD but are not allowed by
+// trace, relative to the $PWD.  Files in the sandbox directory are always allowed, as are the files
+// in the $PATH directories, the shared libraries of the declared tools and directories themselves.
+func undeclaredInputs(tracedFiles []string, trace *sbox_proto.InputTrace, sandboxDir string) ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return filepath.Clean(path)
+		}
+		return filepath.Join(wd, path)
+	}
+
+	declared := make(map[string]bool)
+	for _, input := range trace.GetDeclaredInputs() {
+		declared[abs(input)] = true
+	}
+
+	// Directories whose contents are allowed.
+	allowedDirs := []string{abs(sandboxDir)}
+
+	// Directories whose shared libraries are allowed.  Host tools load their shared libraries
+	// through an rpath of $ORIGIN/../lib64 or $ORIGIN/../lib, which the rule can't declare.
+	libDirs := make(map[string]bool)
+	for _, tool := range trace.GetDeclaredTools() {
+		declared[abs(tool)] = true
+		if filepath.Ext(tool) != ".so" {
+			toolRoot := filepath.Dir(filepath.Dir(abs(tool)))
+			libDirs[filepath.Join(toolRoot, "lib64")] = true
+			libDirs[filepath.Join(toolRoot, "lib")] = true
+		}
+	}
+
+	// Prefixes of files that are allowed.  The $PATH directories are prefixes rather than
+	// directories to include the path interposer files that soong_ui puts next to them.
+	var allowedPrefixes []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.IsAbs(dir) {
+			allowedPrefixes = append(allowedPrefixes, filepath.Clean(dir))
+		}
+	}
+
+	var undeclared []string
+	for _, file := range tracedFiles {
+		rel, err := filepath.Rel(wd, file)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			// Files outside the $PWD are not part of the source or output trees.
+			continue
+		}
+		if declared[file] || inAnyDir(file, allowedDirs) || hasAnyPrefix(file, allowedPrefixes) {
+			continue
+		}
+		if libDirs[filepath.Dir(file)] && isSharedLibrary(file) {
+			continue
+		}
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		undeclared = append(undeclared, rel)
+	}
+	sort.Strings(undeclared)
+	return undeclared, nil
+}
+
+// isSharedLibrary returns true if path is named like a shared library, including versioned names
+// like libfoo.so.1.
+func isSharedLibrary(path string) bool {
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, ".so") || strings.Contains(base, ".so.")
+}
+
+func inAnyDir(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeInputTraceReport writes a report of the undeclared inputs of the commands in a manifest to
+// the report directory of trace, or removes the previous report if there are no undeclared inputs.
+func writeInputTraceReport(trace *sbox_proto.InputTrace, manifestFile string, undeclared []string) error {
+	hash := sha1.Sum([]byte(manifestFile))
+	reportFile := filepath.Join(trace.GetReportDir(), hex.EncodeToString(hash[:])+".textproto")
+
+	if len(undeclared) == 0 {
+		if err := os.Remove(reportFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Multiple commands in the manifest may read the same files.
+	sort.Strings(undeclared)
+	unique := undeclared[:1]
+	for _, file := range undeclared[1:] {
+		if file != unique[len(unique)-1] {
+			unique = append(unique, file)
+		}
+	}
+
+	report := &sbox_proto.InputTraceReport{
+		Module:           trace.Module,
+		Manifest:         &manifestFile,
+		UndeclaredInputs: unique,
+	}
+	data, err := prototext.MarshalOptions{Multiline: true}.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(trace.GetReportDir(), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(reportFile, data, 0666)
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const traceChildArg0 = "sbox-trace-child"
+
+func tracedCommand(rawCommand string) (*exec.Cmd, *os.File, error) {
+	return nil, nil, errors.New("tracing inputs is only supported on Linux")
+}
+
+func readTrace(traceFile *os.File) ([]string, error) {
+	return nil, errors.New("tracing inputs is only supported on Linux")
+}
+
+func runTraceChild() int {
+	fmt.Fprintln(os.Stderr, "sbox: tracing inputs is only supported on Linux")
+	return 1
+}
@@ -0,0 +1,266 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// A traced command is run by reexecuting sbox as a tracer, which starts the command with ptrace
+// and follows it and all of its descendants through their system calls.  The tracer writes the
+// files that were successfully opened for reading or executed to a file on fd 3 and exits with the
+// exit code of the command, so that sbox can handle it like any other command.  Reading the
+// system call arguments uses the x86_64 register layout, the only one that Soong runs on.
+
+// traceChildArg0 is the argv[0] that sbox is reexecuted with to trace a command.
+const traceChildArg0 = "sbox-trace-child"
+
+// PTRACE_O_EXITKILL kills the tracees if the tracer exits, it is missing from package syscall.
+const ptraceOExitKill = 0x100000
+
+// The openat2 system call and AT_FDCWD are missing from package syscall.
+const (
+	sysOpenat2 = 437
+	atFdcwd    = -100
+)
+
+const ptraceOptions = syscall.PTRACE_O_TRACESYSGOOD | syscall.PTRACE_O_TRACEFORK |
+	syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACECLONE | syscall.PTRACE_O_TRACEEXEC |
+	ptraceOExitKill
+
+// tracedCommand returns a command that runs rawCommand and records the files that it reads, and a
+// file that they are written to while the command runs.
+func tracedCommand(rawCommand string) (*exec.Cmd, *os.File, error) {
+	// Pass the list of files as an unlinked temporary file, it can be larger than a pipe buffer.
+	traceFile, err := ioutil.TempFile("", "sbox-trace")
+	if err != nil {
+		return nil, nil, err
+	}
+	os.Remove(traceFile.Name())
+
+	cmd := exec.Command("/proc/self/exe")
+	cmd.Args = []string{traceChildArg0, rawCommand}
+	cmd.ExtraFiles = []*os.File{traceFile}
+	return cmd, traceFile, nil
+}
+
+// readTrace returns the files recorded in a file returned by tracedCommand after the command has
+// finished.
+func readTrace(traceFile *os.File) ([]string, error) {
+	if _, err := traceFile.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	var files []string
+	scanner := bufio.NewScanner(traceFile)
+	for scanner.Scan() {
+		files = append(files, scanner.Text())
+	}
+	return files, scanner.Err()
+}
+
+// tracee is the state of a traced thread.
+type tracee struct {
+	// True between the syscall-enter-stop and syscall-exit-stop of a system call.
+	inSyscall bool
+
+	// The file that the current system call opens, if it opens one for reading.
+	file string
+}
+
+// runTraceChild runs the command in os.Args[1] under ptrace and writes the files that it reads to
+// fd 3.  It returns the exit code of the command.
+func runTraceChild() int {
+	traceFile := os.NewFile(3, "trace")
+	defer traceFile.Close()
+
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "sbox: expected a command to trace")
+		return 1
+	}
+
+	// All ptrace requests must come from the thread that started the tracee.
+	runtime.LockOSThread()
+
+	cmd := exec.Command("bash", "-c", os.Args[1])
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Ptrace: true}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox:", err)
+		return 1
+	}
+	pid := cmd.Process.Pid
+
+	// The command stops when it executes bash.
+	var status syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &status, syscall.WALL, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox:", os.NewSyscallError("wait4", err))
+		return 1
+	}
+	if err := syscall.PtraceSetOptions(pid, ptraceOptions); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox:", os.NewSyscallError("ptrace", err))
+		return 1
+	}
+
+	files := make(map[string]bool)
+	tracees := map[int]*tracee{pid: &tracee{}}
+	exitCode := 1
+	resume := pid
+	signal := 0
+	for {
+		if resume != 0 {
+			// Errors from resuming a tracee mean it was killed, it will be reported by wait4.
+			syscall.PtraceSyscall(resume, signal)
+		}
+
+		wpid, err := syscall.Wait4(-1, &status, syscall.WALL, nil)
+		if err == syscall.EINTR {
+			resume = 0
+			continue
+		} else if err == syscall.ECHILD {
+			break
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, "sbox:", os.NewSyscallError("wait4", err))
+			return 1
+		}
+
+		if status.Exited() || status.Signaled() {
+			delete(tracees, wpid)
+			if wpid == pid {
+				if status.Signaled() {
+					exitCode = 128 + int(status.Signal())
+				} else {
+					exitCode = status.ExitStatus()
+				}
+			}
+			resume = 0
+			continue
+		}
+
+		t := tracees[wpid]
+		if t == nil {
+			// A new child of a tracee, it starts out stopped by SIGSTOP.
+			t = &tracee{}
+			tracees[wpid] = t
+		}
+		resume, signal = wpid, 0
+
+		switch stopSignal := status.StopSignal(); {
+		case stopSignal == syscall.SIGTRAP|0x80:
+			t.inSyscall = !t.inSyscall
+			if t.inSyscall {
+				t.file = openedFile(wpid)
+			} else if t.file != "" {
+				var regs syscall.PtraceRegs
+				if err := syscall.PtraceGetRegs(wpid, &regs); err == nil && int64(regs.Rax) >= 0 {
+					files[t.file] = true
+				}
+				t.file = ""
+			}
+		case stopSignal == syscall.SIGTRAP && status.TrapCause() != 0:
+			// A fork, clone or exec event, the new children are traced automatically.
+		case stopSignal == syscall.SIGSTOP:
+			// The initial stop of a new child, or a stop that can't be told apart from it.
+		default:
+			signal = int(stopSignal)
+		}
+	}
+
+	w := bufio.NewWriter(traceFile)
+	for file := range files {
+		fmt.Fprintln(w, file)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox: failed to write trace:", err)
+		return 1
+	}
+	return exitCode
+}
+
+// openedFile returns the absolute path of the file that the system call that the tracee pid just
+// entered opens for reading or executes, or "" if it doesn't.
+func openedFile(pid int) string {
+	var regs syscall.PtraceRegs
+	if err := syscall.PtraceGetRegs(pid, &regs); err != nil {
+		return ""
+	}
+
+	dirfd, pathAddr, flags := int64(atFdcwd), uintptr(0), uint64(0)
+	switch regs.Orig_rax {
+	case syscall.SYS_OPEN:
+		pathAddr, flags = uintptr(regs.Rdi), regs.Rsi
+	case syscall.SYS_OPENAT:
+		dirfd, pathAddr, flags = int64(int32(regs.Rdi)), uintptr(regs.Rsi), regs.Rdx
+	case sysOpenat2:
+		// The flags are the first field of the struct open_how.
+		var how [8]byte
+		if _, err := syscall.PtracePeekData(pid, uintptr(regs.Rdx), how[:]); err != nil {
+			return ""
+		}
+		dirfd, pathAddr = int64(int32(regs.Rdi)), uintptr(regs.Rsi)
+		flags = uint64(how[0]) | uint64(how[1])<<8 | uint64(how[2])<<16 | uint64(how[3])<<24
+	case syscall.SYS_EXECVE:
+		pathAddr = uintptr(regs.Rdi)
+	default:
+		return ""
+	}
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		return ""
+	}
+
+	path := peekString(pid, pathAddr)
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		dir := "cwd"
+		if dirfd != atFdcwd {
+			dir = filepath.Join("fd", strconv.FormatInt(dirfd, 10))
+		}
+		base, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), dir))
+		if err != nil {
+			return ""
+		}
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
+
+// peekString reads a NUL terminated string from the memory of the tracee pid.
+func peekString(pid int, addr uintptr) string {
+	var buf []byte
+	chunk := make([]byte, 256)
+	for len(buf) < 4096 {
+		n, err := syscall.PtracePeekData(pid, addr+uintptr(len(buf)), chunk)
+		if i := bytes.IndexByte(chunk[:n], 0); i >= 0 {
+			return string(append(buf, chunk[:i]...))
+		}
+		if err != nil || n == 0 {
+			break
+		}
+		buf = append(buf, chunk[:n]...)
+	}
+	return ""
+}
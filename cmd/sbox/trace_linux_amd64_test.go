@@ -0,0 +1,106 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTracedCommand(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, file := range []string{
+		"src/declared.txt",
+		"src/undeclared.txt",
+		"src/dir/file.txt",
+		"prebuilts/tool/bin/tool",
+		"prebuilts/tool/lib64/libtool.so",
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(file+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	command := &sbox_proto.Command{
+		Command: proto.String("cat src/declared.txt prebuilts/tool/lib64/libtool.so > /dev/null && " +
+			"(cd src && cat undeclared.txt > /dev/null) && ls src/dir > /dev/null && " +
+			"echo output > out/sbox/output.txt && cat out/sbox/output.txt src/missing.txt 2> /dev/null; " +
+			"true"),
+	}
+	trace := &sbox_proto.InputTrace{
+		Module:         proto.String("src:module"),
+		DeclaredInputs: []string{"src/declared.txt"},
+		DeclaredTools:  []string{"prebuilts/tool/bin/tool"},
+		ReportDir:      proto.String("out/traces"),
+	}
+
+	_, undeclared, err := runCommand(command, "out/sbox", false, trace, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := undeclared, []string{"src/undeclared.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected undeclared inputs %q, got %q", w, g)
+	}
+
+	if err := writeInputTraceReport(trace, "out/sbox.textproto", undeclared); err != nil {
+		t.Fatal(err)
+	}
+	reports, err := filepath.Glob("out/traces/*.textproto")
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("expected one report, got %q: %v", reports, err)
+	}
+	data, err := ioutil.ReadFile(reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report sbox_proto.InputTraceReport
+	if err := prototext.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := report.GetModule(), "src:module"; g != w {
+		t.Errorf("expected module %q, got %q", w, g)
+	}
+	if g, w := report.GetUndeclaredInputs(), []string{"src/undeclared.txt"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("expected reported undeclared inputs %q, got %q", w, g)
+	}
+
+	// The report is removed when the command no longer reads undeclared inputs.
+	if err := writeInputTraceReport(trace, "out/sbox.textproto", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(reports[0]); !os.IsNotExist(err) {
+		t.Errorf("expected report to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"android/soong/cmd/sbox/sbox_proto"
+)
+
+func TestUndeclaredInputs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	files := []string{
+		"src/declared.txt",
+		"src/undeclared.txt",
+		"out/sbox/output.txt",
+		"prebuilts/tool/bin/tool",
+		"prebuilts/tool/bin/other_tool",
+		"prebuilts/tool/lib/data.txt",
+		"prebuilts/tool/lib64/libtool.so",
+		"prebuilts/tool/lib64/libversioned.so.1",
+		"prebuilts/tool/lib64/data.txt",
+		"prebuilts/tool/lib64/nested/libnested.so",
+		"prebuilts/packaged/bin/packaged",
+		"prebuilts/packaged/lib64/libpackaged.so",
+		"prebuilts/packaged/lib64/libundeclared.so",
+	}
+	var tracedFiles []string
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(file+"\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		tracedFiles = append(tracedFiles, filepath.Join(dir, file))
+	}
+	tracedFiles = append(tracedFiles, filepath.Join(dir, "src"), "/etc/passwd")
+
+	trace := &sbox_proto.InputTrace{
+		DeclaredInputs: []string{"src/declared.txt"},
+		DeclaredTools: []string{
+			"prebuilts/tool/bin/tool",
+			"prebuilts/packaged/lib64/libpackaged.so",
+		},
+	}
+
+	undeclared, err := undeclaredInputs(tracedFiles, trace, "out/sbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the declared tools and the shared libraries next to a declared tool's bin directory
+	// are allowed, not everything that happens to be installed next to a tool.
+	want := []string{
+		"prebuilts/packaged/bin/packaged",
+		"prebuilts/packaged/lib64/libundeclared.so",
+		"prebuilts/tool/bin/other_tool",
+		"prebuilts/tool/lib/data.txt",
+		"prebuilts/tool/lib64/data.txt",
+		"prebuilts/tool/lib64/nested/libnested.so",
+		"src/undeclared.txt",
+	}
+	if !reflect.DeepEqual(undeclared, want) {
+		t.Errorf("expected undeclared inputs %q, got %q", want, undeclared)
+	}
+}
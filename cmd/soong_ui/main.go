@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -56,6 +57,10 @@ type command struct {
 	// Sets a prefix string to use for filenames of log files.
 	logsPrefix string
 
+	// Keep the metrics of the command in the metrics history that is checked by
+	// --metrics-regressions-mode.
+	recordMetricsHistory bool
+
 	// Creates the build configuration based on the args and build context.
 	config func(ctx build.Context, args ...string) build.Config
 
@@ -74,8 +79,9 @@ var commands []command = []command{
 		config: func(ctx build.Context, args ...string) build.Config {
 			return build.NewConfig(ctx, args...)
 		},
-		stdio: stdio,
-		run:   runMake,
+		stdio:                stdio,
+		run:                  runMake,
+		recordMetricsHistory: true,
 	}, {
 		flag:         "--dumpvar-mode",
 		description:  "print the value of the legacy make variable VAR to stdout",
@@ -93,11 +99,38 @@ var commands []command = []command{
 		stdio:        customStdio,
 		run:          dumpVars,
 	}, {
-		flag:        "--build-mode",
-		description: "build modules based on the specified build action",
-		config:      buildActionConfig,
-		stdio:       stdio,
-		run:         runMake,
+		flag:                 "--build-mode",
+		description:          "build modules based on the specified build action",
+		config:               buildActionConfig,
+		stdio:                stdio,
+		run:                  runMake,
+		recordMetricsHistory: true,
+	}, {
+		flag:         "--metrics-regressions-mode",
+		description:  "compare the last build against the median of recent builds with the same config",
+		simpleOutput: true,
+		logsPrefix:   "metrics-regressions-",
+		config:       dumpVarConfig,
+		stdio:        customStdio,
+		run:          metricsRegressions,
+	}, {
+		flag:         "--finder-daemon-mode",
+		description:  "watch the source tree to speed up finding build files in other soong_ui processes",
+		simpleOutput: true,
+		logsPrefix:   "finder-daemon-",
+		config:       dumpVarConfig,
+		stdio:        stdio,
+		run:          runFinderDaemon,
+	}, {
+		flag:        "--undeclared-inputs-mode",
+		description: "build the modules by the target name and report the files that sandboxed actions read without declaring them",
+		config: func(ctx build.Context, args ...string) build.Config {
+			config := build.NewConfig(ctx, args...)
+			build.EnableInputTracing(config)
+			return config
+		},
+		stdio: stdio,
+		run:   runUndeclaredInputs,
 	},
 }
 
@@ -236,6 +269,10 @@ func main() {
 	stat.AddOutput(status.NewErrorLog(log, filepath.Join(logsDir, c.logsPrefix+"error.log")))
 	stat.AddOutput(status.NewProtoErrorLog(log, buildErrorFile))
 	stat.AddOutput(status.NewCriticalPath(log))
+	if config.Environment().IsEnvTrue("SOONG_UI_EXPLAIN_REBUILDS") {
+		stat.AddOutput(status.NewRebuildExplainer(log, []string{config.OutDir(), config.SoongOutDir()},
+			filepath.Join(logsDir, c.logsPrefix+"rebuild_explanations.txt")))
+	}
 	stat.AddOutput(status.NewBuildProgressLog(log, filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")))
 
 	buildCtx.Verbosef("Detected %.3v GB total RAM", float32(config.TotalRAM())/(1024*1024*1024))
@@ -254,6 +291,9 @@ func main() {
 			soongMetricsFile,         // high level metrics related to this build system.
 			config.BazelMetricsDir(), // directory that contains a set of bazel metrics.
 		}
+		if c.recordMetricsHistory {
+			defer recordMetricsHistory(buildCtx, config, soongMetricsFile)
+		}
 		defer build.UploadMetrics(buildCtx, config, c.simpleOutput, buildStarted, files...)
 		defer met.Dump(soongMetricsFile)
 		defer build.DumpRBEMetrics(buildCtx, config, rbeMetricsFile)
@@ -421,6 +461,110 @@ func dumpVars(ctx build.Context, config build.Config, args []string, _ string) {
 	}
 }
 
+// metricsHistoryDir returns the directory that keeps the metrics of recent builds.
+func metricsHistoryDir(config build.Config) string {
+	return filepath.Join(config.OutDir(), "metrics_history")
+}
+
+// recordMetricsHistory adds the metrics of the current build to the metrics history.
+func recordMetricsHistory(ctx build.Context, config build.Config, metricsFile string) {
+	if err := metrics.AddToHistory(metricsHistoryDir(config), metricsFile, metrics.MaxHistory); err != nil {
+		ctx.Verbosef("Failed to add %s to the metrics history: %s", metricsFile, err)
+	}
+}
+
+func metricsRegressions(ctx build.Context, config build.Config, args []string, _ string) {
+	flags := flag.NewFlagSet("metrics-regressions", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(ctx.Writer, "usage: %s --metrics-regressions-mode [--threshold=PERCENT] [--builds=N]\n\n", os.Args[0])
+		fmt.Fprintln(ctx.Writer, "In metrics regressions mode, compare the time spent in each phase of the last")
+		fmt.Fprintln(ctx.Writer, "build against the median of the previous builds with the same configuration")
+		fmt.Fprintln(ctx.Writer, "and build command, and print the phases that regressed. Exits with an error")
+		fmt.Fprintln(ctx.Writer, "if any phase regressed.")
+		fmt.Fprintln(ctx.Writer, "")
+		fmt.Fprintf(ctx.Writer, "The metrics of the last %d builds are kept in $OUT_DIR/metrics_history.\n", metrics.MaxHistory)
+		fmt.Fprintln(ctx.Writer, "")
+		flags.PrintDefaults()
+	}
+	threshold := flags.Float64("threshold", 10, "Percentage that a phase must regress by to be reported")
+	minIncrease := flags.Duration("min-increase", 5*time.Second, "Minimum increase of a phase to be reported")
+	builds := flags.Int("builds", 10, "Maximum number of previous builds to compute the median from")
+	minBuilds := flags.Int("min-builds", 3, "Minimum number of previous builds required to compare a phase")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	history, err := metrics.ReadHistory(metricsHistoryDir(config))
+	if err != nil {
+		ctx.Fatalf("Failed to read the metrics history: %s", err)
+	}
+	if len(history) == 0 {
+		fmt.Println("No builds in the metrics history.")
+		return
+	}
+
+	current, previous := history[len(history)-1], history[:len(history)-1]
+	regressions := metrics.FindRegressions(current, previous, metrics.RegressionOptions{
+		Threshold:   *threshold / 100,
+		MinIncrease: *minIncrease,
+		MaxBuilds:   *builds,
+		MinBuilds:   *minBuilds,
+	})
+
+	if len(regressions) == 0 {
+		fmt.Println("No regressions found.")
+		return
+	}
+	for _, r := range regressions {
+		fmt.Println(r)
+	}
+	ctx.Fatalf("%d phases regressed", len(regressions))
+}
+
+// runFinderDaemon runs a finder daemon until soong_ui is interrupted.
+func runFinderDaemon(ctx build.Context, config build.Config, args []string, _ string) {
+	build.RunFinderDaemon(ctx, config)
+}
+
+// runUndeclaredInputs builds like --make-mode with input tracing enabled, then writes the files that
+// sandboxed actions read without declaring them to a file in the logs dir and fails if there are
+// any.
+func runUndeclaredInputs(ctx build.Context, config build.Config, args []string, logsDir string) {
+	runMake(ctx, config, args, logsDir)
+
+	undeclared, err := build.UndeclaredInputs(config)
+	if err != nil {
+		ctx.Fatalf("Failed to read the undeclared inputs: %s", err)
+	}
+	if len(undeclared) == 0 {
+		fmt.Fprintln(ctx.Writer, "No undeclared inputs found.")
+		return
+	}
+
+	var modules []string
+	for module := range undeclared {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	reportFile := filepath.Join(logsDir, "undeclared_inputs.txt")
+	report := &strings.Builder{}
+	for _, module := range modules {
+		fmt.Fprintf(report, "%s:\n", module)
+		for _, input := range undeclared[module] {
+			fmt.Fprintf(report, "    %s\n", input)
+		}
+		fmt.Fprintf(ctx.Writer, "%s: %d undeclared inputs\n", module, len(undeclared[module]))
+	}
+	if err := ioutil.WriteFile(reportFile, []byte(report.String()), 0666); err != nil {
+		ctx.Fatalf("Failed to write %s: %s", reportFile, err)
+	}
+	ctx.Fatalf("%d modules read undeclared inputs, see %s", len(modules), reportFile)
+}
+
 func stdio() terminal.StdioInterface {
 	return terminal.StdioImpl{}
 }
@@ -48,6 +48,12 @@ func init() {
 	pctx.Import("android/soong/android")
 	RegisterPrebuiltEtcBuildComponents(android.InitRegistrationContext)
 	snapshot.RegisterSnapshotAction(generatePrebuiltSnapshot)
+
+	for _, moduleType := range []string{"prebuilt_etc", "prebuilt_etc_host", "prebuilt_root",
+		"prebuilt_usr_share", "prebuilt_usr_share_host", "prebuilt_font", "prebuilt_firmware",
+		"prebuilt_dsp", "prebuilt_rfsa"} {
+		android.RegisterIncrementalModuleType(moduleType)
+	}
 }
 
 func RegisterPrebuiltEtcBuildComponents(ctx android.RegistrationContext) {
@@ -356,6 +362,24 @@ func (p *PrebuiltEtc) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
+var _ android.IncrementalModule = (*PrebuiltEtc)(nil)
+
+func (p *PrebuiltEtc) SaveAnalysisState(state *android.AnalysisState) {
+	state.SetPath("source_file", p.sourceFilePath)
+	state.SetPath("output_file", p.outputFilePath)
+	state.SetPath("install_dir", p.installDirPath)
+}
+
+func (p *PrebuiltEtc) RestoreAnalysisState(ctx android.ModuleContext, state *android.AnalysisState) {
+	p.sourceFilePath = state.Path("source_file")
+	p.outputFilePath, _ = state.Path("output_file").(android.OutputPath)
+	p.installDirPath, _ = state.Path("install_dir").(android.InstallPath)
+
+	if !p.Installable() {
+		p.SkipInstall()
+	}
+}
+
 func (p *PrebuiltEtc) AndroidMkEntries() []android.AndroidMkEntries {
 	nameSuffix := ""
 	if p.inRamdisk() && !p.onlyInRamdisk() {
@@ -0,0 +1,514 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"android/soong/finder/fs"
+)
+
+// This file provides a Daemon that keeps the cache of a Finder up to date by watching
+// the cached directories for changes, and serves the cache to Finders in other processes
+// over a unix socket. A Finder created with NewWithDaemon loads the cache from the Daemon
+// instead of calling Stat on every cached directory, which can take several seconds on
+// a large source tree even when nothing changed.
+
+// The protocol is:
+// 1. The client sends its cacheMetadata as a line of json
+// 2. The Daemon makes sure it has seen every change to the filesystem made before the
+//    request by creating a cookie file in a watched directory and waiting for the event
+//    about that file, which is queued after the events for every earlier change
+// 3. The Daemon updates the directories that changed, and replies with a line of json
+//    containing a daemonResponse
+// 4. If there was no error, the Daemon then sends its cache database, in the same format
+//    as the database on disk
+// The client falls back to checking the filesystem itself if any of these steps fails,
+// for example because the Daemon isn't running or was started with different CacheParams.
+
+// daemonTimeout is how long a client waits for the Daemon to answer before giving up,
+// and how long the Daemon waits for its own cookie file to be reported
+const daemonTimeout = 10 * time.Second
+
+// a daemonResponse is the first line of the Daemon's reply to a request
+type daemonResponse struct {
+	// Error tells why the Daemon couldn't serve the request, if it couldn't
+	Error string
+}
+
+// a watcher reports changes to the entries of the directories it watches by calling
+// onChange with the path of the directory and the name of the entry that changed.
+// The name is empty if the directory itself changed. If the watcher loses events,
+// it calls onOverflow instead.
+type watcher interface {
+	// watch starts watching the directory at <path>
+	watch(path string) error
+
+	close() error
+}
+
+// NewWithDaemon is like New, but loads the cache from the Daemon listening on <socketPath>
+// if there is one, instead of checking every cached directory for changes.
+// NewWithDaemon falls back to checking the filesystem if the Daemon isn't running, was
+// started with different parameters or fails to answer.
+func NewWithDaemon(cacheParams CacheParams, filesystem fs.FileSystem,
+	logger Logger, dbPath string, socketPath string) (f *Finder, err error) {
+	f = newUnloadedFinder(cacheParams, filesystem, logger, dbPath, defaultNumThreads)
+
+	err = f.loadFromDaemon(socketPath)
+	if err != nil {
+		f.verbosef("Not using finder daemon at %v: %v\n", socketPath, err)
+		f.nodes = *newPathMap("/")
+		f.loadFromFilesystem()
+	}
+
+	err = f.checkLoaded()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// loadFromDaemon populates the in-memory cache with the cache of the Daemon listening
+// on <socketPath>
+func (f *Finder) loadFromDaemon(socketPath string) error {
+	startTime := time.Now()
+
+	conn, err := net.DialTimeout("unix", socketPath, daemonTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(startTime.Add(daemonTimeout))
+
+	request, err := json.Marshal(f.cacheMetadata)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write(append(request, lineSeparator))
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+	responseBytes, err := f.readLine(reader)
+	if err != nil {
+		return err
+	}
+	var response daemonResponse
+	err = json.Unmarshal(responseBytes, &response)
+	if err != nil {
+		return err
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+
+	if !f.validateCacheHeader(reader) {
+		return errors.New("Cache header does not match")
+	}
+
+	// The Daemon has already checked every directory, so the stats in its database
+	// can be used as is.
+	tree := newPathMap("/")
+	for {
+		data, err := f.readLine(reader)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(data) > 0 {
+			cachedNodes, parseErr := f.parseCacheEntry(data)
+			if parseErr != nil {
+				return parseErr
+			}
+			for _, cachedNode := range cachedNodes {
+				container := tree.GetNode(cachedNode.Path, true)
+				container.mapNode = mapNode{
+					statResponse: cachedNode.statResponse,
+					FileNames:    cachedNode.FileNames,
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	tree.UpdateNumDescendentsRecursive()
+	f.nodes = *tree
+
+	f.verbosef("Loaded db from finder daemon in %v\n", time.Since(startTime))
+	return nil
+}
+
+// a Daemon keeps the cache of a Finder up to date and serves it to other processes
+type Daemon struct {
+	finder   *Finder
+	watcher  watcher
+	listener net.Listener
+	closed   int32
+
+	// directories that changed since the last request, written by the watcher
+	dirtyLock  sync.Mutex
+	dirty      map[string]bool
+	overflowed bool
+
+	// cookie files that the watcher reported
+	cookieDir    string
+	cookiePrefix string
+	cookieCount  int
+	cookies      chan string
+}
+
+// NewDaemon starts watching the directories cached by <f> and listening on <socketPath>.
+// <f> must have been created with New and the OS filesystem, and must not be used by
+// anything else after being passed to NewDaemon.
+// Call Serve to answer requests.
+func NewDaemon(f *Finder, socketPath string) (d *Daemon, err error) {
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a finder daemon is already listening on %v", socketPath)
+	}
+
+	// Cookies are written next to the socket, which is in the output directory, because the
+	// source tree may be read-only.
+	cookieDir, err := filepath.Abs(filepath.Dir(socketPath))
+	if err != nil {
+		return nil, err
+	}
+	d = &Daemon{
+		finder:       f,
+		dirty:        make(map[string]bool),
+		cookieDir:    cookieDir,
+		cookiePrefix: fmt.Sprintf(".finder_cookie.%v.", os.Getpid()),
+		cookies:      make(chan string, 16),
+	}
+
+	d.watcher, err = newWatcher(d.onChange, d.onOverflow)
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock()
+	for _, node := range subtree(&f.nodes) {
+		if node.ModTime != 0 {
+			err = d.watcher.watch(node.path)
+			if err != nil {
+				break
+			}
+		}
+	}
+	f.unlock()
+	if err == nil {
+		// The cookie directory is usually not part of the cached tree, so watch it explicitly.
+		err = d.watcher.watch(d.cookieDir)
+	}
+	if err != nil {
+		d.watcher.close()
+		return nil, err
+	}
+	// Anything that changed between the Finder scanning the filesystem and the watches
+	// being added was missed, so check every directory again before the first answer.
+	d.onOverflow()
+
+	// Remove the socket of a Daemon that didn't exit cleanly.
+	os.Remove(socketPath)
+	d.listener, err = net.Listen("unix", socketPath)
+	if err != nil {
+		d.watcher.close()
+		return nil, err
+	}
+	f.verbosef("Finder daemon listening on %v\n", socketPath)
+	return d, nil
+}
+
+// Serve answers requests until Close is called
+func (d *Daemon) Serve() error {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&d.closed) > 0 {
+				return nil
+			}
+			return err
+		}
+		d.handle(conn)
+		conn.Close()
+	}
+}
+
+// Close stops serving and watching, and saves the cache database to disk so that
+// Finders that don't use the Daemon start from an up-to-date cache
+func (d *Daemon) Close() error {
+	atomic.StoreInt32(&d.closed, 1)
+	err := d.listener.Close()
+	d.watcher.close()
+
+	f := d.finder
+	f.lock()
+	defer f.unlock()
+	if f.wasModified() {
+		dumpErr := f.dumpDb()
+		if err == nil {
+			err = dumpErr
+		}
+	}
+	return err
+}
+
+func (d *Daemon) handle(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(daemonTimeout))
+
+	var db []byte
+	var request cacheMetadata
+	line, err := d.finder.readLine(bufio.NewReader(conn))
+	if err == nil {
+		err = json.Unmarshal(line, &request)
+	}
+	if err == nil {
+		db, err = d.serve(request)
+	}
+
+	var response daemonResponse
+	if err != nil {
+		d.finder.verbosef("Failed to serve finder request: %v\n", err)
+		response.Error = err.Error()
+	}
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		panic(fmt.Sprintf("Could not serialize finder daemon response: %v\n", err))
+	}
+	conn.Write(append(responseBytes, lineSeparator))
+	if response.Error == "" {
+		conn.Write(db)
+	}
+}
+
+// serve returns the cache database for <request> after bringing it up to date
+func (d *Daemon) serve(request cacheMetadata) ([]byte, error) {
+	f := d.finder
+	if request.Version != f.cacheMetadata.Version {
+		return nil, fmt.Errorf("finder daemon has version %q, not %q",
+			f.cacheMetadata.Version, request.Version)
+	}
+	requestedConfig, err := request.Config.Dump()
+	if err != nil {
+		return nil, err
+	}
+	config, err := f.cacheMetadata.Config.Dump()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(requestedConfig, config) {
+		return nil, fmt.Errorf("finder daemon has params %q, not %q", config, requestedConfig)
+	}
+
+	err = d.sync()
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock()
+	defer f.unlock()
+	err = d.refresh()
+	if err != nil {
+		return nil, err
+	}
+	return f.serializeDb()
+}
+
+// sync waits until the watcher has reported every change made before sync was called
+func (d *Daemon) sync() error {
+	d.cookieCount++
+	name := d.cookiePrefix + strconv.Itoa(d.cookieCount)
+	path := filepath.Join(d.cookieDir, name)
+	err := ioutil.WriteFile(path, nil, 0666)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	timeout := time.After(daemonTimeout)
+	for {
+		select {
+		case cookie := <-d.cookies:
+			if cookie == name {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for the finder daemon to see %v", path)
+		}
+	}
+}
+
+// onChange is called by the watcher when an entry of <dir> changed
+func (d *Daemon) onChange(dir string, name string) {
+	if dir == d.cookieDir && strings.HasPrefix(name, d.cookiePrefix) {
+		select {
+		case d.cookies <- name:
+		default:
+			// Nobody is waiting for this cookie
+		}
+		return
+	}
+	d.dirtyLock.Lock()
+	d.dirty[dir] = true
+	d.dirtyLock.Unlock()
+}
+
+// onOverflow is called by the watcher when it lost events
+func (d *Daemon) onOverflow() {
+	d.dirtyLock.Lock()
+	d.overflowed = true
+	d.dirtyLock.Unlock()
+}
+
+// refresh rereads the directories that changed since the last call to refresh
+func (d *Daemon) refresh() error {
+	f := d.finder
+	startTime := time.Now()
+
+	d.dirtyLock.Lock()
+	dirty, overflowed := d.dirty, d.overflowed
+	d.dirty, d.overflowed = make(map[string]bool), false
+	d.dirtyLock.Unlock()
+
+	// Look up every node before changing any of them, see the invariants in finder.go
+	nodes := []*pathMap{}
+	if overflowed {
+		// Any directory may have changed
+		for _, node := range subtree(&f.nodes) {
+			if node.ModTime != 0 {
+				nodes = append(nodes, node)
+			}
+		}
+	} else {
+		for path := range dirty {
+			node := f.nodes.GetNode(path, false)
+			if node != nil {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+	f.setModified()
+
+	numDirs := 0
+	for len(nodes) > 0 {
+		numDirs += len(nodes)
+		var err error
+		nodes, err = d.relist(nodes)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The errors were already reported when the Daemon started; later errors mean the
+	// filesystem changed while being read, and the next event will fix the cache.
+	f.errlock.Lock()
+	for _, fsErr := range f.fsErrs {
+		f.verbosef("Ignoring %v\n", fsErr)
+	}
+	f.fsErrs = nil
+	f.errlock.Unlock()
+
+	f.verbosef("Finder daemon reread %v dirs in %v\n", numDirs, time.Since(startTime))
+	return nil
+}
+
+// relist rereads <nodes>, starts watching the directories that appeared in them, and returns
+// the nodes of those directories. The returned nodes must be reread again to catch changes
+// made between first reading them and starting to watch them.
+func (d *Daemon) relist(nodes []*pathMap) ([]*pathMap, error) {
+	f := d.finder
+
+	oldStats := make([]statResponse, len(nodes))
+	oldChildren := make([]map[string]bool, len(nodes))
+	for i, node := range nodes {
+		oldStats[i] = node.statResponse
+		oldChildren[i] = make(map[string]bool, len(node.children))
+		for name := range node.children {
+			oldChildren[i][name] = true
+		}
+	}
+
+	f.threadPool = newThreadPool(f.numDbLoadingThreads)
+	for _, node := range nodes {
+		node := node
+		f.threadPool.Run(func() {
+			node.mapNode = mapNode{statResponse: f.statDirSync(node.path), FileNames: []string{}}
+			if node.ModTime != 0 {
+				f.listDirSync(node)
+			} else {
+				node.children = make(map[string]*pathMap)
+			}
+		})
+	}
+	f.threadPool.Wait()
+	f.threadPool = nil
+
+	added := []*pathMap{}
+	for i, node := range nodes {
+		if node.ModTime == 0 {
+			continue
+		}
+		// A directory that was replaced by another one needs a new watch
+		if node.Inode != oldStats[i].Inode || node.Device != oldStats[i].Device {
+			added = append(added, node)
+		}
+		for name, child := range node.children {
+			if !oldChildren[i][name] {
+				added = append(added, subtree(child)...)
+			}
+		}
+	}
+
+	for _, node := range added {
+		if node.ModTime != 0 {
+			err := d.watcher.watch(node.path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return added, nil
+}
+
+// subtree returns <node> and all of its descendants
+func subtree(node *pathMap) []*pathMap {
+	nodes := []*pathMap{node}
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].children {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+func createFiles(t *testing.T, root string, paths ...string) {
+	for _, path := range paths {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func absPaths(root string, paths ...string) []string {
+	result := []string{}
+	for _, path := range paths {
+		result = append(result, filepath.Join(root, path))
+	}
+	return result
+}
+
+// findFromDaemon asks the Daemon listening on <socketPath> for its cache and searches it
+func findFromDaemon(t *testing.T, cacheParams CacheParams, socketPath string) []string {
+	logger := log.New(ioutil.Discard, "", 0)
+	f := newUnloadedFinder(cacheParams, fs.OsFs, logger, "", 2)
+	if err := f.loadFromDaemon(socketPath); err != nil {
+		t.Fatal(err)
+	}
+	return f.FindNamedAt(filepath.Join(cacheParams.WorkingDirectory, cacheParams.RootDirs[0]), "findme.txt")
+}
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	socketPath := filepath.Join(dir, "finder.sock")
+	createFiles(t, root,
+		"findme.txt",
+		"a/findme.txt",
+		"b/c/findme.txt",
+		"b/c/skipme.txt",
+		"d/findme.txt")
+
+	cacheParams := CacheParams{
+		WorkingDirectory: dir,
+		RootDirs:         []string{"root"},
+		PruneFiles:       []string{".find-ignore"},
+		IncludeFiles:     []string{"findme.txt"},
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	f, err := newImpl(cacheParams, fs.OsFs, logger, filepath.Join(dir, "finder.db"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WaitForDbDump()
+	daemon, err := NewDaemon(f, socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- daemon.Serve() }()
+	defer func() {
+		if err := daemon.Close(); err != nil {
+			t.Error(err)
+		}
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	fs.AssertSameResponse(t, findFromDaemon(t, cacheParams, socketPath),
+		absPaths(root, "findme.txt", "a/findme.txt", "b/c/findme.txt", "d/findme.txt"))
+
+	// Cookies are written next to the socket rather than into the source tree.
+	if daemon.cookieDir != dir {
+		t.Errorf("expected cookies in %q, got %q", dir, daemon.cookieDir)
+	}
+
+	// Add, remove and move files and directories.
+	createFiles(t, root, "e/f/findme.txt", "d/.find-ignore")
+	if err := os.Remove(filepath.Join(root, "a/findme.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(root, "b/c"), filepath.Join(root, "b/g")); err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, findFromDaemon(t, cacheParams, socketPath),
+		absPaths(root, "findme.txt", "b/g/findme.txt", "e/f/findme.txt"))
+
+	// New and moved directories are watched too.
+	createFiles(t, root, "e/f/h/findme.txt", "b/g/i/findme.txt")
+	if err := os.RemoveAll(filepath.Join(root, "b/g/findme.txt")); err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, findFromDaemon(t, cacheParams, socketPath),
+		absPaths(root, "findme.txt", "b/g/i/findme.txt", "e/f/findme.txt", "e/f/h/findme.txt"))
+
+	// A directory replaced by another one with the same name is watched too.
+	if err := os.RemoveAll(filepath.Join(root, "e")); err != nil {
+		t.Fatal(err)
+	}
+	createFiles(t, root, "e/f/findme.txt")
+	fs.AssertSameResponse(t, findFromDaemon(t, cacheParams, socketPath),
+		absPaths(root, "findme.txt", "b/g/i/findme.txt", "e/f/findme.txt"))
+	createFiles(t, root, "e/f/j/findme.txt")
+	fs.AssertSameResponse(t, findFromDaemon(t, cacheParams, socketPath),
+		absPaths(root, "findme.txt", "b/g/i/findme.txt", "e/f/findme.txt", "e/f/j/findme.txt"))
+
+	// A Finder with different params doesn't use the Daemon.
+	otherParams := cacheParams
+	otherParams.IncludeFiles = []string{"findme.txt", "skipme.txt"}
+	other := newUnloadedFinder(otherParams, fs.OsFs, logger, "", 2)
+	if err := other.loadFromDaemon(socketPath); err == nil {
+		t.Errorf("expected loading from a daemon with different params to fail")
+	}
+}
+
+func TestNewWithDaemonFallback(t *testing.T) {
+	dir := t.TempDir()
+	createFiles(t, dir, "a/findme.txt")
+
+	cacheParams := CacheParams{
+		WorkingDirectory: dir,
+		RootDirs:         []string{"."},
+		IncludeFiles:     []string{"findme.txt"},
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	f, err := NewWithDaemon(cacheParams, fs.OsFs, logger, filepath.Join(dir, "finder.db"),
+		filepath.Join(dir, "missing.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+
+	fs.AssertSameResponse(t, f.FindNamedAt(".", "findme.txt"), []string{"a/findme.txt"})
+}
@@ -173,6 +173,21 @@ func New(cacheParams CacheParams, filesystem fs.FileSystem,
 // newImpl is like New but accepts more params
 func newImpl(cacheParams CacheParams, filesystem fs.FileSystem,
 	logger Logger, dbPath string, numThreads int) (f *Finder, err error) {
+	f = newUnloadedFinder(cacheParams, filesystem, logger, dbPath, numThreads)
+
+	f.loadFromFilesystem()
+
+	err = f.checkLoaded()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// newUnloadedFinder creates a Finder that hasn't loaded anything yet
+func newUnloadedFinder(cacheParams CacheParams, filesystem fs.FileSystem,
+	logger Logger, dbPath string, numThreads int) *Finder {
 	numDbLoadingThreads := numThreads
 	numSearchingThreads := numThreads
 
@@ -184,7 +199,7 @@ func newImpl(cacheParams CacheParams, filesystem fs.FileSystem,
 		},
 	}
 
-	f = &Finder{
+	return &Finder{
 		numDbLoadingThreads: numDbLoadingThreads,
 		numSearchingThreads: numSearchingThreads,
 		cacheMetadata:       metadata,
@@ -196,27 +211,28 @@ func newImpl(cacheParams CacheParams, filesystem fs.FileSystem,
 
 		shutdownWaitgroup: sync.WaitGroup{},
 	}
+}
 
-	f.loadFromFilesystem()
-
+// checkLoaded returns an error if the Finder failed to load the directories in its CacheParams
+func (f *Finder) checkLoaded() error {
 	// check for any filesystem errors
-	err = f.getErr()
+	err := f.getErr()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// confirm that every path mentioned in the CacheConfig exists
-	for _, path := range cacheParams.RootDirs {
+	for _, path := range f.cacheMetadata.Config.RootDirs {
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, path)
 		}
 		node := f.nodes.GetNode(filepath.Clean(path), false)
 		if node == nil || node.ModTime == 0 {
-			return nil, fmt.Errorf("path %v was specified to be included in the cache but does not exist\n", path)
+			return fmt.Errorf("path %v was specified to be included in the cache but does not exist\n", path)
 		}
 	}
 
-	return f, nil
+	return nil
 }
 
 // FindNamed searches for every cached file
@@ -0,0 +1,123 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// The events that can change what the Finder knows about a directory. Changes to the
+// contents of files don't matter, only which entries exist and whether the directory
+// can be read.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF |
+	syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+// an inotifyWatcher is a watcher that uses inotify
+type inotifyWatcher struct {
+	fd   int
+	file *os.File
+
+	onChange   func(dir string, name string)
+	onOverflow func()
+
+	// the path of each watch descriptor
+	lock  sync.Mutex
+	paths map[int32]string
+}
+
+func newWatcher(onChange func(dir string, name string), onOverflow func()) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &inotifyWatcher{
+		fd: fd,
+		// The file is non-blocking, so reads go through the runtime poller and can be
+		// interrupted by closing the file.
+		file:       os.NewFile(uintptr(fd), "inotify"),
+		onChange:   onChange,
+		onOverflow: onOverflow,
+		paths:      make(map[int32]string),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+func (w *inotifyWatcher) watch(path string) error {
+	// Watching a directory that is already watched, for example because it was moved,
+	// returns the existing watch descriptor, which then reports the new path.
+	wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+	if err != nil {
+		return &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	w.lock.Lock()
+	w.paths[int32(wd)] = path
+	w.lock.Unlock()
+	return nil
+}
+
+func (w *inotifyWatcher) close() error {
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) readEvents() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			// The watcher was closed
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			offset = nameStart + int(event.Len)
+			name := string(bytes.TrimRight(buf[nameStart:offset], "\x00"))
+			w.handleEvent(event.Wd, event.Mask, name)
+		}
+	}
+}
+
+func (w *inotifyWatcher) handleEvent(wd int32, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		w.onOverflow()
+		return
+	}
+
+	w.lock.Lock()
+	path, ok := w.paths[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		// The directory was deleted
+		delete(w.paths, wd)
+	}
+	w.lock.Unlock()
+	if !ok {
+		return
+	}
+
+	if mask&syscall.IN_ATTRIB != 0 && name != "" {
+		// The attributes of a file changed, which doesn't change what the Finder knows.
+		// The attributes of a subdirectory are reported by its own watch.
+		return
+	}
+	if mask&inotifyMask != 0 {
+		w.onChange(path, name)
+	}
+}
@@ -48,6 +48,8 @@ type AndroidAppImport struct {
 	outputFile  android.Path
 	certificate Certificate
 
+	proguardDictionary android.OptionalPath
+
 	dexpreopter
 
 	usesLibrary usesLibrary
@@ -102,6 +104,10 @@ type AndroidAppImportProperties struct {
 
 	// Optional. Install to a subdirectory of the default install path for the module
 	Relative_install_path *string
+
+	// The R8 mapping file of the prebuilt apk, if it is obfuscated.  It is added to the
+	// r8-mappings zip so that its stack traces can be retraced.
+	Proguard_dictionary *string `android:"path"`
 }
 
 func (a *AndroidAppImport) IsInstallable() bool {
@@ -341,6 +347,8 @@ func (a *AndroidAppImport) generateAndroidBuildActions(ctx android.ModuleContext
 
 	// TODO: Optionally compress the output apk.
 
+	a.proguardDictionary = android.OptionalPathForModuleSrc(ctx, a.properties.Proguard_dictionary)
+
 	if apexInfo.IsForPlatform() {
 		a.installPath = ctx.InstallFile(installDir, apkFilename, a.outputFile)
 	}
@@ -356,6 +364,10 @@ func (a *AndroidAppImport) Name() string {
 	return a.prebuilt.Name(a.ModuleBase.Name())
 }
 
+func (a *AndroidAppImport) r8Mappings() (dictionary, configuration android.OptionalPath) {
+	return a.proguardDictionary, android.OptionalPath{}
+}
+
 func (a *AndroidAppImport) OutputFile() android.Path {
 	return a.outputFile
 }
@@ -73,6 +73,11 @@ type CommonProperties struct {
 	// list of module-specific flags that will be used for kotlinc compiles
 	Kotlincflags []string `android:"arch_variant"`
 
+	// If set to true, compile the Kotlin sources incrementally, keeping the caches of kotlinc in
+	// the module's intermediates directory.  The caches are deleted when the classpath, the
+	// kotlincflags or the list of sources change.  Defaults to false.
+	Kotlin_incremental *bool
+
 	// list of java libraries that will be in the classpath
 	Libs []string `android:"arch_variant"`
 
@@ -294,6 +299,9 @@ type Module struct {
 	// inserting into the bootclasspath/classpath of another compile
 	headerJarFile android.Path
 
+	// file containing the ABI of headerJarFile, which is only modified when the ABI changes
+	headerJarAbiFile android.Path
+
 	// jar file containing implementation classes including static library dependencies but no
 	// resources
 	implementationJarFile android.Path
@@ -732,6 +740,7 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 	flags.bootClasspath = append(flags.bootClasspath, deps.bootClasspath...)
 	flags.classpath = append(flags.classpath, deps.classpath...)
 	flags.java9Classpath = append(flags.java9Classpath, deps.java9Classpath...)
+	flags.classpathAbis = deps.classpathAbis
 	flags.processorPath = append(flags.processorPath, deps.processorPath...)
 	flags.errorProneProcessorPath = append(flags.errorProneProcessorPath, deps.errorProneProcessorPath...)
 
@@ -930,6 +939,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.bootClasspath...)
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.classpath...)
+		flags.kotlinIncremental = proptools.Bool(j.properties.Kotlin_incremental)
 
 		if len(flags.processorPath) > 0 {
 			// Use kapt for annotation processing
@@ -1188,6 +1198,14 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		j.headerJarFile = j.implementationJarFile
 	}
 
+	// Let dependents depend on the ABI of the header jar so that they are not recompiled when only
+	// the implementation of this module changes.
+	if !ctx.Config().IsEnvFalse("JAVA_ABI_RESTAT") {
+		headerJarAbiFile := android.PathForModuleOut(ctx, "abi", jarName+".abi")
+		TransformJarToAbi(ctx, headerJarAbiFile, j.headerJarFile)
+		j.headerJarAbiFile = headerJarAbiFile
+	}
+
 	if j.shouldInstrumentInApex(ctx) {
 		j.properties.Instrument = true
 	}
@@ -1309,6 +1327,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 
 	ctx.SetProvider(JavaInfoProvider, JavaInfo{
 		HeaderJars:                     android.PathsIfNonNil(j.headerJarFile),
+		HeaderJarAbis:                  android.PathsIfNonNil(j.headerJarAbiFile),
 		ImplementationAndResourcesJars: android.PathsIfNonNil(j.implementationAndResourcesJar),
 		ImplementationJars:             android.PathsIfNonNil(j.implementationJarFile),
 		ResourceJars:                   android.PathsIfNonNil(j.resourceJar),
@@ -1702,13 +1721,16 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				deps.bootClasspath = append(deps.bootClasspath, dep.HeaderJars...)
 			case libTag, instrumentationForTag:
 				deps.classpath = append(deps.classpath, dep.HeaderJars...)
+				deps.classpathAbis.add(dep.HeaderJars, dep.HeaderJarAbis)
 				deps.aidlIncludeDirs = append(deps.aidlIncludeDirs, dep.AidlIncludeDirs...)
 				addPlugins(&deps, dep.ExportedPlugins, dep.ExportedPluginClasses...)
 				deps.disableTurbine = deps.disableTurbine || dep.ExportedPluginDisableTurbine
 			case java9LibTag:
 				deps.java9Classpath = append(deps.java9Classpath, dep.HeaderJars...)
+				deps.classpathAbis.add(dep.HeaderJars, dep.HeaderJarAbis)
 			case staticLibTag:
 				deps.classpath = append(deps.classpath, dep.HeaderJars...)
+				deps.classpathAbis.add(dep.HeaderJars, dep.HeaderJarAbis)
 				deps.staticJars = append(deps.staticJars, dep.ImplementationJars...)
 				deps.staticHeaderJars = append(deps.staticHeaderJars, dep.HeaderJars...)
 				deps.staticResourceJars = append(deps.staticResourceJars, dep.ResourceJars...)
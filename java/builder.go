@@ -222,6 +222,17 @@ var (
 		},
 		"packages")
 
+	// java_abi leaves the output untouched when the ABI of the jar didn't change, so with restat
+	// the actions that depend on the ABI file instead of the jar are skipped when only the
+	// implementation of the classes in the jar changed.
+	javaAbi = pctx.AndroidStaticRule("javaAbi",
+		blueprint.RuleParams{
+			Command:     "${config.JavaAbiCmd} -o $out $in",
+			CommandDeps: []string{"${config.JavaAbiCmd}"},
+			Restat:      true,
+		},
+	)
+
 	jetifier = pctx.AndroidStaticRule("jetifier",
 		blueprint.RuleParams{
 			Command:     "${config.JavaCmd}  ${config.JavaVmFlags} -jar ${config.JetifierJar} -l error -o $out -i $in",
@@ -261,8 +272,12 @@ type javaBuilderFlags struct {
 	errorProneExtraJavacFlags string
 	errorProneProcessorPath   classpath
 
-	kotlincFlags     string
-	kotlincClasspath classpath
+	kotlincFlags      string
+	kotlincClasspath  classpath
+	kotlinIncremental bool
+
+	// classpathAbis maps jars in the classpath to their ABI files.
+	classpathAbis classpathAbis
 
 	proto android.ProtoFlags
 }
@@ -426,7 +441,9 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 		}
 	}
 
-	deps = append(deps, classpath...)
+	// Depend on the ABI files of the jars in the classpath instead of the jars themselves, so that
+	// javac doesn't rerun when only the implementation of a dependency changed.
+	deps = append(deps, flags.classpathAbis.implicits(classpath)...)
 	deps = append(deps, flags.processorPath...)
 
 	processor := "-proc:none"
@@ -553,6 +570,18 @@ func CheckJarPackages(ctx android.ModuleContext, outputFile android.WritablePath
 	})
 }
 
+// TransformJarToAbi writes the ABI of the classes in a jar, the non-private classes and members
+// with their signatures, annotations and constant values.  The ABI file is only modified
+// when the ABI changes.
+func TransformJarToAbi(ctx android.ModuleContext, outputFile android.WritablePath, jar android.Path) {
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        javaAbi,
+		Description: "java abi",
+		Output:      outputFile,
+		Input:       jar,
+	})
+}
+
 func TransformJetifier(ctx android.ModuleContext, outputFile android.WritablePath,
 	inputFile android.Path) {
 	ctx.Build(pctx, android.BuildParams{
@@ -625,6 +654,36 @@ func (x *classpath) Strings() []string {
 	return ret
 }
 
+// classpathAbis maps the paths of jars to the files with their ABI written by TransformJarToAbi.
+type classpathAbis map[string]android.Path
+
+// add records the ABI files of the jars, which are either empty or parallel lists.
+func (x *classpathAbis) add(jars, abis android.Paths) {
+	if len(abis) != len(jars) {
+		return
+	}
+	if *x == nil {
+		*x = make(classpathAbis)
+	}
+	for i, jar := range jars {
+		(*x)[jar.String()] = abis[i]
+	}
+}
+
+// implicits returns the files that an action that reads the jars needs to depend on: the ABI file
+// of each jar that has one, which is built after the jar, and the jar itself otherwise.
+func (x classpathAbis) implicits(jars android.Paths) android.Paths {
+	ret := make(android.Paths, len(jars))
+	for i, jar := range jars {
+		if abi, ok := x[jar.String()]; ok {
+			ret[i] = abi
+		} else {
+			ret[i] = jar
+		}
+	}
+	return ret
+}
+
 type systemModules struct {
 	dir  android.Path
 	deps android.Paths
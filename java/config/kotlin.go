@@ -43,6 +43,11 @@ func init() {
 		"-J--add-opens=java.base/sun.net.www.protocol.jar=ALL-UNNAMED",
 	}, " "))
 
+	// These flags make kotlinc compile incrementally, keeping its caches in the directory that
+	// is appended to them.  The kotlincIncremental rule deletes the classes before every run, so a
+	// kotlinc that ignores them still never leaves the classes of removed declarations in the jar.
+	pctx.StaticVariable("KotlincIncrementalFlags", "-Xenable-incremental-compilation -Xic-cache-dir=")
+
 	// These flags silence "Illegal reflective access" warnings when running kotlinc in OpenJDK9+
 	pctx.StaticVariable("KotlincSuppressJDK9Warnings", strings.Join([]string{
 		"-J--add-opens=java.base/java.util=ALL-UNNAMED", // https://youtrack.jetbrains.com/issue/KT-43704
@@ -160,6 +160,11 @@ type JavaInfo struct {
 	// against this module.  If empty, ImplementationJars should be used instead.
 	HeaderJars android.Paths
 
+	// HeaderJarAbis contains a file with the ABI of each jar in HeaderJars, which is only modified
+	// when the public or protected API of the classes in the jar changes.  Compiling against
+	// HeaderJars can depend on them instead of the jars.  Empty if the module doesn't have them.
+	HeaderJarAbis android.Paths
+
 	// ImplementationAndResourceJars is a list of jars that contain the implementations of classes
 	// in the module as well as any resources included in the module.
 	ImplementationAndResourcesJars android.Paths
@@ -380,6 +385,7 @@ type deps struct {
 	aidlPreprocess          android.OptionalPath
 	kotlinStdlib            android.Paths
 	kotlinAnnotations       android.Paths
+	classpathAbis           classpathAbis
 
 	disableTurbine bool
 }
@@ -979,6 +979,49 @@ func TestTurbine(t *testing.T) {
 	android.AssertStringDoesContain(t, "baz javac classpath", bazJavac.Args["classpath"], "prebuilts/sdk/14/public/android.jar")
 }
 
+func TestJavaAbi(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			libs: ["foo"],
+		}
+	`
+	fooHeaderJar := "out/soong/.intermediates/foo/android_common/turbine-combined/foo.jar"
+	fooAbi := "out/soong/.intermediates/foo/android_common/abi/foo.jar.abi"
+
+	result := android.GroupFixturePreparers(prepareForJavaTest).RunTestWithBp(t, bp)
+
+	abi := result.ModuleForTests("foo", "android_common").Output("abi/foo.jar.abi")
+	android.AssertPathRelativeToTopEquals(t, "foo abi input", fooHeaderJar, abi.Input)
+	android.AssertBoolEquals(t, "foo abi restat", true, abi.RuleParams.Restat)
+
+	// bar's javac depends on the ABI of foo instead of its header jar, but still compiles against
+	// the header jar.
+	barJavac := result.ModuleForTests("bar", "android_common").Rule("javac")
+	implicits := android.PathsRelativeToTop(barJavac.Implicits)
+	android.AssertStringListContains(t, "bar javac implicits", implicits, fooAbi)
+	android.AssertStringListDoesNotContain(t, "bar javac implicits", implicits, fooHeaderJar)
+	android.AssertStringDoesContain(t, "bar javac classpath", barJavac.Args["classpath"], "foo/android_common/turbine-combined/foo.jar")
+
+	// JAVA_ABI_RESTAT=false restores the dependency on the header jar.
+	result = android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeEnv(map[string]string{"JAVA_ABI_RESTAT": "false"}),
+	).RunTestWithBp(t, bp)
+
+	if result.ModuleForTests("foo", "android_common").MaybeOutput("abi/foo.jar.abi").Rule != nil {
+		t.Errorf("unexpected abi rule for foo with JAVA_ABI_RESTAT=false")
+	}
+	barJavac = result.ModuleForTests("bar", "android_common").Rule("javac")
+	android.AssertStringListContains(t, "bar javac implicits", android.PathsRelativeToTop(barJavac.Implicits), fooHeaderJar)
+}
+
 func TestSharding(t *testing.T) {
 	ctx, _ := testJava(t, `
 		java_library {
@@ -26,6 +26,20 @@ import (
 	"github.com/google/blueprint"
 )
 
+var kotlincCommandDeps = []string{
+	"${config.KotlincCmd}",
+	"${config.KotlinCompilerJar}",
+	"${config.KotlinPreloaderJar}",
+	"${config.KotlinReflectJar}",
+	"${config.KotlinScriptRuntimeJar}",
+	"${config.KotlinStdlibJar}",
+	"${config.KotlinTrove4jJar}",
+	"${config.KotlinAnnotationJar}",
+	"${config.GenKotlinBuildFileCmd}",
+	"${config.SoongZipCmd}",
+	"${config.ZipSyncCmd}",
+}
+
 var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports{Goma: true},
 	blueprint.RuleParams{
 		Command: `rm -rf "$classesDir" "$srcJarDir" "$kotlinBuildFile" "$emptyDir" && ` +
@@ -39,25 +53,53 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 			`-kotlin-home $emptyDir && ` +
 			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir && ` +
 			`rm -rf "$srcJarDir"`,
-		CommandDeps: []string{
-			"${config.KotlincCmd}",
-			"${config.KotlinCompilerJar}",
-			"${config.KotlinPreloaderJar}",
-			"${config.KotlinReflectJar}",
-			"${config.KotlinScriptRuntimeJar}",
-			"${config.KotlinStdlibJar}",
-			"${config.KotlinTrove4jJar}",
-			"${config.KotlinAnnotationJar}",
-			"${config.GenKotlinBuildFileCmd}",
-			"${config.SoongZipCmd}",
-			"${config.ZipSyncCmd}",
-		},
+		CommandDeps:    kotlincCommandDeps,
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
 	},
 	"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
 	"kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
 
+// kotlincIncremental is the kotlinc rule for modules with kotlin_incremental: true.  It keeps the
+// incremental caches of kotlinc in $incrementalDir between builds.  The caches are not an output
+// known to ninja, the only output is still the jar.
+//
+// The classes directory is deleted on every run, so the jar only ever contains the classes written
+// by the current kotlinc invocation.  A class, lambda or companion object removed from a source
+// never stays behind in the jar, even if kotlinc ignores the incremental compilation flags.
+//
+// The caches are only valid for the classpath, flags and sources that they were created with,
+// which are recorded with the checksums of the classpath jars in $incrementalDir/classpath.  When
+// they differ the caches are deleted.  The record is removed while kotlinc runs, so the caches of a
+// failed or interrupted compile are deleted next time too.
+var kotlincIncremental = pctx.AndroidStaticRule("kotlincIncremental",
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kotlinBuildFile" "$emptyDir" "$classesDir" && ` +
+			`mkdir -p "$incrementalDir" "$srcJarDir" "$emptyDir" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+			`(echo "$classpath" && echo "$kotlincFlags" && cksum /dev/null $classpathJars && ` +
+			`cat "$out.rsp" "$srcJarDir/list") > "$incrementalDir/classpath.new" && ` +
+			`if ! cmp -s "$incrementalDir/classpath.new" "$incrementalDir/classpath"; then ` +
+			`rm -rf "$incrementalDir/caches"; fi && ` +
+			`rm -f "$incrementalDir/classpath" && ` +
+			`mkdir -p "$incrementalDir/caches" "$classesDir" && ` +
+			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
+			` --out_dir "$classesDir" --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
+			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
+			`${config.KotlincCmd} ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} ` +
+			`$kotlincFlags -jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile ` +
+			`${config.KotlincIncrementalFlags}"$incrementalDir/caches" ` +
+			`-kotlin-home $emptyDir && ` +
+			`mv "$incrementalDir/classpath.new" "$incrementalDir/classpath" && ` +
+			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps:    kotlincCommandDeps,
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+	},
+	"kotlincFlags", "classpath", "classpathJars", "srcJars", "commonSrcFilesArg", "srcJarDir",
+	"classesDir", "incrementalDir", "kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
+
 func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Paths) android.OptionalPath {
 	if len(commonSrcFiles) > 0 {
 		// The list of common_srcs may be too long to put on the command line, but
@@ -94,25 +136,33 @@ func kotlinCompile(ctx android.ModuleContext, outputFile android.WritablePath,
 		commonSrcFilesArg = "--common_srcs " + commonSrcsList.String()
 	}
 
+	rule := kotlinc
+	args := map[string]string{
+		"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
+		"kotlincFlags":      flags.kotlincFlags,
+		"commonSrcFilesArg": commonSrcFilesArg,
+		"srcJars":           strings.Join(srcJars.Strings(), " "),
+		"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+		"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+		"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+		"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
+		// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
+		"kotlinJvmTarget": "1.8",
+		"name":            kotlinName,
+	}
+	if flags.kotlinIncremental {
+		rule = kotlincIncremental
+		args["classpathJars"] = strings.Join(flags.kotlincClasspath.Strings(), " ")
+		args["incrementalDir"] = android.PathForModuleOut(ctx, "kotlinc", "incremental").String()
+	}
+
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        kotlinc,
+		Rule:        rule,
 		Description: "kotlinc",
 		Output:      outputFile,
 		Inputs:      srcFiles,
 		Implicits:   deps,
-		Args: map[string]string{
-			"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
-			"kotlincFlags":      flags.kotlincFlags,
-			"commonSrcFilesArg": commonSrcFilesArg,
-			"srcJars":           strings.Join(srcJars.Strings(), " "),
-			"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
-			"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
-			"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
-			// http://b/69160377 kotlinc only supports -jvm-target 1.6 and 1.8
-			"kotlinJvmTarget": "1.8",
-			"name":            kotlinName,
-		},
+		Args:        args,
 	})
 }
 
@@ -15,6 +15,12 @@
 package java
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -84,6 +90,159 @@ func TestKotlin(t *testing.T) {
 	}
 }
 
+func TestKotlinIncremental(t *testing.T) {
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			libs: ["bar"],
+			kotlin_incremental: true,
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.kt"],
+		}
+		`)
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+	fooKotlinc := foo.Rule("kotlincIncremental")
+	if g, w := fooKotlinc.Args["incrementalDir"], filepath.Join("kotlinc", "incremental"); !strings.HasSuffix(g, w) {
+		t.Errorf("foo incrementalDir %q does not end with %q", g, w)
+	}
+
+	// The checksums of the jars on the classpath invalidate the incremental caches.
+	if g, w := fooKotlinc.Args["classpathJars"], strings.ReplaceAll(fooKotlinc.Args["classpath"], ":", " "); g == "" || g != w {
+		t.Errorf("foo classpathJars %q does not match classpath %q", g, fooKotlinc.Args["classpath"])
+	}
+
+	// The incremental caches are not outputs of the rule.
+	for _, output := range append(android.WritablePaths{fooKotlinc.Output}, fooKotlinc.ImplicitOutputs...) {
+		if strings.Contains(output.String(), "incremental") {
+			t.Errorf("foo kotlinc has incremental cache output %q", output)
+		}
+	}
+
+	barKotlinc := ctx.ModuleForTests("bar", "android_common").Rule("kotlinc")
+	if strings.Contains(barKotlinc.Rule.String(), "Incremental") {
+		t.Errorf("bar uses incremental kotlinc rule %q without kotlin_incremental", barKotlinc.Rule.String())
+	}
+
+	t.Run("invalidation", func(t *testing.T) {
+		testKotlincIncrementalInvalidation(t, fooKotlinc)
+	})
+}
+
+// kotlincIncrementalFakeTools are shell scripts that stand in for the tools used by the
+// kotlincIncremental rule.  The fake kotlinc writes a class for every declaration listed in a
+// source into the classes directory but never deletes classes, like a compiler that ignores the
+// incremental compilation flags.
+var kotlincIncrementalFakeTools = map[string]string{
+	"ZipSyncCmd": `while [ $# -gt 0 ]; do case $1 in -l) list=$2; shift 2;; -d|-f) shift 2;; *) break;; esac; done
+: > "$list"`,
+	"GenKotlinBuildFileCmd": `while [ $# -gt 0 ]; do case $1 in --out_dir) dir=$2;; --srcs) srcs="$srcs $2";; --out) out=$2;; esac; shift 2; done
+{ echo "$dir"; cat $srcs; } > "$out"`,
+	"KotlincCmd": `for arg; do case $arg in -Xbuild-file=*) buildFile=${arg#-Xbuild-file=};; esac; done
+dir=$(head -n 1 "$buildFile")
+for src in $(tail -n +2 "$buildFile"); do for decl in $(cat "$src"); do touch "$dir/$decl.class"; done; done`,
+	"SoongZipCmd": `while [ $# -gt 0 ]; do case $1 in -o) out=$2; shift 2;; -C) dir=$2; shift 2;; *) shift;; esac; done
+ls "$dir" > "$out"`,
+}
+
+// testKotlincIncrementalInvalidation runs the command of a kotlincIncremental rule with fake tools
+// to check that classes of removed sources and declarations don't stay in the jar.
+func testKotlincIncrementalInvalidation(t *testing.T, params android.TestingBuildParams) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	dir := t.TempDir()
+
+	vars := map[string]string{
+		"KotlincIncrementalFlags": "-Xic-cache-dir=",
+	}
+	for name, script := range kotlincIncrementalFakeTools {
+		tool := filepath.Join(dir, "tools", name)
+		if err := os.MkdirAll(filepath.Dir(tool), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(tool, []byte(script+"\n"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		vars[name] = bash + " " + tool
+	}
+	command := regexp.MustCompile(`\$\{config\.(\w+)\}`).ReplaceAllStringFunc(params.RuleParams.Command,
+		func(s string) string {
+			return vars[s[len("${config."):len(s)-1]]
+		})
+
+	out := params.Output.String()
+	args := map[string]string{"out": out}
+	for name, value := range params.Args {
+		args[name] = value
+	}
+	command = regexp.MustCompile(`\$(\w+)`).ReplaceAllStringFunc(command, func(s string) string {
+		value, ok := args[s[1:]]
+		if !ok {
+			t.Fatalf("unknown variable %s in %q", s, params.RuleParams.Command)
+		}
+		return value
+	})
+	if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(out)), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, jar := range strings.Fields(params.Args["classpathJars"]) {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(jar)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, jar), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// writeSource writes a source with the given declarations.
+	writeSource := func(name string, decls ...string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(strings.Join(decls, "\n")), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// run compiles srcs, like ninja it writes the rsp file first, and returns the classes in the jar.
+	run := func(srcs ...string) []string {
+		t.Helper()
+		rsp := filepath.Join(dir, out+".rsp")
+		if err := ioutil.WriteFile(rsp, []byte(strings.Join(srcs, " ")), 0666); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(bash, "-c", command)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("command failed: %v\n%s", err, output)
+		}
+		jar, err := ioutil.ReadFile(filepath.Join(dir, out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		classes := strings.Fields(string(jar))
+		sort.Strings(classes)
+		return classes
+	}
+
+	writeSource("a.kt", "A", "A$Companion")
+	writeSource("b.kt", "B")
+	writeSource("c.kt", "C")
+	android.AssertDeepEquals(t, "classes", []string{"A$Companion.class", "A.class", "B.class"}, run("a.kt", "b.kt"))
+
+	// Removing a declaration from a source that is still compiled removes its class.
+	writeSource("a.kt", "A")
+	android.AssertDeepEquals(t, "classes", []string{"A.class", "B.class"}, run("a.kt", "b.kt"))
+
+	// Removing a source removes its classes.
+	android.AssertDeepEquals(t, "classes", []string{"A.class"}, run("a.kt"))
+	android.AssertDeepEquals(t, "classes", []string{"A.class", "C.class"}, run("a.kt", "c.kt"))
+}
+
 func TestKapt(t *testing.T) {
 	bp := `
 		java_library {
@@ -95,6 +95,11 @@ type lintOutputs struct {
 	text android.Path
 	xml  android.Path
 
+	// The baseline of the module and the refreshed baseline written by rerunning lint with all of
+	// the issues it finds, if the module has a baseline.
+	baseline        android.Path
+	updatedBaseline android.Path
+
 	depSets LintDepSets
 }
 
@@ -111,18 +116,18 @@ type lintDepSetsIntf interface {
 }
 
 type LintDepSets struct {
-	HTML, Text, XML *android.DepSet
+	HTML, Text, XML *android.DepSet[android.Path]
 }
 
 type LintDepSetsBuilder struct {
-	HTML, Text, XML *android.DepSetBuilder
+	HTML, Text, XML *android.DepSetBuilder[android.Path]
 }
 
 func NewLintDepSetBuilder() LintDepSetsBuilder {
 	return LintDepSetsBuilder{
-		HTML: android.NewDepSetBuilder(android.POSTORDER),
-		Text: android.NewDepSetBuilder(android.POSTORDER),
-		XML:  android.NewDepSetBuilder(android.POSTORDER),
+		HTML: android.NewDepSetBuilder[android.Path](android.POSTORDER),
+		Text: android.NewDepSetBuilder[android.Path](android.POSTORDER),
+		XML:  android.NewDepSetBuilder[android.Path](android.POSTORDER),
 	}
 }
 
@@ -209,14 +214,16 @@ func lintRBEExecStrategy(ctx android.ModuleContext) string {
 	return ctx.Config().GetenvWithDefault("RBE_LINT_EXEC_STRATEGY", remoteexec.LocalExecStrategy)
 }
 
-func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.RuleBuilder) lintPaths {
-	projectXMLPath := android.PathForModuleOut(ctx, "lint", "project.xml")
+func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.RuleBuilder, dir string,
+	manifest android.Path) lintPaths {
+
+	projectXMLPath := android.PathForModuleOut(ctx, dir, "project.xml")
 	// Lint looks for a lint.xml file next to the project.xml file, give it one.
-	configXMLPath := android.PathForModuleOut(ctx, "lint", "lint.xml")
-	cacheDir := android.PathForModuleOut(ctx, "lint", "cache")
-	homeDir := android.PathForModuleOut(ctx, "lint", "home")
+	configXMLPath := android.PathForModuleOut(ctx, dir, "lint.xml")
+	cacheDir := android.PathForModuleOut(ctx, dir, "cache")
+	homeDir := android.PathForModuleOut(ctx, dir, "home")
 
-	srcJarDir := android.PathForModuleOut(ctx, "lint", "srcjars")
+	srcJarDir := android.PathForModuleOut(ctx, dir, "srcjars")
 	srcJarList := zipSyncCmd(ctx, rule, srcJarDir, l.srcJars)
 
 	cmd := rule.Command().
@@ -231,8 +238,8 @@ func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.Ru
 	if l.test {
 		cmd.Flag("--test")
 	}
-	if l.manifest != nil {
-		cmd.FlagWithInput("--manifest ", l.manifest)
+	if manifest != nil {
+		cmd.FlagWithInput("--manifest ", manifest)
 	}
 	if l.mergedManifest != nil {
 		cmd.FlagWithInput("--merged_manifest ", l.mergedManifest)
@@ -240,13 +247,13 @@ func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.Ru
 
 	// TODO(ccross): some of the files in l.srcs are generated sources and should be passed to
 	// lint separately.
-	srcsList := android.PathForModuleOut(ctx, "lint-srcs.list")
+	srcsList := android.PathForModuleOut(ctx, dir+"-srcs.list")
 	cmd.FlagWithRspFileInputList("--srcs ", srcsList, l.srcs)
 
 	cmd.FlagWithInput("--generated_srcs ", srcJarList)
 
 	if len(l.resources) > 0 {
-		resourcesList := android.PathForModuleOut(ctx, "lint-resources.list")
+		resourcesList := android.PathForModuleOut(ctx, dir+"-resources.list")
 		cmd.FlagWithRspFileInputList("--resources ", resourcesList, l.resources)
 	}
 
@@ -292,8 +299,8 @@ func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.Ru
 
 // generateManifest adds a command to the rule to write a simple manifest that contains the
 // minSdkVersion and targetSdkVersion for modules (like java_library) that don't have a manifest.
-func (l *linter) generateManifest(ctx android.ModuleContext, rule *android.RuleBuilder) android.WritablePath {
-	manifestPath := android.PathForModuleOut(ctx, "lint", "AndroidManifest.xml")
+func (l *linter) generateManifest(ctx android.ModuleContext, rule *android.RuleBuilder, dir string) android.WritablePath {
+	manifestPath := android.PathForModuleOut(ctx, dir, "AndroidManifest.xml")
 
 	rule.Command().Text("(").
 		Text(`echo "<?xml version='1.0' encoding='utf-8'?>" &&`).
@@ -350,9 +357,78 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		}
 	}
 
+	html := android.PathForModuleOut(ctx, "lint", "lint-report.html")
+	text := android.PathForModuleOut(ctx, "lint", "lint-report.txt")
+	xml := android.PathForModuleOut(ctx, "lint", "lint-report.xml")
+
+	depSetsBuilder := NewLintDepSetBuilder().Direct(html, text, xml)
+
+	ctx.VisitDirectDepsWithTag(staticLibTag, func(dep android.Module) {
+		if depLint, ok := dep.(lintDepSetsIntf); ok {
+			depSetsBuilder.Transitive(depLint.LintDepSets())
+		}
+	})
+
+	lintBaseline := l.getBaselineFilepath(ctx)
+
+	l.buildLintRule(ctx, lintRuleParams{
+		name:     "lint",
+		html:     html,
+		text:     text,
+		xml:      xml,
+		baseline: lintBaseline,
+	})
+
+	l.outputs = lintOutputs{
+		html: html,
+		text: text,
+		xml:  xml,
+
+		depSets: depSetsBuilder.Build(),
+	}
+
+	android.BuildSarifReport(ctx, "lint", android.Paths{xml})
+
+	if lintBaseline.Valid() {
+		// Rerun lint without failing on errors to write a refreshed baseline for the
+		// lint-baseline-update goal.
+		updatedBaseline := android.PathForModuleOut(ctx, "lint-baseline", lintBaseline.Path().Base())
+		l.buildLintRule(ctx, lintRuleParams{
+			name:            "lint-baseline",
+			text:            android.PathForModuleOut(ctx, "lint-baseline", "lint-report.txt"),
+			baseline:        lintBaseline,
+			updatedBaseline: updatedBaseline,
+		})
+
+		l.outputs.baseline = lintBaseline.Path()
+		l.outputs.updatedBaseline = updatedBaseline
+	}
+
+	if l.buildModuleReportZip {
+		l.reports = BuildModuleLintReportZips(ctx, l.LintDepSets())
+	}
+}
+
+// lintRuleParams contains the outputs of a rule that runs lint.
+type lintRuleParams struct {
+	// name is the name of the rule and of the directory in the module's output directory that
+	// contains its intermediate files.
+	name string
+
+	// The reports written by lint.  html and xml are optional.
+	html, text, xml android.WritablePath
+
+	baseline android.OptionalPath
+
+	// If set, lint writes all of the issues it finds to updatedBaseline, including the ones that
+	// are suppressed by the baseline, instead of failing on errors.
+	updatedBaseline android.WritablePath
+}
+
+func (l *linter) buildLintRule(ctx android.ModuleContext, params lintRuleParams) {
 	rule := android.NewRuleBuilder(pctx, ctx).
-		Sbox(android.PathForModuleOut(ctx, "lint"),
-			android.PathForModuleOut(ctx, "lint.sbox.textproto")).
+		Sbox(android.PathForModuleOut(ctx, params.name),
+			android.PathForModuleOut(ctx, params.name+".sbox.textproto")).
 		SandboxInputs()
 
 	if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_LINT") {
@@ -366,29 +442,23 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		})
 	}
 
-	if l.manifest == nil {
-		manifest := l.generateManifest(ctx, rule)
-		l.manifest = manifest
-		rule.Temporary(manifest)
+	manifest := l.manifest
+	if manifest == nil {
+		generatedManifest := l.generateManifest(ctx, rule, params.name)
+		manifest = generatedManifest
+		rule.Temporary(generatedManifest)
 	}
 
-	lintPaths := l.writeLintProjectXML(ctx, rule)
-
-	html := android.PathForModuleOut(ctx, "lint", "lint-report.html")
-	text := android.PathForModuleOut(ctx, "lint", "lint-report.txt")
-	xml := android.PathForModuleOut(ctx, "lint", "lint-report.xml")
-
-	depSetsBuilder := NewLintDepSetBuilder().Direct(html, text, xml)
-
-	ctx.VisitDirectDepsWithTag(staticLibTag, func(dep android.Module) {
-		if depLint, ok := dep.(lintDepSetsIntf); ok {
-			depSetsBuilder.Transitive(depLint.LintDepSets())
-		}
-	})
+	lintPaths := l.writeLintProjectXML(ctx, rule, params.name, manifest)
 
 	rule.Command().Text("rm -rf").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
 	rule.Command().Text("mkdir -p").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
-	rule.Command().Text("rm -f").Output(html).Output(text).Output(xml)
+	rmCmd := rule.Command().Text("rm -f")
+	for _, output := range []android.WritablePath{params.html, params.text, params.xml, params.updatedBaseline} {
+		if output != nil {
+			rmCmd.Output(output)
+		}
+	}
 
 	var apiVersionsName, apiVersionsPrebuilt string
 	if l.compileSdkKind == android.SdkModule || l.compileSdkKind == android.SdkSystemServer {
@@ -422,16 +492,26 @@ func (l *linter) lint(ctx android.ModuleContext) {
 	cmd.BuiltTool("lint").ImplicitTool(ctx.Config().HostJavaToolPath(ctx, "lint.jar")).
 		Flag("--quiet").
 		FlagWithInput("--project ", lintPaths.projectXML).
-		FlagWithInput("--config ", lintPaths.configXML).
-		FlagWithOutput("--html ", html).
-		FlagWithOutput("--text ", text).
-		FlagWithOutput("--xml ", xml).
-		FlagWithArg("--compile-sdk-version ", l.compileSdkVersion).
+		FlagWithInput("--config ", lintPaths.configXML)
+
+	if params.html != nil {
+		cmd.FlagWithOutput("--html ", params.html)
+	}
+	cmd.FlagWithOutput("--text ", params.text)
+	if params.xml != nil {
+		cmd.FlagWithOutput("--xml ", params.xml)
+	}
+
+	cmd.FlagWithArg("--compile-sdk-version ", l.compileSdkVersion).
 		FlagWithArg("--java-language-level ", l.javaLanguageLevel).
 		FlagWithArg("--kotlin-language-level ", l.kotlinLanguageLevel).
-		FlagWithArg("--url ", fmt.Sprintf(".=.,%s=out", android.PathForOutput(ctx).String())).
-		Flag("--exitcode").
-		Flags(l.properties.Lint.Flags).
+		FlagWithArg("--url ", fmt.Sprintf(".=.,%s=out", android.PathForOutput(ctx).String()))
+
+	if params.updatedBaseline == nil {
+		cmd.Flag("--exitcode")
+	}
+
+	cmd.Flags(l.properties.Lint.Flags).
 		Implicit(annotationsZipPath).
 		Implicit(apiVersionsXMLPath)
 
@@ -442,37 +522,30 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		cmd.FlagWithArg("--check ", checkOnly)
 	}
 
-	lintBaseline := l.getBaselineFilepath(ctx)
-	if lintBaseline.Valid() {
-		cmd.FlagWithInput("--baseline ", lintBaseline.Path())
+	if params.baseline.Valid() {
+		cmd.FlagWithInput("--baseline ", params.baseline.Path())
 	}
 
-	cmd.Text("|| (").Text("if [ -e").Input(text).Text("]; then cat").Input(text).Text("; fi; exit 7)")
+	if params.updatedBaseline != nil {
+		cmd.FlagWithOutput("--write-reference-baseline ", params.updatedBaseline)
+	} else {
+		cmd.Text("|| (").Text("if [ -e").Input(params.text).Text("]; then cat").Input(params.text).Text("; fi; exit 7)")
+	}
 
 	rule.Command().Text("rm -rf").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
 
-	// The HTML output contains a date, remove it to make the output deterministic.
-	rule.Command().Text(`sed -i.tmp -e 's|Check performed at .*\(</nav>\)|\1|'`).Output(html)
-
-	rule.Build("lint", "lint")
-
-	l.outputs = lintOutputs{
-		html: html,
-		text: text,
-		xml:  xml,
-
-		depSets: depSetsBuilder.Build(),
+	if params.html != nil {
+		// The HTML output contains a date, remove it to make the output deterministic.
+		rule.Command().Text(`sed -i.tmp -e 's|Check performed at .*\(</nav>\)|\1|'`).Output(params.html)
 	}
 
-	if l.buildModuleReportZip {
-		l.reports = BuildModuleLintReportZips(ctx, l.LintDepSets())
-	}
+	rule.Build(params.name, strings.Replace(params.name, "-", " ", -1))
 }
 
 func BuildModuleLintReportZips(ctx android.ModuleContext, depSets LintDepSets) android.Paths {
-	htmlList := depSets.HTML.ToSortedList()
-	textList := depSets.Text.ToSortedList()
-	xmlList := depSets.XML.ToSortedList()
+	htmlList := android.SortedUniquePaths(depSets.HTML.ToList())
+	textList := android.SortedUniquePaths(depSets.Text.ToList())
+	xmlList := android.SortedUniquePaths(depSets.XML.ToList())
 
 	if len(htmlList) == 0 && len(textList) == 0 && len(xmlList) == 0 {
 		return nil
@@ -494,10 +567,13 @@ type lintSingleton struct {
 	htmlZip android.WritablePath
 	textZip android.WritablePath
 	xmlZip  android.WritablePath
+
+	baselineReport android.WritablePath
 }
 
 func (l *lintSingleton) GenerateBuildActions(ctx android.SingletonContext) {
 	l.generateLintReportZips(ctx)
+	l.generateLintBaselineUpdates(ctx)
 	l.copyLintDependencies(ctx)
 }
 
@@ -571,23 +647,8 @@ func (l *lintSingleton) generateLintReportZips(ctx android.SingletonContext) {
 
 	var outputs []*lintOutputs
 	var dirs []string
-	ctx.VisitAllModules(func(m android.Module) {
-		if ctx.Config().KatiEnabled() && !m.ExportedToMake() {
-			return
-		}
-
-		if apex, ok := m.(android.ApexModule); ok && apex.NotAvailableForPlatform() {
-			apexInfo := ctx.ModuleProvider(m, android.ApexInfoProvider).(android.ApexInfo)
-			if apexInfo.IsForPlatform() {
-				// There are stray platform variants of modules in apexes that are not available for
-				// the platform, and they sometimes can't be built.  Don't depend on them.
-				return
-			}
-		}
-
-		if l, ok := m.(lintOutputsIntf); ok {
-			outputs = append(outputs, l.lintOutputs())
-		}
+	visitLintOutputs(ctx, func(m android.Module, o *lintOutputs) {
+		outputs = append(outputs, o)
 	})
 
 	dirs = android.SortedUniqueStrings(dirs)
@@ -616,10 +677,92 @@ func (l *lintSingleton) generateLintReportZips(ctx android.SingletonContext) {
 	ctx.Phony("lint-check", l.htmlZip, l.textZip, l.xmlZip)
 }
 
+// visitLintOutputs calls visit for the lint outputs of each module that can be built.
+func visitLintOutputs(ctx android.SingletonContext, visit func(android.Module, *lintOutputs)) {
+	android.VisitBuildableModules(ctx, func(m android.Module) {
+		if l, ok := m.(lintOutputsIntf); ok {
+			visit(m, l.lintOutputs())
+		}
+	})
+}
+
+// generateLintBaselineUpdates adds the lint-baseline-update goal, which copies the refreshed
+// baselines of all modules that have a baseline to out/soong/lint-baselines/<dir>/<module> and
+// writes a report of the issues that can be removed from the baselines and the issues that are
+// only suppressed by them, grouped by directory.
+func (l *lintSingleton) generateLintBaselineUpdates(ctx android.SingletonContext) {
+	type baselineUpdate struct {
+		dir, name         string
+		baseline, updated android.Path
+	}
+	var updates []baselineUpdate
+	seen := make(map[string]bool)
+
+	visitLintOutputs(ctx, func(m android.Module, o *lintOutputs) {
+		if o.updatedBaseline == nil {
+			return
+		}
+
+		// Only use the first variant of each module.
+		dir, name := ctx.ModuleDir(m), ctx.ModuleName(m)
+		if seen[dir+"/"+name] {
+			return
+		}
+		seen[dir+"/"+name] = true
+
+		updates = append(updates, baselineUpdate{dir, name, o.baseline, o.updatedBaseline})
+	})
+
+	if len(updates) == 0 {
+		return
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].dir != updates[j].dir {
+			return updates[i].dir < updates[j].dir
+		}
+		return updates[i].name < updates[j].name
+	})
+
+	var baselines, updatedBaselines, copiedBaselines android.Paths
+	var list strings.Builder
+	for _, update := range updates {
+		copied := android.PathForOutput(ctx, "lint-baselines", update.dir, update.name, update.updated.Base())
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Cp,
+			Input:  update.updated,
+			Output: copied,
+		})
+
+		baselines = append(baselines, update.baseline)
+		updatedBaselines = append(updatedBaselines, update.updated)
+		copiedBaselines = append(copiedBaselines, copied)
+		fmt.Fprintf(&list, "%s %s %s %s\n", update.dir, update.name, update.baseline, update.updated)
+	}
+
+	baselineList := android.PathForOutput(ctx, "lint-baselines.list")
+	android.WriteFileRule(ctx, baselineList, list.String())
+
+	l.baselineReport = android.PathForOutput(ctx, "lint-baseline-report.txt")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("lint_baseline_report").
+		FlagWithInput("--baselines ", baselineList).
+		FlagWithOutput("--out ", l.baselineReport).
+		Implicits(baselines).
+		Implicits(updatedBaselines)
+	rule.Build("lint_baseline_report", "lint baseline report")
+
+	ctx.Phony("lint-baseline-update", append(copiedBaselines, l.baselineReport)...)
+}
+
 func (l *lintSingleton) MakeVars(ctx android.MakeVarsContext) {
 	if !ctx.Config().UnbundledBuild() {
 		ctx.DistForGoal("lint-check", l.htmlZip, l.textZip, l.xmlZip)
 	}
+	if l.baselineReport != nil {
+		ctx.DistForGoal("lint-baseline-update", l.baselineReport)
+	}
 }
 
 var _ android.SingletonMakeVarsProvider = (*lintSingleton)(nil)
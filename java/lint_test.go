@@ -67,6 +67,86 @@ func TestJavaLintWithoutBaseline(t *testing.T) {
 	}
 }
 
+func TestJavaLintBaselineUpdate(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		// The lint singleton is not registered by default as it requires framework-doc-stubs
+		// unless missing dependencies are allowed.
+		android.PrepareForTestWithAllowMissingDependencies,
+		android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+			ctx.RegisterSingletonType("lint", func() android.Singleton { return &lintSingleton{} })
+		}),
+		android.FixtureAddTextFile("a/Android.bp", `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				min_sdk_version: "29",
+				sdk_version: "system_current",
+			}
+		`),
+		android.FixtureAddTextFile("b/Android.bp", `
+			java_library {
+				name: "bar",
+				srcs: ["a.java"],
+				min_sdk_version: "29",
+				sdk_version: "system_current",
+				lint: {
+					baseline_filename: "bar-baseline.xml",
+				},
+			}
+		`),
+		android.MockFS{
+			"a/lint-baseline.xml": nil,
+			"b/bar-baseline.xml":  nil,
+		}.AddToFixture(),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "baz",
+			srcs: ["a.java"],
+			min_sdk_version: "29",
+			sdk_version: "system_current",
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	sboxProto := android.RuleBuilderSboxProtoForTests(t, foo.Output("lint-baseline.sbox.textproto"))
+	cmd := *sboxProto.Commands[0].Command
+	if !strings.Contains(cmd, "--baseline a/lint-baseline.xml") {
+		t.Error("did not pass --baseline flag to the baseline update")
+	}
+	if !strings.Contains(cmd, "--write-reference-baseline __SBOX_SANDBOX_DIR__/out/lint-baseline.xml") {
+		t.Error("did not write the refreshed baseline")
+	}
+	if strings.Contains(cmd, "--exitcode") {
+		t.Error("baseline update should not fail on lint errors")
+	}
+
+	bar := result.ModuleForTests("bar", "android_common")
+	bar.Output("lint-baseline/bar-baseline.xml")
+
+	// Modules without a baseline don't rerun lint.
+	baz := result.ModuleForTests("baz", "android_common")
+	if baz.MaybeOutput("lint-baseline.sbox.textproto").Rule != nil {
+		t.Error("reran lint for a module without a baseline")
+	}
+
+	lint := result.SingletonForTests("lint")
+	lint.Output("lint-baselines/a/foo/lint-baseline.xml")
+	lint.Output("lint-baselines/b/bar/bar-baseline.xml")
+
+	list := android.ContentFromFileRuleForTests(t, lint.Output("lint-baselines.list"))
+	android.AssertStringEquals(t, "baselines list", strings.Join([]string{
+		"a foo a/lint-baseline.xml out/soong/.intermediates/a/foo/android_common/lint-baseline/lint-baseline.xml",
+		"b bar b/bar-baseline.xml out/soong/.intermediates/b/bar/android_common/lint-baseline/bar-baseline.xml",
+		"",
+	}, "\n"), android.StringRelativeToTop(result.Config, list))
+
+	report := lint.Rule("lint_baseline_report")
+	android.AssertStringDoesContain(t, "report command",
+		android.StringRelativeToTop(result.Config, report.RuleParams.Command),
+		"--baselines out/soong/lint-baselines.list")
+}
+
 func TestJavaLintRequiresCustomLintFileToExist(t *testing.T) {
 	android.GroupFixturePreparers(
 		PrepareForTestWithJavaDefaultModules,
@@ -294,3 +374,21 @@ func TestJavaLintDatabaseSelectionPublicFiltered(t *testing.T) {
 		}
 	}
 }
+
+func TestJavaLintSarif(t *testing.T) {
+	result := android.GroupFixturePreparers(PrepareForTestWithJavaDefaultModules).
+		RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			min_sdk_version: "29",
+			sdk_version: "system_current",
+		}
+	`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	sarif := foo.Output("sarif/lint.sarif")
+	android.AssertStringDoesContain(t, "sarif_report command", sarif.RuleParams.Command, "-format lint")
+	android.AssertPathsRelativeToTopEquals(t, "sarif_report inputs",
+		[]string{"out/soong/.intermediates/foo/android_common/lint/lint-report.xml"}, sarif.Inputs)
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+// The r8-mappings goal collects the R8 mapping files of the apps and libraries that are optimized
+// by R8 and of the prebuilt apps that provide one, together with the merged keep rules that R8
+// used, into out/soong/r8/r8-mappings.zip.  The zip indexes the mapping files by build ID so that
+// the retrace tool can symbolize a stack trace with it:
+//
+//	retrace -index r8-mappings.zip -id <build id> trace.txt
+
+func init() {
+	registerR8MappingsBuildComponents(android.InitRegistrationContext)
+}
+
+func registerR8MappingsBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("r8_mappings", r8MappingsSingletonFactory)
+}
+
+// r8MappingsIntf is implemented by modules that may have an R8 mapping file.
+type r8MappingsIntf interface {
+	// r8Mappings returns the mapping file and the merged keep rules of the module, either of
+	// which may be invalid.
+	r8Mappings() (dictionary, configuration android.OptionalPath)
+}
+
+func (d *dexer) r8Mappings() (dictionary, configuration android.OptionalPath) {
+	return d.proguardDictionary, d.proguardConfiguration
+}
+
+var _ r8MappingsIntf = (*dexer)(nil)
+var _ r8MappingsIntf = (*AndroidAppImport)(nil)
+
+func r8MappingsSingletonFactory() android.Singleton {
+	return &r8MappingsSingleton{}
+}
+
+type r8MappingsSingleton struct {
+	mappingsZip android.Path
+}
+
+func (s *r8MappingsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	// Each line of the list is the name of a module, its mapping file and optionally its keep
+	// rules, as read by retrace -build-index.
+	list := &strings.Builder{}
+	var inputs android.Paths
+	android.VisitBuildableModules(ctx, func(m android.Module) {
+		r, ok := m.(r8MappingsIntf)
+		if !ok {
+			return
+		}
+		dictionary, configuration := r.r8Mappings()
+		if !dictionary.Valid() {
+			return
+		}
+
+		fmt.Fprintf(list, "%s %s", android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName(m)), dictionary.Path())
+		inputs = append(inputs, dictionary.Path())
+		if configuration.Valid() {
+			fmt.Fprintf(list, " %s", configuration.Path())
+			inputs = append(inputs, configuration.Path())
+		}
+		fmt.Fprintln(list)
+	})
+
+	if len(inputs) == 0 {
+		return
+	}
+
+	listFile := android.PathForOutput(ctx, "r8", "r8-mappings.list")
+	android.WriteFileRule(ctx, listFile, list.String())
+
+	mappingsZip := android.PathForOutput(ctx, "r8", "r8-mappings.zip")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("retrace").
+		Flag("-build-index").
+		FlagWithOutput("-o ", mappingsZip).
+		FlagWithInput("-l ", listFile).
+		Implicits(inputs)
+	rule.Build("r8_mappings", "R8 mappings zip")
+
+	s.mappingsZip = mappingsZip
+	ctx.Phony("r8-mappings", mappingsZip)
+}
+
+func (s *r8MappingsSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.mappingsZip != nil {
+		ctx.DistForGoal("r8-mappings", s.mappingsZip)
+	}
+}
+
+var _ android.SingletonMakeVarsProvider = (*r8MappingsSingleton)(nil)
@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestR8Mappings(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeMockFs(android.MockFS{
+			"prebuilts/apk/app.apk":     nil,
+			"prebuilts/apk/mapping.txt": nil,
+		}),
+	).RunTestWithBp(t, `
+		android_app {
+			name: "app",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "unoptimized",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			optimize: {
+				enabled: false,
+			},
+		}
+
+		android_app_import {
+			name: "prebuilt",
+			apk: "prebuilts/apk/app.apk",
+			presigned: true,
+			proguard_dictionary: "prebuilts/apk/mapping.txt",
+		}
+	`)
+
+	dictionary := "out/soong/.intermediates/app/android_common/proguard_dictionary"
+	configuration := "out/soong/.intermediates/app/android_common/proguard_configuration.txt"
+
+	r8 := result.ModuleForTests("app", "android_common").Rule("r8")
+	android.AssertStringListContains(t, "r8 implicit outputs",
+		android.PathsRelativeToTop(r8.ImplicitOutputs.Paths()), configuration)
+
+	mappings := result.SingletonForTests("r8_mappings")
+	list := android.ContentFromFileRuleForTests(t, mappings.Output("r8/r8-mappings.list"))
+	android.AssertStringDoesContain(t, "mappings list", list, "app "+dictionary+" "+configuration+"\n")
+	android.AssertStringDoesContain(t, "mappings list", list, "prebuilt prebuilts/apk/mapping.txt\n")
+	android.AssertStringDoesNotContain(t, "mappings list", list, "unoptimized")
+
+	rule := mappings.Rule("r8_mappings")
+	android.AssertStringDoesContain(t, "retrace command", rule.RuleParams.Command,
+		"retrace -build-index -o out/soong/r8/r8-mappings.zip -l out/soong/r8/r8-mappings.list")
+	android.AssertStringListContains(t, "retrace inputs", android.PathsRelativeToTop(rule.Implicits), dictionary)
+	android.AssertStringListContains(t, "retrace inputs", android.PathsRelativeToTop(rule.Implicits), configuration)
+	android.AssertStringListContains(t, "retrace inputs", android.PathsRelativeToTop(rule.Implicits),
+		"prebuilts/apk/mapping.txt")
+}
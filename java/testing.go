@@ -248,6 +248,7 @@ func registerRequiredBuildComponentsForTest(ctx android.RegistrationContext) {
 	registerJavaBuildComponents(ctx)
 	registerPlatformBootclasspathBuildComponents(ctx)
 	RegisterPrebuiltApisBuildComponents(ctx)
+	registerR8MappingsBuildComponents(ctx)
 	RegisterRuntimeResourceOverlayBuildComponents(ctx)
 	RegisterSdkLibraryBuildComponents(ctx)
 	RegisterStubsBuildComponents(ctx)
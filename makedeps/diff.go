@@ -0,0 +1,81 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// A DepsDiff is the difference between two depfiles.  The order and duplicates of the inputs are
+// not significant.
+type DepsDiff struct {
+	OldOutput, NewOutput string
+
+	// Added are the inputs that are only in the new depfile, sorted.
+	Added []string
+
+	// Removed are the inputs that are only in the old depfile, sorted.
+	Removed []string
+}
+
+// Diff returns the difference between old and new.
+func Diff(old, new *Deps) *DepsDiff {
+	oldInputs := make(map[string]bool)
+	for _, input := range old.Inputs {
+		oldInputs[input] = true
+	}
+	newInputs := make(map[string]bool)
+	for _, input := range new.Inputs {
+		newInputs[input] = true
+	}
+
+	diff := &DepsDiff{OldOutput: old.Output, NewOutput: new.Output}
+	for input := range newInputs {
+		if !oldInputs[input] {
+			diff.Added = append(diff.Added, input)
+		}
+	}
+	for input := range oldInputs {
+		if !newInputs[input] {
+			diff.Removed = append(diff.Removed, input)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// Empty returns true if the depfiles have the same output and inputs.
+func (d *DepsDiff) Empty() bool {
+	return d.OldOutput == d.NewOutput && len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// String returns the difference in a format similar to a unified diff, with a line for each
+// removed or added path prefixed by "-" or "+".
+func (d *DepsDiff) String() string {
+	b := &bytes.Buffer{}
+	if d.OldOutput != d.NewOutput {
+		fmt.Fprintf(b, "-%s:\n+%s:\n", d.OldOutput, d.NewOutput)
+	}
+	for _, input := range d.Removed {
+		fmt.Fprintf(b, "-%s\n", input)
+	}
+	for _, input := range d.Added {
+		fmt.Fprintf(b, "+%s\n", input)
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new Deps
+		want     string
+	}{
+		{
+			name: "same",
+			old:  Deps{Output: "a", Inputs: []string{"b", "c"}},
+			new:  Deps{Output: "a", Inputs: []string{"c", "b", "c"}},
+			want: "",
+		},
+		{
+			name: "inputs",
+			old:  Deps{Output: "a", Inputs: []string{"b", "d", "c"}},
+			new:  Deps{Output: "a", Inputs: []string{"c", "f", "e"}},
+			want: "-b\n-d\n+e\n+f\n",
+		},
+		{
+			name: "output",
+			old:  Deps{Output: "a", Inputs: []string{"b"}},
+			new:  Deps{Output: "z", Inputs: []string{"b"}},
+			want: "-a:\n+z:\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := Diff(&tc.old, &tc.new)
+			if g, w := diff.Empty(), tc.want == ""; g != w {
+				t.Errorf("want Empty() %v, got %v", w, g)
+			}
+			if g := diff.String(); g != tc.want {
+				t.Errorf("want diff:\n%s\ngot:\n%s", tc.want, g)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Merge returns a Deps with the output of the first of deps and the inputs of all of deps, in
+// order and without duplicates.  Ninja only supports a single output per depfile, so the outputs
+// of the others are ignored.
+func Merge(deps ...*Deps) *Deps {
+	ret := &Deps{}
+	seen := make(map[string]bool)
+	for i, d := range deps {
+		if i == 0 {
+			ret.Output = d.Output
+		}
+		for _, input := range d.Inputs {
+			if !seen[input] {
+				seen[input] = true
+				ret.Inputs = append(ret.Inputs, input)
+			}
+		}
+	}
+	return ret
+}
+
+// Relativize rewrites the absolute paths in d that are under root to be relative to root, which
+// must be an absolute path.  Ninja records the paths from depfiles in .ninja_deps verbatim, so an
+// absolute path to a file in the source tree makes it look like a different file than the
+// relative path that the rest of the build uses.
+//
+// Absolute inputs that are not under root are left unchanged and returned, so that the caller can
+// decide whether to drop them with RemoveInputs or report an error.
+func (d *Deps) Relativize(root string) (outside []string) {
+	root = filepath.Clean(root)
+	relativize := func(path string) (string, bool) {
+		if !filepath.IsAbs(path) {
+			return path, true
+		}
+		if rel, ok := relativeTo(root, path); ok {
+			return rel, true
+		}
+		return path, false
+	}
+
+	d.Output, _ = relativize(d.Output)
+	for i, input := range d.Inputs {
+		rel, ok := relativize(input)
+		if !ok {
+			outside = append(outside, input)
+		}
+		d.Inputs[i] = rel
+	}
+	return outside
+}
+
+// RemoveInputs removes all of the inputs in remove from d.
+func (d *Deps) RemoveInputs(remove []string) {
+	removeSet := make(map[string]bool)
+	for _, input := range remove {
+		removeSet[input] = true
+	}
+	inputs := d.Inputs[:0]
+	for _, input := range d.Inputs {
+		if !removeSet[input] {
+			inputs = append(inputs, input)
+		}
+	}
+	d.Inputs = inputs
+}
+
+// relativeTo returns path relative to root if path is root or a path under it.  Both paths must
+// be clean and absolute.
+func relativeTo(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
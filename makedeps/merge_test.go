@@ -0,0 +1,72 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	got := Merge(
+		&Deps{Output: "a", Inputs: []string{"b", "c"}},
+		&Deps{Output: "d", Inputs: []string{"c", "e"}},
+		&Deps{Inputs: []string{"b", "f"}},
+	)
+	want := &Deps{Output: "a", Inputs: []string{"b", "c", "e", "f"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestRelativize(t *testing.T) {
+	deps := &Deps{
+		Output: "/src/out/a.o",
+		Inputs: []string{
+			"a.c",
+			"/src/a.h",
+			"/src/../usr/include/stdio.h",
+			"/src2/b.h",
+			"/src",
+		},
+	}
+
+	outside := deps.Relativize("/src/")
+
+	want := &Deps{
+		Output: "out/a.o",
+		Inputs: []string{
+			"a.c",
+			"a.h",
+			"/src/../usr/include/stdio.h",
+			"/src2/b.h",
+			".",
+		},
+	}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("want %#v, got %#v", want, deps)
+	}
+
+	wantOutside := []string{"/src/../usr/include/stdio.h", "/src2/b.h"}
+	if !reflect.DeepEqual(outside, wantOutside) {
+		t.Errorf("want outside %q, got %q", wantOutside, outside)
+	}
+
+	deps.RemoveInputs(outside)
+	wantInputs := []string{"a.c", "a.h", "."}
+	if !reflect.DeepEqual(deps.Inputs, wantInputs) {
+		t.Errorf("want inputs %q after RemoveInputs, got %q", wantInputs, deps.Inputs)
+	}
+}
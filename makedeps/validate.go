@@ -0,0 +1,92 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A ValidationError lists the inputs of a depfile that failed Validate.
+type ValidationError struct {
+	Output string
+
+	// Missing are the inputs that don't exist.
+	Missing []string
+
+	// Disallowed are the inputs that are not under any of the allowed roots.
+	Disallowed []string
+}
+
+func (e *ValidationError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing inputs: %s", strings.Join(e.Missing, " ")))
+	}
+	if len(e.Disallowed) > 0 {
+		problems = append(problems, fmt.Sprintf("inputs outside of the allowed roots: %s",
+			strings.Join(e.Disallowed, " ")))
+	}
+	return fmt.Sprintf("invalid dependencies for %q: %s", e.Output, strings.Join(problems, "; "))
+}
+
+// Validate checks that every input of d exists and, if allowedRoots is not empty, is under one of
+// allowedRoots.  Relative inputs and roots are relative to the working directory.  It returns a
+// *ValidationError that lists all of the invalid inputs.
+func (d *Deps) Validate(allowedRoots []string) error {
+	var roots []string
+	for _, root := range allowedRoots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		roots = append(roots, absRoot)
+	}
+
+	validationErr := &ValidationError{Output: d.Output}
+	for _, input := range d.Inputs {
+		if len(roots) > 0 {
+			absInput, err := filepath.Abs(input)
+			if err != nil {
+				return err
+			}
+			if !underAnyRoot(roots, absInput) {
+				validationErr.Disallowed = append(validationErr.Disallowed, input)
+			}
+		}
+
+		if _, err := os.Stat(input); os.IsNotExist(err) {
+			validationErr.Missing = append(validationErr.Missing, input)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(validationErr.Missing) > 0 || len(validationErr.Disallowed) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+func underAnyRoot(roots []string, path string) bool {
+	for _, root := range roots {
+		if _, ok := relativeTo(root, path); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package makedeps
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"src/a.h", "prebuilts/b.h", "other/c.h"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(file string) string { return filepath.Join(dir, file) }
+	roots := []string{path("src"), path("prebuilts")}
+
+	testCases := []struct {
+		name           string
+		inputs         []string
+		roots          []string
+		wantMissing    []string
+		wantDisallowed []string
+	}{
+		{
+			name:   "valid",
+			inputs: []string{path("src/a.h"), path("prebuilts/b.h")},
+			roots:  roots,
+		},
+		{
+			name:   "no roots",
+			inputs: []string{path("src/a.h"), path("other/c.h")},
+		},
+		{
+			name:        "missing",
+			inputs:      []string{path("src/a.h"), path("src/missing.h")},
+			roots:       roots,
+			wantMissing: []string{path("src/missing.h")},
+		},
+		{
+			name:           "disallowed",
+			inputs:         []string{path("other/c.h"), path("src/../other/c.h"), path("src/a.h")},
+			roots:          roots,
+			wantDisallowed: []string{path("other/c.h"), path("src/../other/c.h")},
+		},
+		{
+			name:           "missing and disallowed",
+			inputs:         []string{path("other/missing.h")},
+			roots:          roots,
+			wantMissing:    []string{path("other/missing.h")},
+			wantDisallowed: []string{path("other/missing.h")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Deps{Output: "out", Inputs: tc.inputs}).Validate(tc.roots)
+			if tc.wantMissing == nil && tc.wantDisallowed == nil {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("want *ValidationError, got %v", err)
+			}
+			if !reflect.DeepEqual(validationErr.Missing, tc.wantMissing) {
+				t.Errorf("want missing %q, got %q", tc.wantMissing, validationErr.Missing)
+			}
+			if !reflect.DeepEqual(validationErr.Disallowed, tc.wantDisallowed) {
+				t.Errorf("want disallowed %q, got %q", tc.wantDisallowed, validationErr.Disallowed)
+			}
+		})
+	}
+}
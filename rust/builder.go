@@ -60,7 +60,10 @@ var (
 				// Because clippy-driver uses rustc as backend, we need to have some output even during the linting.
 				// Use the metadata output as it has the smallest footprint.
 				"--emit metadata -o $out --emit dep-info=$out.d.raw $in ${libFlags} " +
-				"$rustcFlags $clippyFlags" +
+				// The diagnostics are printed and also saved in $out.log to be converted to a
+				// SARIF report.
+				"$rustcFlags $clippyFlags > $out.log 2>&1" +
+				"; status=$$?; cat $out.log; [ $$status -eq 0 ]" +
 				" && grep \"^$out:\" $out.d.raw > $out.d",
 			CommandDeps: []string{"$clippyCmd"},
 			Deps:        blueprint.DepsGCC,
@@ -282,11 +285,12 @@ func transformSrctoCrate(ctx ModuleContext, main android.Path, deps PathDeps, fl
 
 	if flags.Clippy {
 		clippyFile := android.PathForModuleOut(ctx, outputFile.Base()+".clippy")
+		clippyLog := android.PathForModuleOut(ctx, outputFile.Base()+".clippy.log")
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            clippyDriver,
 			Description:     "clippy " + main.Rel(),
 			Output:          clippyFile,
-			ImplicitOutputs: nil,
+			ImplicitOutputs: android.WritablePaths{clippyLog},
 			Inputs:          inputs,
 			Implicits:       implicits,
 			Args: map[string]string{
@@ -298,6 +302,8 @@ func transformSrctoCrate(ctx ModuleContext, main android.Path, deps PathDeps, fl
 		})
 		// Declare the clippy build as an implicit dependency of the original crate.
 		implicits = append(implicits, clippyFile)
+
+		android.BuildSarifReport(ctx, "clippy", android.Paths{clippyLog})
 	}
 
 	ctx.Build(pctx, android.BuildParams{
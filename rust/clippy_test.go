@@ -75,3 +75,30 @@ func TestClippy(t *testing.T) {
 		})
 	}
 }
+
+func TestClippySarif(t *testing.T) {
+	ctx := testRust(t, `
+		rust_library {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			crate_name: "foo",
+		}
+		rust_library {
+			name: "libfoobar",
+			srcs: ["foo.rs"],
+			crate_name: "foobar",
+			clippy_lints: "none",
+		}`)
+
+	foo := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_dylib")
+	clippy := foo.Rule("clippy")
+	sarif := foo.Output("sarif/clippy.sarif")
+	android.AssertStringDoesContain(t, "sarif_report command", sarif.RuleParams.Command, "-format clippy")
+	android.AssertPathsRelativeToTopEquals(t, "sarif_report inputs",
+		[]string{clippy.Output.RelativeToTop().String() + ".log"}, sarif.Inputs)
+
+	foobar := ctx.ModuleForTests("libfoobar", "android_arm64_armv8-a_dylib")
+	if foobar.MaybeOutput("sarif/clippy.sarif").Rule != nil {
+		t.Errorf("libfoobar has a SARIF report when clippy is disabled")
+	}
+}
@@ -0,0 +1,118 @@
+#!/usr/bin/env python3
+#
+# Copyright (C) 2021 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+
+"""This file compares lint baselines with the refreshed baselines written by lint and reports the
+issues that have been fixed and the issues that are only suppressed by the baselines."""
+
+import argparse
+import collections
+from xml.dom import minidom
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--baselines', dest='baselines', required=True,
+                      help='file containing a line for each baseline with the directory and name of '
+                      'the module, the path to the baseline and the path to the refreshed baseline.')
+  parser.add_argument('--out', dest='out', required=True,
+                      help='file to which the report will be written.')
+  return parser.parse_args()
+
+
+def read_baseline_list(f):
+  """Returns (dir, module, baseline, refreshed) tuples read from the list of baselines."""
+  baselines = []
+  for line in f:
+    fields = line.split()
+    if not fields:
+      continue
+    if len(fields) != 4:
+      raise RuntimeError('expected "dir module baseline refreshed", got %r' % line)
+    baselines.append(tuple(fields))
+  return baselines
+
+
+def baseline_issues(baseline):
+  """Returns a Counter of the issues in a baseline.
+
+  Issues are identified by their id, message and the file of their first location, ignoring line
+  numbers the same way lint does when it matches issues against a baseline.
+  """
+  issues_element = baseline.documentElement
+  if issues_element.tagName != 'issues':
+    raise RuntimeError('expected issues tag at root')
+  issues = collections.Counter()
+  for issue in issues_element.getElementsByTagName('issue'):
+    locations = issue.getElementsByTagName('location')
+    location = locations[0].getAttribute('file') if locations else ''
+    issues[(issue.getAttribute('id'), location, issue.getAttribute('message'))] += 1
+  return issues
+
+
+def compare_baselines(baseline, refreshed):
+  """Returns the sorted lists of fixed issues and issues suppressed by the baseline."""
+  old = baseline_issues(baseline)
+  new = baseline_issues(refreshed)
+  fixed = sorted((old - new).elements())
+  suppressed = sorted((old & new).elements())
+  return fixed, suppressed
+
+
+def write_report(f, results):
+  """Writes the report for results, a list of (dir, module, baseline, fixed, suppressed) tuples."""
+  total_fixed = sum(len(r[3]) for r in results)
+  total_suppressed = sum(len(r[4]) for r in results)
+  f.write('%d fixed issues can be removed and %d issues are suppressed only by %d baselines\n'
+          % (total_fixed, total_suppressed, len(results)))
+
+  by_dir = collections.defaultdict(list)
+  for result in results:
+    by_dir[result[0]].append(result)
+
+  for dir in sorted(by_dir):
+    f.write('\n%s\n' % dir)
+    for _, module, baseline, fixed, suppressed in sorted(by_dir[dir], key=lambda r: r[1]):
+      f.write('  %s (%s)\n' % (module, baseline))
+      for title, issues in (('fixed, can be removed', fixed),
+                            ('suppressed only by the baseline', suppressed)):
+        if issues:
+          f.write('    %s (%d):\n' % (title, len(issues)))
+          for id, location, message in issues:
+            f.write('      %s %s: %s\n' % (id, location, message))
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  with open(args.baselines) as f:
+    baselines = read_baseline_list(f)
+
+  results = []
+  for dir, module, baseline_path, refreshed_path in baselines:
+    fixed, suppressed = compare_baselines(minidom.parse(baseline_path),
+                                          minidom.parse(refreshed_path))
+    results.append((dir, module, baseline_path, fixed, suppressed))
+
+  with open(args.out, 'w') as f:
+    write_report(f, results)
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+#
+# Copyright (C) 2021 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+
+"""Unit tests for lint_baseline_report.py."""
+
+import io
+import unittest
+from xml.dom import minidom
+
+import lint_baseline_report
+
+
+def issues_xml(*issues):
+  xml = ('<?xml version="1.0" encoding="utf-8"?>\n'
+         '<issues format="5" by="lint 4.1.0" client="cli" variant="all" version="4.1.0">\n')
+  for id, file, line, message in issues:
+    xml += ('    <issue id="%s" message="%s">\n'
+            '        <location file="%s" line="%d"/>\n'
+            '    </issue>\n') % (id, message, file, line)
+  xml += '</issues>\n'
+  return minidom.parseString(xml)
+
+
+class CompareBaselinesTest(unittest.TestCase):
+  """Unit tests for compare_baselines function."""
+
+  def test_compare_baselines(self):
+    baseline = issues_xml(
+        ('NewApi', 'a/b.java', 3, 'foo is evil'),
+        ('NewApi', 'a/b.java', 5, 'foo is evil'),
+        ('Bar', 'a/c.java', 10, 'bar is evil'),
+        ('Baz', 'a/c.java', 12, 'baz is evil'))
+    # One of the foo issues and the bar issue were fixed, the baz issue moved to a different line
+    # and a new qux issue was added.
+    refreshed = issues_xml(
+        ('NewApi', 'a/b.java', 4, 'foo is evil'),
+        ('Baz', 'a/c.java', 20, 'baz is evil'),
+        ('Qux', 'a/c.java', 30, 'qux is evil'))
+
+    fixed, suppressed = lint_baseline_report.compare_baselines(baseline, refreshed)
+    self.assertEqual([
+        ('Bar', 'a/c.java', 'bar is evil'),
+        ('NewApi', 'a/b.java', 'foo is evil'),
+    ], fixed)
+    self.assertEqual([
+        ('Baz', 'a/c.java', 'baz is evil'),
+        ('NewApi', 'a/b.java', 'foo is evil'),
+    ], suppressed)
+
+
+class WriteReportTest(unittest.TestCase):
+  """Unit tests for read_baseline_list and write_report functions."""
+
+  def test_read_baseline_list(self):
+    baselines = lint_baseline_report.read_baseline_list(io.StringIO(
+        'a foo a/lint-baseline.xml out/foo/lint-baseline.xml\n'
+        '\n'
+        'b bar b/baseline.xml out/bar/baseline.xml\n'))
+    self.assertEqual([
+        ('a', 'foo', 'a/lint-baseline.xml', 'out/foo/lint-baseline.xml'),
+        ('b', 'bar', 'b/baseline.xml', 'out/bar/baseline.xml'),
+    ], baselines)
+
+  def test_write_report(self):
+    results = [
+        ('b', 'baz', 'b/lint-baseline.xml', [], []),
+        ('a', 'foo', 'a/lint-baseline.xml',
+         [('Bar', 'a/c.java', 'bar is evil')],
+         [('Baz', 'a/c.java', 'baz is evil')]),
+        ('a', 'bar', 'a/bar-baseline.xml', [('Foo', 'a/d.java', 'foo is evil')], []),
+    ]
+    f = io.StringIO()
+    lint_baseline_report.write_report(f, results)
+    self.assertEqual(
+        '2 fixed issues can be removed and 1 issues are suppressed only by 3 baselines\n'
+        '\n'
+        'a\n'
+        '  bar (a/bar-baseline.xml)\n'
+        '    fixed, can be removed (1):\n'
+        '      Foo a/d.java: foo is evil\n'
+        '  foo (a/lint-baseline.xml)\n'
+        '    fixed, can be removed (1):\n'
+        '      Bar a/c.java: bar is evil\n'
+        '    suppressed only by the baseline (1):\n'
+        '      Baz a/c.java: baz is evil\n'
+        '\n'
+        'b\n'
+        '  baz (b/lint-baseline.xml)\n',
+        f.getvalue())
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -29,6 +29,9 @@ var (
 	from   = flag.String("from", "", "optional existing value of the symbol for verification")
 	value  = flag.String("v", "", "value to inject into symbol")
 
+	manifest = flag.String("manifest", "", "JSON or symbol=value manifest file of symbols to inject")
+
+	list = flag.Bool("list", false, "list the symbols with their section, offset and size")
 	dump = flag.Bool("dump", false, "dump the symbol table for copying into a test")
 )
 
@@ -45,20 +48,39 @@ func main() {
 		usageError("-i is required")
 	}
 
-	if !*dump {
+	if !*dump && !*list {
 		if *output == "" {
 			usageError("-o is required")
 		}
 
-		if *symbol == "" {
-			usageError("-s is required")
+		if *symbol == "" && *manifest == "" {
+			usageError("-s or -manifest is required")
 		}
 
-		if *value == "" {
+		if *symbol != "" && *value == "" {
 			usageError("-v is required")
 		}
 	}
 
+	var injections []symbol_inject.Injection
+	if *symbol != "" {
+		injections = append(injections, symbol_inject.Injection{Symbol: *symbol, Value: *value, From: *from})
+	}
+	if *manifest != "" && !*dump && !*list {
+		m, err := os.Open(*manifest)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		manifestInjections, err := symbol_inject.ReadInjections(m)
+		m.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", *manifest, err.Error())
+			os.Exit(2)
+		}
+		injections = append(injections, manifestInjections...)
+	}
+
 	r, err := os.Open(*input)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -75,6 +97,20 @@ func main() {
 		return
 	}
 
+	if *list {
+		file, err := symbol_inject.OpenFile(r)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(4)
+		}
+		err = symbol_inject.ListSymbols(file, os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(6)
+		}
+		return
+	}
+
 	w, err := os.OpenFile(*output, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -88,7 +124,7 @@ func main() {
 		os.Exit(4)
 	}
 
-	err = symbol_inject.InjectStringSymbol(file, w, *symbol, *value, *from)
+	err = symbol_inject.InjectSymbols(file, w, injections)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Remove(*output)
@@ -0,0 +1,129 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbol_inject
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An Injection is a value to inject into a symbol.
+type Injection struct {
+	Symbol string `json:"symbol"`
+	Value  string `json:"value"`
+
+	// From is the optional existing value of a string symbol for verification.
+	From string `json:"from,omitempty"`
+
+	// Uint64 injects Value, parsed as an unsigned integer, as a little endian uint64 instead of a
+	// string.
+	Uint64 bool `json:"uint64,omitempty"`
+}
+
+// ReadInjections reads a list of injections from r.  The injections can be either a JSON list of
+// Injection objects, or a manifest with a "symbol=value" line for each string symbol.  Empty lines
+// and lines starting with "#" in a manifest are ignored.
+func ReadInjections(r io.Reader) ([]Injection, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var injections []Injection
+		if err := json.Unmarshal(data, &injections); err != nil {
+			return nil, err
+		}
+		for i, injection := range injections {
+			if injection.Symbol == "" {
+				return nil, fmt.Errorf("injection %d is missing a symbol", i)
+			}
+		}
+		return injections, nil
+	}
+
+	var injections []Injection
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		eq := strings.IndexByte(line, '=')
+		if eq < 1 {
+			return nil, fmt.Errorf("line %d: expected symbol=value, got %q", lineNum, line)
+		}
+		injections = append(injections, Injection{
+			Symbol: strings.TrimSpace(line[:eq]),
+			Value:  line[eq+1:],
+		})
+	}
+	return injections, scanner.Err()
+}
+
+// InjectSymbols copies the file to w with all of the injections applied in a single pass.  It
+// returns an error without writing anything if any of the values doesn't fit in its symbol, or if
+// a symbol is injected more than once.
+func InjectSymbols(file *File, w io.Writer, injections []Injection) error {
+	var values []injectedValue
+	symbols := make(map[uint64]string)
+
+	for _, injection := range injections {
+		offset, size, err := findSymbol(file, injection.Symbol)
+		if err != nil {
+			return fmt.Errorf("symbol %q: %w", injection.Symbol, err)
+		}
+
+		if other, exists := symbols[offset]; exists {
+			if other == injection.Symbol {
+				return fmt.Errorf("symbol %q is injected more than once", injection.Symbol)
+			}
+			return fmt.Errorf("symbols %q and %q overlap", other, injection.Symbol)
+		}
+		symbols[offset] = injection.Symbol
+
+		var buf []byte
+		if injection.Uint64 {
+			var value uint64
+			value, err = strconv.ParseUint(injection.Value, 0, 64)
+			if err == nil {
+				buf, err = uint64SymbolContents(injection.Symbol, size, value)
+			}
+		} else {
+			buf, err = stringSymbolContents(file, offset, size, injection.Value, injection.From)
+		}
+		if err != nil {
+			return fmt.Errorf("symbol %q: %w", injection.Symbol, err)
+		}
+
+		values = append(values, injectedValue{offset, buf})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].offset < values[j].offset })
+	for i := 1; i < len(values); i++ {
+		if prev := values[i-1]; prev.offset+uint64(len(prev.buf)) > values[i].offset {
+			return fmt.Errorf("symbols %q and %q overlap", symbols[prev.offset], symbols[values[i].offset])
+		}
+	}
+
+	return copyAndInjectAll(file.r, w, values)
+}
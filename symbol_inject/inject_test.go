@@ -0,0 +1,163 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package symbol_inject
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadInjections(t *testing.T) {
+	testCases := []struct {
+		name    string
+		in      string
+		want    []Injection
+		wantErr string
+	}{
+		{
+			name: "json",
+			in: `[
+				{"symbol": "soong_build_number", "value": "1234", "from": "PLACEHOLDER"},
+				{"symbol": "feature_flags", "value": "0x5", "uint64": true}
+			]`,
+			want: []Injection{
+				{Symbol: "soong_build_number", Value: "1234", From: "PLACEHOLDER"},
+				{Symbol: "feature_flags", Value: "0x5", Uint64: true},
+			},
+		},
+		{
+			name: "manifest",
+			in:   "# build stamps\nsoong_build_number=1234\n\nversion = a=b \n",
+			want: []Injection{
+				{Symbol: "soong_build_number", Value: "1234"},
+				{Symbol: "version", Value: " a=b "},
+			},
+		},
+		{
+			name:    "json missing symbol",
+			in:      `[{"value": "1234"}]`,
+			wantErr: "injection 0 is missing a symbol",
+		},
+		{
+			name:    "manifest missing value",
+			in:      "soong_build_number=1234\nversion\n",
+			wantErr: `line 2: expected symbol=value, got "version"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadInjections(strings.NewReader(tc.in))
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("want error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
+
+// testInjectFile returns a file with a 24 byte .data section at offset 4 containing three 8 byte
+// symbols a, b and c, and a 4 byte .rodata section at offset 28 containing the symbol d that
+// claims to be 8 bytes long.
+func testInjectFile() *File {
+	data := &Section{Name: ".data", Offset: 4, Size: 24}
+	rodata := &Section{Name: ".rodata", Offset: 28, Size: 4}
+	return &File{
+		r: strings.NewReader("----aaaaaaa\x00bbbbbbb\x00ccccccc\x00ddd\x00"),
+		Symbols: []*Symbol{
+			{Name: "a", Addr: 0, Size: 8, Section: data},
+			{Name: "b", Addr: 8, Size: 8, Section: data},
+			{Name: "c", Addr: 16, Size: 8, Section: data},
+			{Name: "d", Addr: 0, Size: 8, Section: rodata},
+		},
+		Sections: []*Section{data, rodata},
+	}
+}
+
+func TestInjectSymbols(t *testing.T) {
+	testCases := []struct {
+		name       string
+		injections []Injection
+		want       string
+		wantErr    string
+	}{
+		{
+			name: "multiple",
+			injections: []Injection{
+				{Symbol: "c", Value: "C"},
+				{Symbol: "a", Value: "AAAAAAA", From: "aaaaaaa"},
+				{Symbol: "b", Value: "0x4142434445464748", Uint64: true},
+			},
+			want: "----AAAAAAA\x00HGFEDCBAC\x00\x00\x00\x00\x00\x00\x00ddd\x00",
+		},
+		{
+			name:       "too long",
+			injections: []Injection{{Symbol: "a", Value: "AAAAAAAA"}},
+			wantErr:    `symbol "a": value length 8 overflows symbol size 8`,
+		},
+		{
+			name:       "past end of section",
+			injections: []Injection{{Symbol: "d", Value: "D"}},
+			wantErr:    `symbol "d": symbol "d" extends past the end of section ".rodata"`,
+		},
+		{
+			name:       "duplicate",
+			injections: []Injection{{Symbol: "a", Value: "A"}, {Symbol: "a", Value: "B"}},
+			wantErr:    `symbol "a" is injected more than once`,
+		},
+		{
+			name:       "from mismatch",
+			injections: []Injection{{Symbol: "a", Value: "A", From: "x"}},
+			wantErr:    `symbol "a": existing symbol contents "aaaaaaa\x00" did not match expected value "x\x00\x00\x00\x00\x00\x00\x00"`,
+		},
+		{
+			name:       "missing",
+			injections: []Injection{{Symbol: "e", Value: "E"}},
+			wantErr:    `symbol "e": symbol not found`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := InjectSymbols(testInjectFile(), out, tc.injections)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("want error %q, got %v", tc.wantErr, err)
+				}
+				if out.Len() != 0 {
+					t.Errorf("want no output after an error, got %q", out.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("want %q, got %q", tc.want, out.String())
+			}
+		})
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"sort"
+	"strconv"
 )
 
 var maxUint64 uint64 = math.MaxUint64
@@ -57,8 +59,19 @@ func InjectStringSymbol(file *File, w io.Writer, symbol, value, from string) err
 		return err
 	}
 
+	buf, err := stringSymbolContents(file, offset, size, value, from)
+	if err != nil {
+		return err
+	}
+
+	return copyAndInject(file.r, w, offset, buf)
+}
+
+// stringSymbolContents returns the contents of a symbol with the given offset and size after
+// injecting value into it, verifying the existing contents against from if it is not empty.
+func stringSymbolContents(file *File, offset, size uint64, value, from string) ([]byte, error) {
 	if uint64(len(value))+1 > size {
-		return fmt.Errorf("value length %d overflows symbol size %d", len(value), size)
+		return nil, fmt.Errorf("value length %d overflows symbol size %d", len(value), size)
 	}
 
 	if from != "" {
@@ -68,10 +81,10 @@ func InjectStringSymbol(file *File, w io.Writer, symbol, value, from string) err
 		copy(expected, from)
 		_, err := file.r.ReadAt(existing, int64(offset))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if bytes.Compare(existing, expected) != 0 {
-			return fmt.Errorf("existing symbol contents %q did not match expected value %q",
+			return nil, fmt.Errorf("existing symbol contents %q did not match expected value %q",
 				string(existing), string(expected))
 		}
 	}
@@ -79,7 +92,7 @@ func InjectStringSymbol(file *File, w io.Writer, symbol, value, from string) err
 	buf := make([]byte, size)
 	copy(buf, value)
 
-	return copyAndInject(file.r, w, offset, buf)
+	return buf, nil
 }
 
 func InjectUint64Symbol(file *File, w io.Writer, symbol string, value uint64) error {
@@ -88,27 +101,54 @@ func InjectUint64Symbol(file *File, w io.Writer, symbol string, value uint64) er
 		return err
 	}
 
+	buf, err := uint64SymbolContents(symbol, size, value)
+	if err != nil {
+		return err
+	}
+
+	return copyAndInject(file.r, w, offset, buf)
+}
+
+func uint64SymbolContents(symbol string, size uint64, value uint64) ([]byte, error) {
 	if size != 8 {
-		return fmt.Errorf("symbol %q is not a uint64, it is %d bytes long", symbol, size)
+		return nil, fmt.Errorf("symbol %q is not a uint64, it is %d bytes long", symbol, size)
 	}
 
 	buf := make([]byte, 8)
 	binary.LittleEndian.PutUint64(buf, value)
 
-	return copyAndInject(file.r, w, offset, buf)
+	return buf, nil
 }
 
 func copyAndInject(r io.ReaderAt, w io.Writer, offset uint64, buf []byte) (err error) {
-	// Copy the first bytes up to the symbol offset
-	_, err = io.Copy(w, io.NewSectionReader(r, 0, int64(offset)))
+	return copyAndInjectAll(r, w, []injectedValue{{offset, buf}})
+}
 
-	// Write the injected value in the output file
-	if err == nil {
-		_, err = w.Write(buf)
+// injectedValue is the contents of a symbol at an offset into the file.
+type injectedValue struct {
+	offset uint64
+	buf    []byte
+}
+
+// copyAndInjectAll copies r to w, replacing the bytes at each of values, which must be sorted by
+// offset and must not overlap.
+func copyAndInjectAll(r io.ReaderAt, w io.Writer, values []injectedValue) (err error) {
+	var pos int64
+	for _, value := range values {
+		// Copy the bytes up to the symbol offset
+		if err == nil {
+			_, err = io.Copy(w, io.NewSectionReader(r, pos, int64(value.offset)-pos))
+		}
+
+		// Write the injected value in the output file
+		if err == nil {
+			_, err = w.Write(value.buf)
+		}
+
+		pos = int64(value.offset) + int64(len(value.buf))
 	}
 
 	// Write the remainder of the file
-	pos := int64(offset) + int64(len(buf))
 	if err == nil {
 		_, err = io.Copy(w, io.NewSectionReader(r, pos, 1<<63-1-pos))
 	}
@@ -123,41 +163,53 @@ func copyAndInject(r io.ReaderAt, w io.Writer, offset uint64, buf []byte) (err e
 func findSymbol(file *File, symbolName string) (uint64, uint64, error) {
 	for i, symbol := range file.Symbols {
 		if symbol.Name == symbolName {
-			// Find the next symbol (n the same section with a higher address
-			var n int
-			for n = i; n < len(file.Symbols); n++ {
-				if file.Symbols[n].Section != symbol.Section {
-					n = len(file.Symbols)
-					break
-				}
-				if file.Symbols[n].Addr > symbol.Addr {
-					break
-				}
-			}
+			return symbolExtent(file, i)
+		}
+	}
 
-			size := symbol.Size
-			if size == 0 {
-				var end uint64
-				if n < len(file.Symbols) {
-					end = file.Symbols[n].Addr
-				} else {
-					end = symbol.Section.Size
-				}
+	return maxUint64, maxUint64, fmt.Errorf("symbol not found")
+}
 
-				if end <= symbol.Addr || end > symbol.Addr+4096 {
-					return maxUint64, maxUint64, fmt.Errorf("symbol end address does not seem valid, %x:%x", symbol.Addr, end)
-				}
+// symbolExtent returns the offset into the file and the size of the i-th symbol of the file.
+func symbolExtent(file *File, i int) (uint64, uint64, error) {
+	symbol := file.Symbols[i]
 
-				size = end - symbol.Addr
-			}
+	// Find the next symbol (n the same section with a higher address
+	var n int
+	for n = i; n < len(file.Symbols); n++ {
+		if file.Symbols[n].Section != symbol.Section {
+			n = len(file.Symbols)
+			break
+		}
+		if file.Symbols[n].Addr > symbol.Addr {
+			break
+		}
+	}
 
-			offset := symbol.Section.Offset + symbol.Addr
+	size := symbol.Size
+	if size == 0 {
+		var end uint64
+		if n < len(file.Symbols) {
+			end = file.Symbols[n].Addr
+		} else {
+			end = symbol.Section.Size
+		}
 
-			return uint64(offset), uint64(size), nil
+		if end <= symbol.Addr || end > symbol.Addr+4096 {
+			return maxUint64, maxUint64, fmt.Errorf("symbol end address does not seem valid, %x:%x", symbol.Addr, end)
 		}
+
+		size = end - symbol.Addr
 	}
 
-	return maxUint64, maxUint64, fmt.Errorf("symbol not found")
+	if symbol.Addr+size > symbol.Section.Size {
+		return maxUint64, maxUint64, fmt.Errorf("symbol %q extends past the end of section %q",
+			symbol.Name, symbol.Section.Name)
+	}
+
+	offset := symbol.Section.Offset + symbol.Addr
+
+	return uint64(offset), uint64(size), nil
 }
 
 type File struct {
@@ -180,6 +232,42 @@ type Section struct {
 	Size   uint64
 }
 
+// ListSymbols writes a line for each symbol in the file with its name, section, offset into the
+// file and size, sorted by name.  The size is "-" if it can't be determined.
+func ListSymbols(file *File, w io.Writer) error {
+	type listedSymbol struct {
+		symbol *Symbol
+		offset uint64
+		size   string
+	}
+
+	var symbols []listedSymbol
+	for i, symbol := range file.Symbols {
+		offset := symbol.Section.Offset + symbol.Addr
+		size := "-"
+		if _, n, err := symbolExtent(file, i); err == nil {
+			size = strconv.FormatUint(n, 10)
+		}
+		symbols = append(symbols, listedSymbol{symbol, offset, size})
+	}
+
+	sort.SliceStable(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.symbol.Name != b.symbol.Name {
+			return a.symbol.Name < b.symbol.Name
+		}
+		return a.offset < b.offset
+	})
+
+	for _, s := range symbols {
+		_, err := fmt.Fprintf(w, "%s %s 0x%x %s\n", s.symbol.Name, s.symbol.Section.Name, s.offset, s.size)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func DumpSymbols(r io.ReaderAt) error {
 	err := dumpElfSymbols(r)
 	if elfError, ok := err.(cantParseError); ok {
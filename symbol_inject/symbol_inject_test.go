@@ -17,6 +17,7 @@ package symbol_inject
 import (
 	"bytes"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -56,3 +57,35 @@ func TestCopyAndInject(t *testing.T) {
 		})
 	}
 }
+
+func TestListSymbols(t *testing.T) {
+	file, err := extractElfSymbols(elfSymbolTable2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	if err := ListSymbols(file, out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"_DYNAMIC .dynamic 0xe20 464",
+		"_GLOBAL_OFFSET_TABLE_ .got.plt 0x1000 32",
+		"_IO_stdin_used .rodata 0x5c0 4",
+		"__FRAME_END__ .eh_frame 0x6e4 4",
+		"__JCR_END__ .jcr 0xe18 8",
+		"__JCR_LIST__ .jcr 0xe18 8",
+		"__TMC_END__ .data 0x1130 -",
+		"__do_global_dtors_aux_fini_array_entry .fini_array 0xe10 8",
+		"__dso_handle .data 0x1028 264",
+		"__frame_dummy_init_array_entry .init_array 0xe08 8",
+		"completed.6963 .bss 0x1130 1",
+		"symbol1 .data 0x1030 128",
+		"symbol2 .data 0x10b0 128",
+		"",
+	}, "\n")
+	if out.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -231,3 +231,13 @@ func (w *compressedFileWriter) Close() error {
 	_, err := w.zipw.Write(buf)
 	return err
 }
+
+// SetComment sets the end-of-central-directory comment field.
+// It can only be called before Close.
+func (w *Writer) SetComment(comment string) error {
+	if len(comment) > uint16max {
+		return errors.New("zip: Writer.Comment too long")
+	}
+	w.comment = comment
+	return nil
+}
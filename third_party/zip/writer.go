@@ -22,6 +22,10 @@ type Writer struct {
 	last        *fileWriter
 	closed      bool
 	compressors map[uint16]Compressor
+
+	// BEGIN ANDROID CHANGE support zip file comments
+	comment string
+	// END ANDROID CHANGE
 }
 
 type header struct {
@@ -176,10 +180,15 @@ func (w *Writer) Close() error {
 	b.uint16(uint16(records)) // number of entries total
 	b.uint32(uint32(size))    // size of directory
 	b.uint32(uint32(offset))  // start of directory
-	// skipped size of comment (always zero)
+	// BEGIN ANDROID CHANGE support zip file comments
+	b.uint16(uint16(len(w.comment))) // size of comment
 	if _, err := w.cw.Write(buf[:]); err != nil {
 		return err
 	}
+	if _, err := io.WriteString(w.cw, w.comment); err != nil {
+		return err
+	}
+	// END ANDROID CHANGE
 
 	return w.cw.w.(*bufio.Writer).Flush()
 }
@@ -33,8 +33,20 @@ import (
 // under `$OUT_DIR/.module_paths`. This directory can also be dist'd.
 
 // NewSourceFinder returns a new Finder configured to search for source files.
+// If a finder daemon started by RunFinderDaemon is running, the Finder loads
+// its cache from the daemon instead of checking the whole source tree for
+// changes.
 // Callers of NewSourceFinder should call <f.Shutdown()> when done
 func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
+	return newSourceFinder(ctx, config, true)
+}
+
+// finderSocketPath returns the path of the socket that the finder daemon listens on.
+func finderSocketPath(config Config) string {
+	return filepath.Join(config.FileListDir(), "finder.sock")
+}
+
+func newSourceFinder(ctx Context, config Config, useDaemon bool) (f *finder.Finder) {
 	ctx.BeginTrace(metrics.RunSetupTool, "find modules")
 	defer ctx.EndTrace()
 
@@ -92,14 +104,47 @@ func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
 		IncludeSuffixes: []string{".bzl"},
 	}
 	dumpDir := config.FileListDir()
-	f, err = finder.New(cacheParams, filesystem, logger.New(ioutil.Discard),
-		filepath.Join(dumpDir, "files.db"))
+	dbPath := filepath.Join(dumpDir, "files.db")
+	if useDaemon {
+		f, err = finder.NewWithDaemon(cacheParams, filesystem, logger.New(ioutil.Discard),
+			dbPath, finderSocketPath(config))
+	} else {
+		f, err = finder.New(cacheParams, filesystem, logger.New(ioutil.Discard), dbPath)
+	}
 	if err != nil {
 		ctx.Fatalf("Could not create module-finder: %v", err)
 	}
 	return f
 }
 
+// RunFinderDaemon runs a finder daemon that watches the source tree for
+// changes, so that NewSourceFinder in other soong_ui processes doesn't have to
+// check the whole tree. It returns when ctx is cancelled.
+func RunFinderDaemon(ctx Context, config Config) {
+	f := newSourceFinder(ctx, config, false)
+	f.WaitForDbDump()
+
+	socketPath := finderSocketPath(config)
+	d, err := finder.NewDaemon(f, socketPath)
+	if err != nil {
+		ctx.Fatalf("Could not start finder daemon: %v", err)
+	}
+
+	closed := make(chan error)
+	go func() {
+		<-ctx.Done()
+		closed <- d.Close()
+	}()
+
+	ctx.Printf("Finder daemon listening on %s", socketPath)
+	if err := d.Serve(); err != nil {
+		ctx.Fatalf("Finder daemon failed: %v", err)
+	}
+	if err := <-closed; err != nil {
+		ctx.Fatalf("Could not stop finder daemon: %v", err)
+	}
+}
+
 // Finds the list of Bazel-related files (BUILD, WORKSPACE and Starlark) in the tree.
 func findBazelFiles(entries finder.DirEntries) (dirNames []string, fileNames []string) {
 	matches := []string{}
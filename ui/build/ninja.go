@@ -167,6 +167,10 @@ func runNinjaForBuild(ctx Context, config Config) {
 			"CCACHE_BASEDIR",
 			"CCACHE_CPP2",
 			"CCACHE_DIR",
+
+			// sbox action cache settings
+			"SBOX_CACHE_DIR",
+			"SBOX_CACHE_MAX_SIZE",
 		}, config.BuildBrokenNinjaUsesEnvVars()...)...)
 	}
 
@@ -19,6 +19,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"android/soong/ui/metrics"
 	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
@@ -312,8 +314,23 @@ func runSoong(ctx Context, config Config) {
 		target = config.MainNinjaFile()
 	}
 
+	// The time spent in bp2build is recorded separately in the metrics.  It is taken from the entry
+	// of the bp2build marker file in the .ninja_log of the bootstrap ninja, which only changes when
+	// bp2build runs.
+	ninjaLog := filepath.Join(config.SoongOutDir(), ".ninja_log")
+	bp2buildEntry := lastNinjaLogEntry(ninjaLog, config.Bp2BuildMarkerFile())
+	ninjaStart := time.Now()
+
 	ninja("bootstrap", ".bootstrap/build.ninja", target)
 
+	if integratedBp2Build {
+		entry := lastNinjaLogEntry(ninjaLog, config.Bp2BuildMarkerFile())
+		if start, end, ok := ninjaLogEntryTimes(entry); ok && entry != bp2buildEntry {
+			ctx.CompleteTrace(metrics.RunSoong, "bp2build",
+				uint64(ninjaStart.Add(start).UnixNano()), uint64(ninjaStart.Add(end).UnixNano()))
+		}
+	}
+
 	var soongBuildMetrics *soong_metrics_proto.SoongBuildMetrics
 	if shouldCollectBuildSoongMetrics(config) {
 		soongBuildMetrics := loadSoongBuildMetrics(ctx, config)
@@ -332,6 +349,40 @@ func runSoong(ctx Context, config Config) {
 	}
 }
 
+// lastNinjaLogEntry returns the last line of a .ninja_log file for an output, or an empty string if
+// there is none.  Ninja appends a line each time it runs the command of an output.
+func lastNinjaLogEntry(logFile, output string) string {
+	data, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		return ""
+	}
+	var entry string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) == 5 && fields[3] == output {
+			entry = line
+		}
+	}
+	return entry
+}
+
+// ninjaLogEntryTimes returns the times, relative to the start of ninja, at which the command of a
+// .ninja_log line started and finished.
+func ninjaLogEntryTimes(entry string) (start, end time.Duration, ok bool) {
+	fields := strings.Split(entry, "\t")
+	if len(fields) != 5 {
+		return 0, 0, false
+	}
+	startMs, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	endMs, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return time.Duration(startMs) * time.Millisecond, time.Duration(endMs) * time.Millisecond, true
+}
+
 func runMicrofactory(ctx Context, config Config, relExePath string, pkg string, mapping map[string]string) {
 	name := filepath.Base(relExePath)
 	ctx.BeginTrace(metrics.RunSoong, name)
@@ -378,5 +429,8 @@ func logSoongBuildMetrics(ctx Context, metrics *soong_metrics_proto.SoongBuildMe
 	ctx.Verbosef(" max heap size: %v MB", metrics.GetMaxHeapSize()/1e6)
 	ctx.Verbosef(" total allocation count: %v", metrics.GetTotalAllocCount())
 	ctx.Verbosef(" total allocation size: %v MB", metrics.GetTotalAllocSize()/1e6)
+	if cache := metrics.GetAnalysisCache(); cache != nil {
+		ctx.Verbosef(" analysis cache hits: %v misses: %v", cache.GetHits(), cache.GetMisses())
+	}
 
 }
@@ -0,0 +1,52 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBp2BuildNinjaLogEntry(t *testing.T) {
+	const marker = "out/soong/.bootstrap/bp2build_workspace_marker"
+	log := filepath.Join(t.TempDir(), ".ninja_log")
+
+	if entry := lastNinjaLogEntry(log, marker); entry != "" {
+		t.Errorf("want no entry for a missing log, got %q", entry)
+	}
+
+	data := "# ninja log v5\n" +
+		"10\t2000\t1\t" + marker + "\t1234abcd\n" +
+		"0\t500\t1\tout/soong/build.ninja\t5678abcd\n" +
+		"20\t3520\t2\t" + marker + "\t1234abcd\n"
+	if err := ioutil.WriteFile(log, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := lastNinjaLogEntry(log, marker)
+	if want := "20\t3520\t2\t" + marker + "\t1234abcd"; entry != want {
+		t.Errorf("want entry %q, got %q", want, entry)
+	}
+	start, end, ok := ninjaLogEntryTimes(entry)
+	if !ok || start != 20*time.Millisecond || end != 3520*time.Millisecond {
+		t.Errorf("want times 20ms 3.52s, got %v %v %v", start, end, ok)
+	}
+
+	if _, _, ok := ninjaLogEntryTimes(""); ok {
+		t.Errorf("want no times for an empty entry")
+	}
+}
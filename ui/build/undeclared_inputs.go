@@ -0,0 +1,87 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// When this environment variable is set Soong asks sbox to trace the files read by the sandboxed
+// actions, and sbox writes reports of the undeclared inputs to the directory it names.
+const inputTraceDirEnv = "SOONG_SBOX_INPUT_TRACE_DIR"
+
+func inputTraceDir(config Config) string {
+	return filepath.Join(config.SoongOutDir(), "sbox_input_traces")
+}
+
+// EnableInputTracing makes the sandboxed actions of the build report the files they read that
+// were not declared as inputs.  Changing it reruns Soong and all of the sandboxed actions.
+func EnableInputTracing(config Config) {
+	config.Environment().Set(inputTraceDirEnv, inputTraceDir(config))
+}
+
+// UndeclaredInputs returns the undeclared inputs read by the sandboxed actions of the builds that
+// enabled input tracing, by the module that created the action.  Actions created by singletons are
+// reported by the path to their sbox manifest.  Reports of actions that have not run since they
+// stopped reading undeclared inputs or since they were removed from the build may be stale.
+func UndeclaredInputs(config Config) (map[string][]string, error) {
+	dir := inputTraceDir(config)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	undeclared := make(map[string][]string)
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var report sbox_proto.InputTraceReport
+		if err := prototext.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+		module := report.GetModule()
+		if module == "" {
+			module = report.GetManifest()
+		}
+		undeclared[module] = append(undeclared[module], report.GetUndeclaredInputs()...)
+	}
+
+	for module, inputs := range undeclared {
+		undeclared[module] = uniqueSorted(inputs)
+	}
+	return undeclared, nil
+}
+
+func uniqueSorted(list []string) []string {
+	sort.Strings(list)
+	var ret []string
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
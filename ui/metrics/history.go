@@ -0,0 +1,286 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// This file contains the functionality to keep a local history of the metrics
+// of recent builds and to find the phases of a build that regressed compared to
+// that history. The history is a directory of MetricsBase protobuf files, one
+// per build, named so that sorting the names sorts the builds by time. It never
+// leaves the machine.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+const (
+	// The maximum number of builds kept in the history directory.
+	MaxHistory = 50
+
+	historySuffix = ".pb"
+)
+
+// The phases of a build that are compared by FindRegressions.
+const (
+	PhaseSoong    = "soong"
+	PhaseBp2Build = "bp2build"
+	PhaseKati     = "kati"
+	PhaseNinja    = "ninja"
+)
+
+// Phases is the list of phases compared by FindRegressions, in the order that
+// they run.
+var Phases = []string{PhaseBp2Build, PhaseSoong, PhaseKati, PhaseNinja}
+
+// AddToHistory copies the metrics file of a build into the history directory,
+// then removes the oldest builds from the history until at most max are left.
+func AddToHistory(historyDir, metricsFile string, max int) error {
+	data, err := ioutil.ReadFile(metricsFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(historyDir, 0777); err != nil {
+		return err
+	}
+
+	// The names are zero padded so that they sort in the order of the builds.
+	name := fmt.Sprintf("%020d%s", _now().UnixNano(), historySuffix)
+	if err := ioutil.WriteFile(filepath.Join(historyDir, name), data, 0666); err != nil {
+		return err
+	}
+
+	names, err := historyFiles(historyDir)
+	if err != nil {
+		return err
+	}
+	for len(names) > max {
+		if err := os.Remove(filepath.Join(historyDir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// ReadHistory returns the metrics of the builds in the history directory, oldest
+// first. Files that cannot be parsed are skipped.
+func ReadHistory(historyDir string) ([]*soong_metrics_proto.MetricsBase, error) {
+	names, err := historyFiles(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []*soong_metrics_proto.MetricsBase
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(historyDir, name))
+		if err != nil {
+			return nil, err
+		}
+		metrics := &soong_metrics_proto.MetricsBase{}
+		if err := proto.Unmarshal(data, metrics); err != nil {
+			continue
+		}
+		history = append(history, metrics)
+	}
+	return history, nil
+}
+
+// historyFiles returns the sorted names of the files in the history directory.
+func historyFiles(historyDir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), historySuffix) {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ConfigKey returns a string identifying the configuration of a build. Only
+// builds with the same key are compared with each other.
+func ConfigKey(metrics *soong_metrics_proto.MetricsBase) string {
+	// The first word of the build command is the path to soong_ui, the rest
+	// are the targets and the flags.
+	command := strings.Fields(metrics.GetBuildCommand())
+	if len(command) > 0 {
+		command = command[1:]
+	}
+
+	buildConfig := metrics.GetBuildConfig()
+	return strings.Join([]string{
+		metrics.GetTargetProduct(),
+		metrics.GetTargetBuildVariant().String(),
+		metrics.GetTargetArch().String(),
+		metrics.GetTargetArchVariant(),
+		fmt.Sprintf("goma=%t", buildConfig.GetUseGoma()),
+		fmt.Sprintf("rbe=%t", buildConfig.GetUseRbe()),
+		fmt.Sprintf("bazel_as_ninja=%t", buildConfig.GetBazelAsNinja()),
+		fmt.Sprintf("bazel_mixed_build=%t", buildConfig.GetBazelMixedBuild()),
+		strings.Join(command, " "),
+	}, "\n")
+}
+
+// PhaseDurations returns the time spent in each phase of a build. Phases that
+// did not run are not in the map.
+func PhaseDurations(metrics *soong_metrics_proto.MetricsBase) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	add := func(phase string, perf *soong_metrics_proto.PerfInfo) {
+		durations[phase] += time.Duration(perf.GetRealTime())
+	}
+
+	// The soong event covers everything run by soong_ui to produce the Soong
+	// ninja file, including bp2build, and the other Soong events are nested
+	// inside it.
+	for _, perf := range metrics.GetSoongRuns() {
+		switch perf.GetDesc() {
+		case RunSoong:
+			add(PhaseSoong, perf)
+		case PhaseBp2Build:
+			add(PhaseBp2Build, perf)
+		}
+	}
+	if bp2build, ok := durations[PhaseBp2Build]; ok {
+		durations[PhaseSoong] -= bp2build
+	}
+
+	for _, perf := range metrics.GetKatiRuns() {
+		add(PhaseKati, perf)
+	}
+	for _, perf := range metrics.GetNinjaRuns() {
+		add(PhaseNinja, perf)
+	}
+	return durations
+}
+
+// A Regression is a phase of a build that took longer than the median of the
+// same phase in previous builds with the same configuration.
+type Regression struct {
+	Phase string
+
+	// The time spent in the phase by the build.
+	Duration time.Duration
+
+	// The median time spent in the phase by the previous builds.
+	Median time.Duration
+
+	// The number of previous builds the median was computed from.
+	Builds int
+}
+
+// Ratio returns how much longer the phase took than the median, e.g. 0.5 for
+// a phase that took 50% longer.
+func (r Regression) Ratio() float64 {
+	return float64(r.Duration-r.Median) / float64(r.Median)
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %s, median of %d previous builds %s (+%.0f%%)",
+		r.Phase, r.Duration.Round(time.Millisecond), r.Builds, r.Median.Round(time.Millisecond),
+		r.Ratio()*100)
+}
+
+// RegressionOptions controls which phases are reported by FindRegressions.
+type RegressionOptions struct {
+	// The minimum ratio between the increase of a phase and its median for it
+	// to be reported, e.g. 0.1 for 10%.
+	Threshold float64
+
+	// The minimum increase of a phase for it to be reported, to ignore noise
+	// in phases that take very little time.
+	MinIncrease time.Duration
+
+	// The maximum number of previous builds to compute the median from, the
+	// most recent builds are used.
+	MaxBuilds int
+
+	// The minimum number of previous builds required for a phase to be
+	// compared.
+	MinBuilds int
+}
+
+// FindRegressions compares the phases of the current build against the median
+// of the most recent previous builds with the same configuration, and returns
+// the phases that regressed beyond the thresholds in opts. The previous builds
+// must be sorted from oldest to newest.
+func FindRegressions(current *soong_metrics_proto.MetricsBase, previous []*soong_metrics_proto.MetricsBase,
+	opts RegressionOptions) []Regression {
+
+	key := ConfigKey(current)
+	var matching []*soong_metrics_proto.MetricsBase
+	for i := len(previous) - 1; i >= 0 && len(matching) < opts.MaxBuilds; i-- {
+		if ConfigKey(previous[i]) == key {
+			matching = append(matching, previous[i])
+		}
+	}
+
+	currentDurations := PhaseDurations(current)
+	var regressions []Regression
+	for _, phase := range Phases {
+		duration, ok := currentDurations[phase]
+		if !ok {
+			continue
+		}
+
+		var durations []time.Duration
+		for _, m := range matching {
+			if d, ok := PhaseDurations(m)[phase]; ok {
+				durations = append(durations, d)
+			}
+		}
+		if len(durations) == 0 || len(durations) < opts.MinBuilds {
+			continue
+		}
+
+		median := medianDuration(durations)
+		increase := duration - median
+		if median <= 0 || increase < opts.MinIncrease || float64(increase) <= float64(median)*opts.Threshold {
+			continue
+		}
+		regressions = append(regressions, Regression{
+			Phase:    phase,
+			Duration: duration,
+			Median:   median,
+			Builds:   len(durations),
+		})
+	}
+	return regressions
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
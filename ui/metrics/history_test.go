@@ -0,0 +1,159 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+)
+
+func perfInfo(name, desc string, d time.Duration) *soong_metrics_proto.PerfInfo {
+	return &soong_metrics_proto.PerfInfo{
+		Name:     proto.String(name),
+		Desc:     proto.String(desc),
+		RealTime: proto.Uint64(uint64(d)),
+	}
+}
+
+func testBuild(product string, soong, bp2build, ninja time.Duration) *soong_metrics_proto.MetricsBase {
+	m := &soong_metrics_proto.MetricsBase{
+		TargetProduct: proto.String(product),
+		BuildCommand:  proto.String("out/soong_ui --make-mode droid"),
+		SoongRuns: []*soong_metrics_proto.PerfInfo{
+			perfInfo(RunSoong, "blueprint bootstrap", time.Second),
+			perfInfo(RunSoong, RunSoong, soong),
+		},
+		NinjaRuns: []*soong_metrics_proto.PerfInfo{perfInfo(PrimaryNinja, "ninja", ninja)},
+	}
+	if bp2build > 0 {
+		m.SoongRuns = append(m.SoongRuns, perfInfo(RunSoong, PhaseBp2Build, bp2build))
+	}
+	return m
+}
+
+func TestPhaseDurations(t *testing.T) {
+	got := PhaseDurations(testBuild("aosp_arm", 30*time.Second, 10*time.Second, 5*time.Second))
+	want := map[string]time.Duration{
+		PhaseSoong:    20 * time.Second,
+		PhaseBp2Build: 10 * time.Second,
+		PhaseNinja:    5 * time.Second,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindRegressions(t *testing.T) {
+	opts := RegressionOptions{
+		Threshold:   0.1,
+		MinIncrease: time.Second,
+		MaxBuilds:   3,
+		MinBuilds:   2,
+	}
+
+	previous := []*soong_metrics_proto.MetricsBase{
+		// Too old to be included in the median.
+		testBuild("aosp_arm", 100*time.Second, 0, 100*time.Second),
+		testBuild("aosp_arm", 20*time.Second, 0, 10*time.Second),
+		testBuild("aosp_arm", 22*time.Second, 0, 11*time.Second),
+		// A different configuration is never compared.
+		testBuild("aosp_x86", 100*time.Second, 0, 100*time.Second),
+		testBuild("aosp_arm", 21*time.Second, 0, 12*time.Second),
+	}
+
+	testCases := []struct {
+		name    string
+		current *soong_metrics_proto.MetricsBase
+		want    []Regression
+	}{
+		{
+			name:    "no regression",
+			current: testBuild("aosp_arm", 22*time.Second, 0, 12*time.Second),
+		},
+		{
+			name:    "soong regression",
+			current: testBuild("aosp_arm", 30*time.Second, 0, 12*time.Second),
+			want: []Regression{
+				{Phase: PhaseSoong, Duration: 30 * time.Second, Median: 21 * time.Second, Builds: 3},
+			},
+		},
+		{
+			name:    "below minimum increase",
+			current: testBuild("aosp_arm", 21*time.Second+500*time.Millisecond, 0, 12*time.Second),
+		},
+		{
+			name:    "not enough builds",
+			current: testBuild("aosp_x86", 200*time.Second, 0, 200*time.Second),
+		},
+		{
+			// bp2build did not run in the previous builds.
+			name:    "new phase",
+			current: testBuild("aosp_arm", 31*time.Second, 10*time.Second, 11*time.Second),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FindRegressions(tc.current, previous, opts)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	historyDir := filepath.Join(dir, "history")
+	metricsFile := filepath.Join(dir, "soong_metrics")
+
+	initialNow := _now
+	defer func() { _now = initialNow }()
+
+	start := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		_now = func() time.Time { return start.Add(time.Duration(i) * time.Minute) }
+		build := testBuild("aosp_arm", time.Duration(i)*time.Second, 0, time.Second)
+		if err := save(build, metricsFile); err != nil {
+			t.Fatal(err)
+		}
+		if err := AddToHistory(historyDir, metricsFile, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := ReadHistory(historyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []time.Duration
+	for _, m := range history {
+		got = append(got, PhaseDurations(m)[PhaseSoong])
+	}
+	want := []time.Duration{2 * time.Second, 3 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if history, err := ReadHistory(filepath.Join(dir, "missing")); err != nil || history != nil {
+		t.Errorf("expected no history for a missing directory, got %v, %v", history, err)
+	}
+}
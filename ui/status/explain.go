@@ -0,0 +1,492 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+// The reasons that an action was rebuilt, in the order that they are checked.
+type rebuildReason int
+
+const (
+	rebuildReasonUnknown rebuildReason = iota
+	rebuildReasonNotInLog
+	rebuildReasonOutputMissing
+	rebuildReasonInputChanged
+	rebuildReasonInputRebuilt
+	rebuildReasonDepChanged
+	rebuildReasonCommandChanged
+)
+
+func (r rebuildReason) String() string {
+	switch r {
+	case rebuildReasonNotInLog:
+		return "the output was not in .ninja_log"
+	case rebuildReasonOutputMissing:
+		return "the output was missing"
+	case rebuildReasonInputChanged:
+		return "an input changed"
+	case rebuildReasonInputRebuilt:
+		return "an input was rebuilt"
+	case rebuildReasonDepChanged:
+		return "a depfile dependency changed"
+	case rebuildReasonCommandChanged:
+		return "the command line changed"
+	default:
+		return "unknown"
+	}
+}
+
+// The number of files listed in the summary of the files that caused the most rebuilds.
+const explainSummaryFiles = 10
+
+// NewRebuildExplainer returns a StatusOutput that explains why each action run by ninja was
+// rebuilt, by comparing the action against the state of the .ninja_log and .ninja_deps files from
+// before the build.  The build runs more than one ninja, e.g. the Soong bootstrap ninja and the main
+// ninja, and each keeps its logs in its own build directory, so the logs of all of ninjaDirs are
+// read.  A summary is logged at the end of the build and the explanation for each action is written
+// to reportFile.
+//
+// It must be created before ninja runs.
+func NewRebuildExplainer(log logger.Logger, ninjaDirs []string, reportFile string) StatusOutput {
+	e := &rebuildExplainer{
+		log:        log,
+		reportFile: reportFile,
+		stat:       os.Stat,
+		rebuilt:    make(map[string]bool),
+	}
+
+	for _, dir := range ninjaDirs {
+		ninjaLog, err := readNinjaLogFile(filepath.Join(dir, ".ninja_log"))
+		if err != nil {
+			log.Verbosef("Failed to read %s/.ninja_log, its rebuilds will not be explained: %s", dir, err)
+		}
+		for output, entry := range ninjaLog {
+			if e.ninjaLog == nil {
+				e.ninjaLog = make(map[string]ninjaLogEntry)
+			}
+			e.ninjaLog[output] = entry
+		}
+
+		ninjaDeps, err := readNinjaDepsFile(filepath.Join(dir, ".ninja_deps"))
+		if err != nil {
+			log.Verbosef("Failed to read %s/.ninja_deps, its depfile dependencies will not be checked: %s", dir, err)
+		}
+		for output, entry := range ninjaDeps {
+			if e.ninjaDeps == nil {
+				e.ninjaDeps = make(map[string]ninjaDepsEntry)
+			}
+			e.ninjaDeps[output] = entry
+		}
+	}
+	return e
+}
+
+type rebuildExplainer struct {
+	log        logger.Logger
+	reportFile string
+	stat       func(string) (os.FileInfo, error)
+
+	ninjaLog  map[string]ninjaLogEntry
+	ninjaDeps map[string]ninjaDepsEntry
+
+	actions []*explainedAction
+	// The outputs of all of the actions that were run by this build.
+	rebuilt map[string]bool
+}
+
+type explainedAction struct {
+	action *Action
+
+	// The outputs that did not exist when the action started.
+	missingOutputs []string
+
+	reason rebuildReason
+	output string
+	// The changed inputs or depfile dependencies that caused the rebuild.
+	causes []string
+}
+
+func (e *rebuildExplainer) StartAction(action *Action, counts Counts) {
+	// Whether the outputs exist must be checked now, the rest is checked at the end of the build.
+	explained := &explainedAction{action: action}
+	for _, output := range action.Outputs {
+		if _, err := e.stat(output); err != nil {
+			explained.missingOutputs = append(explained.missingOutputs, output)
+		}
+		e.rebuilt[output] = true
+	}
+	e.actions = append(e.actions, explained)
+}
+
+func (e *rebuildExplainer) FinishAction(result ActionResult, counts Counts) {}
+
+func (e *rebuildExplainer) Message(level MsgLevel, msg string) {}
+
+func (e *rebuildExplainer) Write(p []byte) (n int, err error) { return len(p), nil }
+
+func (e *rebuildExplainer) Flush() {
+	if e.ninjaLog == nil || len(e.actions) == 0 {
+		return
+	}
+
+	counts := make(map[rebuildReason]int)
+	// The number of actions that were rebuilt because of each changed file, only counting files
+	// that were not themselves rebuilt.
+	causes := make(map[string]int)
+
+	var report bytes.Buffer
+	for _, action := range e.actions {
+		e.explain(action)
+		counts[action.reason]++
+		if action.reason == rebuildReasonInputChanged || action.reason == rebuildReasonDepChanged {
+			for _, cause := range action.causes {
+				causes[cause]++
+			}
+		}
+
+		fmt.Fprintf(&report, "%s: %s", action.output, action.reason)
+		if len(action.causes) > 0 {
+			fmt.Fprintf(&report, ": %s", strings.Join(action.causes, " "))
+		}
+		fmt.Fprintln(&report)
+	}
+
+	if err := ioutil.WriteFile(e.reportFile, report.Bytes(), 0666); err != nil {
+		e.log.Printf("Failed to write %s: %s", e.reportFile, err)
+	}
+
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Rebuilt %d actions because:\n", len(e.actions))
+	for reason := rebuildReasonNotInLog; reason <= rebuildReasonCommandChanged; reason++ {
+		if counts[reason] > 0 {
+			fmt.Fprintf(&summary, "  %7d %s\n", counts[reason], reason)
+		}
+	}
+	if counts[rebuildReasonUnknown] > 0 {
+		fmt.Fprintf(&summary, "  %7d no reason was found\n", counts[rebuildReasonUnknown])
+	}
+
+	if len(causes) > 0 {
+		files := make([]string, 0, len(causes))
+		for file := range causes {
+			files = append(files, file)
+		}
+		sort.Slice(files, func(i, j int) bool {
+			if causes[files[i]] != causes[files[j]] {
+				return causes[files[i]] > causes[files[j]]
+			}
+			return files[i] < files[j]
+		})
+		if len(files) > explainSummaryFiles {
+			files = files[:explainSummaryFiles]
+		}
+		fmt.Fprintln(&summary, "Changed files that caused the most rebuilds:")
+		for _, file := range files {
+			fmt.Fprintf(&summary, "  %7d %s\n", causes[file], file)
+		}
+	}
+	fmt.Fprintf(&summary, "See %s for the reason each action was rebuilt.", e.reportFile)
+	e.log.Print(summary.String())
+}
+
+// explain finds the reason that an action was rebuilt.  Ninja rebuilds an action if any of its
+// outputs is dirty, so the reason for the first output that has one is used.
+func (e *rebuildExplainer) explain(action *explainedAction) {
+	if len(action.action.Outputs) == 0 {
+		return
+	}
+	action.output = action.action.Outputs[0]
+
+	for _, output := range action.action.Outputs {
+		if _, ok := e.ninjaLog[output]; !ok {
+			action.output, action.reason = output, rebuildReasonNotInLog
+			return
+		}
+	}
+
+	if len(action.missingOutputs) > 0 {
+		action.output, action.reason = action.missingOutputs[0], rebuildReasonOutputMissing
+		return
+	}
+
+	for _, output := range action.action.Outputs {
+		entry := e.ninjaLog[output]
+
+		// Files that changed outside of the build are reported in preference to inputs that were
+		// rebuilt, as they are the root cause.
+		var changed, rebuilt []string
+		for _, input := range action.action.Inputs {
+			if info, err := e.stat(input); err == nil && newerThan(info.ModTime(), entry.mtime) {
+				if e.rebuilt[input] {
+					rebuilt = append(rebuilt, input)
+				} else {
+					changed = append(changed, input)
+				}
+			}
+		}
+		if len(changed) > 0 {
+			action.output, action.reason, action.causes = output, rebuildReasonInputChanged, changed
+			return
+		}
+		if len(rebuilt) > 0 {
+			action.output, action.reason, action.causes = output, rebuildReasonInputRebuilt, rebuilt
+			return
+		}
+
+		if deps, ok := e.ninjaDeps[output]; ok {
+			var changed, rebuilt []string
+			for _, dep := range deps.deps {
+				if info, err := e.stat(dep); err != nil || newerThan(info.ModTime(), deps.mtime) {
+					if e.rebuilt[dep] {
+						rebuilt = append(rebuilt, dep)
+					} else {
+						changed = append(changed, dep)
+					}
+				}
+			}
+			if len(changed) > 0 {
+				action.output, action.reason, action.causes = output, rebuildReasonDepChanged, changed
+				return
+			}
+			if len(rebuilt) > 0 {
+				action.output, action.reason, action.causes = output, rebuildReasonInputRebuilt, rebuilt
+				return
+			}
+		}
+	}
+
+	// Ninja hashes the command line including the contents of the rspfile, which is not available
+	// here, so only check the command line once nothing else explains the rebuild.
+	for _, output := range action.action.Outputs {
+		if e.ninjaLog[output].commandHash != hashCommand(action.action.Command) {
+			action.output, action.reason = output, rebuildReasonCommandChanged
+			return
+		}
+	}
+}
+
+// newerThan returns true if t is newer than a timestamp from the ninja logs.  Older versions of
+// ninja stored timestamps in seconds, newer versions store them in nanoseconds.
+func newerThan(t time.Time, timestamp int64) bool {
+	// A timestamp in nanoseconds that is less than this is in the first 20 minutes of 1970.
+	if timestamp < 1<<40 {
+		return t.Unix() > timestamp
+	}
+	return t.UnixNano() > timestamp
+}
+
+type ninjaLogEntry struct {
+	mtime       int64
+	commandHash uint64
+}
+
+func readNinjaLogFile(path string) (map[string]ninjaLogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readNinjaLog(f)
+}
+
+// readNinjaLog parses a .ninja_log file.  Each line after the header contains the start time, end
+// time, mtime, path and command hash of an output, separated by tabs.  Later lines replace earlier
+// lines for the same output.
+func readNinjaLog(r io.Reader) (map[string]ninjaLogEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty log")
+	}
+	var version int
+	if _, err := fmt.Sscanf(scanner.Text(), "# ninja log v%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid header %q", scanner.Text())
+	}
+	if version < 5 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	entries := make(map[string]ninjaLogEntry)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		mtime, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		hash, err := strconv.ParseUint(fields[4], 16, 64)
+		if err != nil {
+			continue
+		}
+		entries[fields[3]] = ninjaLogEntry{mtime: mtime, commandHash: hash}
+	}
+	return entries, scanner.Err()
+}
+
+// hashCommand returns the hash of a command line that ninja stores in .ninja_log, which is
+// MurmurHash64A with a fixed seed.
+func hashCommand(command string) uint64 {
+	const seed = 0xDECAFBADDECAFBAD
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	data := []byte(command)
+	h := uint64(seed) ^ (uint64(len(data)) * m)
+	for ; len(data) >= 8; data = data[8:] {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+type ninjaDepsEntry struct {
+	mtime int64
+	deps  []string
+}
+
+func readNinjaDepsFile(path string) (map[string]ninjaDepsEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readNinjaDeps(bufio.NewReader(f))
+}
+
+const ninjaDepsHeader = "# ninjadeps\n"
+
+// readNinjaDeps parses a .ninja_deps file.  After the header and version the file contains a list
+// of records, each starting with its size.  If the high bit of the size is set the record contains
+// the id of an output, its mtime when the dependencies were recorded, and the ids of its
+// dependencies.  Otherwise it contains a path, padded to a multiple of 4 bytes, followed by the
+// one's complement of the id that the path is assigned.  Later records replace earlier records for
+// the same output.
+func readNinjaDeps(r io.Reader) (map[string]ninjaDepsEntry, error) {
+	header := make([]byte, len(ninjaDepsHeader))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if string(header) != ninjaDepsHeader {
+		return nil, fmt.Errorf("invalid header %q", header)
+	}
+	var version int32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var paths []string
+	deps := make(map[int32][]int32)
+	mtimes := make(map[int32]int64)
+
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		isDeps := size&0x80000000 != 0
+		size &= 0x7fffffff
+		if size%4 != 0 || size > 1<<20 {
+			return nil, fmt.Errorf("invalid record size %d", size)
+		}
+
+		record := make([]byte, size)
+		if _, err := io.ReadFull(r, record); err == io.ErrUnexpectedEOF || err == io.EOF {
+			// Ninja may have been interrupted while writing the last record.
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if isDeps {
+			ints := make([]int32, size/4)
+			binary.Read(bytes.NewReader(record), binary.LittleEndian, ints)
+			if len(ints) < 2 || (version == 4 && len(ints) < 3) {
+				return nil, fmt.Errorf("invalid deps record")
+			}
+			out := ints[0]
+			if version == 4 {
+				mtimes[out] = int64(uint32(ints[1])) | int64(uint32(ints[2]))<<32
+				deps[out] = ints[3:]
+			} else {
+				mtimes[out] = int64(uint32(ints[1]))
+				deps[out] = ints[2:]
+			}
+		} else {
+			if size < 4 {
+				return nil, fmt.Errorf("invalid path record")
+			}
+			path := strings.TrimRight(string(record[:size-4]), "\x00")
+			id := ^int32(binary.LittleEndian.Uint32(record[size-4:]))
+			if int(id) != len(paths) {
+				return nil, fmt.Errorf("invalid path record for %q", path)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	entries := make(map[string]ninjaDepsEntry, len(deps))
+	for out, ids := range deps {
+		if int(out) >= len(paths) {
+			continue
+		}
+		entry := ninjaDepsEntry{mtime: mtimes[out]}
+		for _, id := range ids {
+			if int(id) < len(paths) {
+				entry.deps = append(entry.deps, paths[id])
+			}
+		}
+		entries[paths[out]] = entry
+	}
+	return entries, nil
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+// writeNinjaDeps writes a version 4 .ninja_deps file.
+func writeNinjaDeps(t *testing.T, path string, mtime int64, deps map[string][]string) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(ninjaDepsHeader)
+	binary.Write(buf, binary.LittleEndian, int32(4))
+
+	ids := make(map[string]int32)
+	id := func(path string) int32 {
+		if id, ok := ids[path]; ok {
+			return id
+		}
+		id := int32(len(ids))
+		ids[path] = id
+		padded := []byte(path)
+		for len(padded)%4 != 0 {
+			padded = append(padded, 0)
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(padded)+4))
+		buf.Write(padded)
+		binary.Write(buf, binary.LittleEndian, ^uint32(id))
+		return id
+	}
+
+	for out, outDeps := range deps {
+		record := []int32{id(out), int32(uint32(mtime)), int32(uint32(mtime >> 32))}
+		for _, dep := range outDeps {
+			record = append(record, id(dep))
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(record)*4)|0x80000000)
+		binary.Write(buf, binary.LittleEndian, record)
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebuildExplainer(t *testing.T) {
+	dir := t.TempDir()
+	p := func(name string) string { return filepath.Join(dir, name) }
+
+	oldTime := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	newTime := oldTime.Add(time.Hour)
+
+	files := map[string]time.Time{
+		"a.c":     newTime,
+		"dep.c":   oldTime,
+		"b.h":     newTime,
+		"other.c": oldTime,
+		"a.o":     oldTime,
+		"lib.a":   oldTime,
+		"dep.o":   oldTime,
+		"cmd.o":   oldTime,
+		"same.o":  oldTime,
+
+		"soong/build.ninja": oldTime,
+	}
+	if err := os.Mkdir(p("soong"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, mtime := range files {
+		if err := ioutil.WriteFile(p(name), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p(name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ninjaLog := &strings.Builder{}
+	fmt.Fprintln(ninjaLog, "# ninja log v5")
+	for _, out := range []string{"missing.o", "a.o", "lib.a", "dep.o", "cmd.o", "same.o"} {
+		fmt.Fprintf(ninjaLog, "0\t1\t%d\t%s\t%x\n", oldTime.UnixNano(), p(out), hashCommand("cc "+out))
+	}
+	if err := ioutil.WriteFile(p(".ninja_log"), []byte(ninjaLog.String()), 0666); err != nil {
+		t.Fatal(err)
+	}
+	writeNinjaDeps(t, p(".ninja_deps"), oldTime.UnixNano(), map[string][]string{
+		p("dep.o"):  {p("dep.c"), p("b.h")},
+		p("same.o"): {p("other.c")},
+	})
+
+	// The Soong bootstrap ninja keeps its own .ninja_log in its build directory.
+	soongLog := fmt.Sprintf("# ninja log v5\n0\t1\t%d\t%s\t%x\n", oldTime.UnixNano(),
+		p("soong/build.ninja"), hashCommand("cc soong/build.ninja"))
+	if err := ioutil.WriteFile(p("soong/.ninja_log"), []byte(soongLog), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &bytes.Buffer{}
+	explainer := NewRebuildExplainer(logger.New(log), []string{dir, p("soong")}, p("explain.txt"))
+
+	actions := []struct {
+		output, input, command string
+	}{
+		{"new.o", "other.c", "cc new.o"},
+		{"missing.o", "other.c", "cc missing.o"},
+		{"a.o", "a.c", "cc a.o"},
+		{"lib.a", "a.o", "cc lib.a"},
+		{"dep.o", "dep.c", "cc dep.o"},
+		{"cmd.o", "other.c", "cc -O2 cmd.o"},
+		{"same.o", "other.c", "cc same.o"},
+		{"soong/build.ninja", "other.c", "cc soong/build.ninja"},
+	}
+	for _, a := range actions {
+		action := &Action{
+			Outputs: []string{p(a.output)},
+			Inputs:  []string{p(a.input)},
+			Command: a.command,
+		}
+		explainer.StartAction(action, Counts{})
+		// Rebuilding the output updates its mtime.
+		os.Chtimes(p(a.output), newTime, newTime)
+	}
+	explainer.Flush()
+
+	report, err := ioutil.ReadFile(p("explain.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		p("new.o") + ": the output was not in .ninja_log",
+		p("missing.o") + ": the output was missing",
+		p("a.o") + ": an input changed: " + p("a.c"),
+		p("lib.a") + ": an input was rebuilt: " + p("a.o"),
+		p("dep.o") + ": a depfile dependency changed: " + p("b.h"),
+		p("cmd.o") + ": the command line changed",
+		p("same.o") + ": unknown",
+		p("soong/build.ninja") + ": unknown",
+	}
+	got := strings.Split(strings.TrimSpace(string(report)), "\n")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong report\nwant: %q\n got: %q", want, got)
+	}
+
+	for _, s := range []string{
+		"Rebuilt 8 actions because:",
+		"      1 the command line changed",
+		"      2 no reason was found",
+		"Changed files that caused the most rebuilds:",
+		"      1 " + p("a.c"),
+		"      1 " + p("b.h"),
+	} {
+		if !strings.Contains(log.String(), s) {
+			t.Errorf("expected summary to contain %q, got:\n%s", s, log.String())
+		}
+	}
+}
+
+func TestHashCommand(t *testing.T) {
+	// Hashes computed by the reference implementation of MurmurHash64A that ninja's
+	// BuildLog::LogEntry::HashCommand uses, with ninja's seed 0xDECAFBADDECAFBAD.
+	for _, test := range []struct {
+		command string
+		hash    uint64
+	}{
+		{"", 0x87c2bc0beaf1d91d},
+		{"cc", 0xac157b38cd2e901b},
+		{"cp a bc", 0x888c69afa190d9f3},
+		{"echo abc", 0xb718193f4abdfb1c},
+		{"touch out", 0x8ea3cc54bdccad2c},
+		{"gcc -c a.c -o a.o", 0xcfc141ee3d0f1211},
+		{"echo hello world > out.txt", 0xc4f88aa577bf9cb1},
+		{`/bin/bash -c "rm -f out && touch out"`, 0xcf70527fee989a86},
+	} {
+		if g := hashCommand(test.command); g != test.hash {
+			t.Errorf("hash of %q: want %x, got %x", test.command, test.hash, g)
+		}
+	}
+
+	// Commands of each length modulo 8 exercise every branch of the hash.
+	seen := make(map[uint64]string)
+	for i := 0; i < 16; i++ {
+		command := strings.Repeat("x", i)
+		h := hashCommand(command)
+		if other, ok := seen[h]; ok {
+			t.Errorf("%q and %q have the same hash %x", command, other, h)
+		}
+		seen[h] = command
+		if h != hashCommand(command) {
+			t.Errorf("hash of %q is not stable", command)
+		}
+	}
+}
+
+func TestNewerThan(t *testing.T) {
+	mtime := time.Unix(1600000000, 500)
+	if !newerThan(mtime, 1600000000*1e9) {
+		t.Errorf("expected %v to be newer than a timestamp in nanoseconds", mtime)
+	}
+	if newerThan(mtime, 1600000000) {
+		t.Errorf("expected %v to not be newer than a timestamp in seconds", mtime)
+	}
+}
@@ -15,8 +15,25 @@
 package tracer
 
 import (
-	"android/soong/ui/status"
+	"sort"
+	"strings"
 	"time"
+
+	"android/soong/ui/status"
+)
+
+const (
+	// actionsPid is the trace process that holds one lane per concurrently
+	// running ninja action.
+	actionsPid = 1
+
+	// modulesPid is the trace process that holds the per-module aggregate
+	// track, written when the build finishes.
+	modulesPid = 2
+
+	// criticalPathFlowId is the id shared by all of the flow events that
+	// connect the actions on the critical path.
+	criticalPathFlowId = 1
 )
 
 func (t *tracerImpl) StatusTracer() status.StatusOutput {
@@ -24,6 +41,8 @@ func (t *tracerImpl) StatusTracer() status.StatusOutput {
 		tracer: t,
 
 		running: map[*status.Action]actionStatus{},
+		nodes:   map[string]*actionNode{},
+		modules: map[string]*moduleStats{},
 	}
 }
 
@@ -32,11 +51,36 @@ type actionStatus struct {
 	start time.Time
 }
 
+// An actionNode records where a finished action was placed in the trace,
+// along with the longest chain of actions (the critical path) that led to it.
+type actionNode struct {
+	tid        uint64
+	start, end time.Time
+
+	cumulativeDuration time.Duration
+	input              *actionNode
+}
+
+// moduleStats accumulates all of the actions that were attributed to a single
+// Soong module.
+type moduleStats struct {
+	name       string
+	start, end time.Time
+	actions    int
+	duration   time.Duration
+	variants   map[string]bool
+}
+
 type statusOutput struct {
 	tracer *tracerImpl
 
 	cpus    []bool
 	running map[*status.Action]actionStatus
+
+	nodes   map[string]*actionNode
+	modules map[string]*moduleStats
+
+	flushed bool
 }
 
 func (s *statusOutput) StartAction(action *status.Action, counts status.Counts) {
@@ -68,19 +112,31 @@ func (s *statusOutput) FinishAction(result status.ActionResult, counts status.Co
 	delete(s.running, result.Action)
 	s.cpus[start.cpu] = false
 
+	end := time.Now()
+
 	str := result.Action.Description
 	if len(result.Action.Outputs) > 0 {
 		str = result.Action.Outputs[0]
 	}
 
+	module, variant, rule := parseSoongDescription(result.Action.Description)
+
+	s.recordNode(result.Action, uint64(start.cpu), start.start, end)
+	if module != "" {
+		s.recordModule(module, variant, start.start, end)
+	}
+
 	s.tracer.writeEvent(&viewerEvent{
 		Name:  str,
 		Phase: "X",
 		Time:  uint64(start.start.UnixNano()) / 1000,
-		Dur:   uint64(time.Since(start.start).Nanoseconds()) / 1000,
-		Pid:   1,
+		Dur:   uint64(end.Sub(start.start).Nanoseconds()) / 1000,
+		Pid:   actionsPid,
 		Tid:   uint64(start.cpu),
 		Arg: &statsArg{
+			SoongModule:                module,
+			SoongVariant:               variant,
+			Rule:                       rule,
 			UserTime:                   result.Stats.UserTime,
 			SystemTime:                 result.Stats.SystemTime,
 			MaxRssKB:                   result.Stats.MaxRssKB,
@@ -94,7 +150,97 @@ func (s *statusOutput) FinishAction(result status.ActionResult, counts status.Co
 	})
 }
 
+// recordNode stores the action's place in the trace under each of its outputs,
+// linking it to the input with the longest critical path so that the overall
+// critical path can be drawn when the build finishes.
+func (s *statusOutput) recordNode(action *status.Action, tid uint64, start, end time.Time) {
+	var criticalPathInput *actionNode
+	for _, input := range action.Inputs {
+		if x := s.nodes[input]; x != nil {
+			if criticalPathInput == nil || x.cumulativeDuration > criticalPathInput.cumulativeDuration {
+				criticalPathInput = x
+			}
+		}
+	}
+
+	node := &actionNode{
+		tid:                tid,
+		start:              start,
+		end:                end,
+		cumulativeDuration: end.Sub(start),
+		input:              criticalPathInput,
+	}
+	if criticalPathInput != nil {
+		node.cumulativeDuration += criticalPathInput.cumulativeDuration
+	}
+
+	for _, output := range action.Outputs {
+		s.nodes[output] = node
+	}
+}
+
+func (s *statusOutput) recordModule(module, variant string, start, end time.Time) {
+	stats := s.modules[module]
+	if stats == nil {
+		stats = &moduleStats{
+			name:     module,
+			start:    start,
+			end:      end,
+			variants: map[string]bool{},
+		}
+		s.modules[module] = stats
+	}
+
+	if start.Before(stats.start) {
+		stats.start = start
+	}
+	if end.After(stats.end) {
+		stats.end = end
+	}
+	stats.actions++
+	stats.duration += end.Sub(start)
+	stats.variants[variant] = true
+}
+
+// parseSoongDescription extracts the Soong module, variant and rule from an
+// action description. Soong prefixes the description of every build statement
+// created by a module with "//<dir>:<module> " and, for non-primary variants,
+// suffixes it with " [<variant>]" (see moduleContext.Build). The first word of
+// the remaining description names the rule by convention (for example "javac"
+// or "cp"). Descriptions that were not created by a Soong module return empty
+// strings.
+func parseSoongDescription(desc string) (module, variant, rule string) {
+	if !strings.HasPrefix(desc, "//") {
+		return "", "", ""
+	}
+
+	space := strings.IndexByte(desc, ' ')
+	if space == -1 {
+		return desc, "", ""
+	}
+	module, desc = desc[:space], desc[space+1:]
+	if !strings.Contains(module, ":") {
+		return "", "", ""
+	}
+
+	if strings.HasSuffix(desc, "]") {
+		if i := strings.LastIndex(desc, " ["); i != -1 {
+			variant = desc[i+2 : len(desc)-1]
+			desc = desc[:i]
+		}
+	}
+
+	if fields := strings.Fields(desc); len(fields) > 0 {
+		rule = fields[0]
+	}
+
+	return module, variant, rule
+}
+
 type statsArg struct {
+	SoongModule                string `json:"soong_module,omitempty"`
+	SoongVariant               string `json:"soong_variant,omitempty"`
+	Rule                       string `json:"rule,omitempty"`
 	UserTime                   uint32 `json:"user_time"`
 	SystemTime                 uint32 `json:"system_time_ms"`
 	MaxRssKB                   uint64 `json:"max_rss_kb"`
@@ -106,7 +252,135 @@ type statsArg struct {
 	InvoluntaryContextSwitches uint64 `json:"involuntary_context_switches"`
 }
 
-func (s *statusOutput) Flush()                                        {}
+type moduleArg struct {
+	Actions    int      `json:"actions"`
+	DurationMs int64    `json:"total_action_duration_ms"`
+	Variants   []string `json:"variants,omitempty"`
+}
+
+// Flush writes the events that can only be computed once all of the actions
+// have finished: the flow arrows along the critical path and the per-module
+// aggregate track.
+func (s *statusOutput) Flush() {
+	if s.flushed {
+		return
+	}
+	s.flushed = true
+
+	s.tracer.lock.Lock()
+	defer s.tracer.lock.Unlock()
+
+	s.writeCriticalPathLocked()
+	s.writeModulesLocked()
+}
+
+// writeCriticalPathLocked connects the actions on the critical path with a
+// single chain of flow events, each bound to the action's Complete Event.
+func (s *statusOutput) writeCriticalPathLocked() {
+	var max *actionNode
+	for _, node := range s.nodes {
+		if max == nil || node.cumulativeDuration > max.cumulativeDuration {
+			max = node
+		}
+	}
+
+	var criticalPath []*actionNode
+	for node := max; node != nil; node = node.input {
+		criticalPath = append(criticalPath, node)
+	}
+
+	if len(criticalPath) < 2 {
+		return
+	}
+
+	for i := len(criticalPath) - 1; i >= 0; i-- {
+		phase := "t"
+		if i == len(criticalPath)-1 {
+			phase = "s"
+		} else if i == 0 {
+			phase = "f"
+		}
+
+		s.tracer.writeEventLocked(&viewerEvent{
+			Name:      "critical path",
+			Category:  "critical_path",
+			Phase:     phase,
+			Time:      uint64(criticalPath[i].start.UnixNano()) / 1000,
+			Pid:       actionsPid,
+			Tid:       criticalPath[i].tid,
+			ID:        criticalPathFlowId,
+			BindPoint: "e",
+		})
+	}
+}
+
+// writeModulesLocked writes one Complete Event per Soong module, spanning from
+// the start of its first action to the end of its last one. Modules are placed
+// on the first free lane, like the actions themselves.
+func (s *statusOutput) writeModulesLocked() {
+	if len(s.modules) == 0 {
+		return
+	}
+
+	modules := make([]*moduleStats, 0, len(s.modules))
+	for _, m := range s.modules {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if !modules[i].start.Equal(modules[j].start) {
+			return modules[i].start.Before(modules[j].start)
+		}
+		return modules[i].name < modules[j].name
+	})
+
+	s.tracer.writeEventLocked(&viewerEvent{
+		Name:  "process_name",
+		Phase: "M",
+		Pid:   modulesPid,
+		Arg: &nameArg{
+			Name: "Soong modules",
+		},
+	})
+
+	var lanes []time.Time
+	for _, m := range modules {
+		lane := -1
+		for i, end := range lanes {
+			if !end.After(m.start) {
+				lane = i
+				break
+			}
+		}
+		if lane == -1 {
+			lane = len(lanes)
+			lanes = append(lanes, time.Time{})
+		}
+		lanes[lane] = m.end
+
+		var variants []string
+		for v := range m.variants {
+			if v != "" {
+				variants = append(variants, v)
+			}
+		}
+		sort.Strings(variants)
+
+		s.tracer.writeEventLocked(&viewerEvent{
+			Name:  m.name,
+			Phase: "X",
+			Time:  uint64(m.start.UnixNano()) / 1000,
+			Dur:   uint64(m.end.Sub(m.start).Nanoseconds()) / 1000,
+			Pid:   modulesPid,
+			Tid:   uint64(lane),
+			Arg: &moduleArg{
+				Actions:    m.actions,
+				DurationMs: m.duration.Milliseconds(),
+				Variants:   variants,
+			},
+		})
+	}
+}
+
 func (s *statusOutput) Message(level status.MsgLevel, message string) {}
 
 func (s *statusOutput) Write(p []byte) (int, error) {
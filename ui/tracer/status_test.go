@@ -0,0 +1,125 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/status"
+)
+
+func TestParseSoongDescription(t *testing.T) {
+	tests := []struct {
+		desc                  string
+		module, variant, rule string
+	}{
+		{
+			desc: "",
+		},
+		{
+			desc: "Packaging system image",
+		},
+		{
+			desc:   "//frameworks/base:framework javac",
+			module: "//frameworks/base:framework",
+			rule:   "javac",
+		},
+		{
+			desc:    "//build/soong:soong_zip clang++ zip.go [linux_glibc x86]",
+			module:  "//build/soong:soong_zip",
+			variant: "linux_glibc x86",
+			rule:    "clang++",
+		},
+		{
+			desc:   "//system/core:libbase",
+			module: "//system/core:libbase",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			module, variant, rule := parseSoongDescription(test.desc)
+			if module != test.module || variant != test.variant || rule != test.rule {
+				t.Errorf("want %q, %q, %q, got %q, %q, %q",
+					test.module, test.variant, test.rule, module, variant, rule)
+			}
+		})
+	}
+}
+
+func TestStatusTracerFlush(t *testing.T) {
+	trace := New(logger.New(ioutil.Discard))
+	s := trace.StatusTracer()
+
+	run := func(desc string, outputs, inputs []string) {
+		action := &status.Action{
+			Description: desc,
+			Outputs:     outputs,
+			Inputs:      inputs,
+		}
+		s.StartAction(action, status.Counts{})
+		s.FinishAction(status.ActionResult{Action: action}, status.Counts{})
+	}
+
+	run("//a:a javac", []string{"a.jar"}, nil)
+	run("//b:b javac", []string{"b.jar"}, []string{"a.jar"})
+	run("//a:a cp [linux_glibc]", []string{"c.jar"}, []string{"b.jar"})
+	run("unrelated", []string{"d"}, []string{"c.jar"})
+	s.Flush()
+
+	var events []viewerEvent
+	if err := json.Unmarshal(append(trace.buf.Bytes(), ']'), &events); err != nil {
+		t.Fatalf("failed to parse trace: %s\n%s", err, trace.buf.String())
+	}
+
+	var flows []string
+	var modules []string
+	for _, event := range events {
+		switch {
+		case event.Category == "critical_path":
+			flows = append(flows, event.Phase)
+			if event.ID != criticalPathFlowId || event.BindPoint != "e" {
+				t.Errorf("unexpected flow event %#v", event)
+			}
+		case event.Pid == modulesPid && event.Phase == "X":
+			modules = append(modules, event.Name)
+			if event.Name == "//a:a" {
+				arg := event.Arg.(map[string]interface{})
+				if arg["actions"] != float64(2) {
+					t.Errorf("want 2 actions for //a:a, got %v", arg["actions"])
+				}
+				if !reflect.DeepEqual(arg["variants"], []interface{}{"linux_glibc"}) {
+					t.Errorf("want variants [linux_glibc] for //a:a, got %v", arg["variants"])
+				}
+			}
+		case event.Pid == actionsPid && event.Name == "b.jar":
+			arg := event.Arg.(map[string]interface{})
+			if arg["soong_module"] != "//b:b" || arg["rule"] != "javac" {
+				t.Errorf("unexpected args for b.jar: %v", arg)
+			}
+		}
+	}
+
+	if want := []string{"s", "t", "t", "f"}; !reflect.DeepEqual(flows, want) {
+		t.Errorf("want critical path flow events %q, got %q", want, flows)
+	}
+	if want := []string{"//a:a", "//b:b"}; !reflect.DeepEqual(modules, want) {
+		t.Errorf("want module events %q, got %q", want, modules)
+	}
+}
@@ -68,15 +68,17 @@ type tracerImpl struct {
 var _ Tracer = &tracerImpl{}
 
 type viewerEvent struct {
-	Name  string      `json:"name,omitempty"`
-	Phase string      `json:"ph"`
-	Scope string      `json:"s,omitempty"`
-	Time  uint64      `json:"ts"`
-	Dur   uint64      `json:"dur,omitempty"`
-	Pid   uint64      `json:"pid"`
-	Tid   uint64      `json:"tid"`
-	ID    uint64      `json:"id,omitempty"`
-	Arg   interface{} `json:"args,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Category  string      `json:"cat,omitempty"`
+	Phase     string      `json:"ph"`
+	Scope     string      `json:"s,omitempty"`
+	Time      uint64      `json:"ts"`
+	Dur       uint64      `json:"dur,omitempty"`
+	Pid       uint64      `json:"pid"`
+	Tid       uint64      `json:"tid"`
+	ID        uint64      `json:"id,omitempty"`
+	BindPoint string      `json:"bp,omitempty"`
+	Arg       interface{} `json:"args,omitempty"`
 }
 
 type nameArg struct {
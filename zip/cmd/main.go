@@ -154,11 +154,13 @@ func main() {
 	manifest := flags.String("m", "", "input jar manifest file name")
 	directories := flags.Bool("d", false, "include directories in zip")
 	compLevel := flags.Int("L", 5, "deflate compression level (0-9)")
+	incremental := flags.Bool("incremental", false, "copy unchanged files from the existing output instead of compressing them again")
 	emulateJar := flags.Bool("jar", false, "modify the resultant .zip to emulate the output of 'jar'")
 	writeIfChanged := flags.Bool("write_if_changed", false, "only update resultant .zip if it has changed")
 	ignoreMissingFiles := flags.Bool("ignore_missing_files", false, "continue if a requested file does not exist")
 	symlinks := flags.Bool("symlinks", true, "store symbolic links in zip instead of following them")
 	srcJar := flags.Bool("srcjar", false, "move .java files to locations that match their package statement")
+	verifyReproducible := flags.Bool("verify-reproducible", false, "fail if creating the zip file again with a different number of parallel threads produces different bytes")
 
 	parallelJobs := flags.Int("parallel", runtime.NumCPU(), "number of parallel threads to use")
 	cpuProfile := flags.String("cpuprofile", "", "write cpu profile to file")
@@ -224,6 +226,8 @@ func main() {
 		WriteIfChanged:           *writeIfChanged,
 		StoreSymlinks:            *symlinks,
 		IgnoreMissingFiles:       *ignoreMissingFiles,
+		VerifyReproducible:       *verifyReproducible,
+		Incremental:              *incremental,
 	})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err.Error())
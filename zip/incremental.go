@@ -0,0 +1,176 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+
+	"android/soong/third_party/zip"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+// An incremental update copies the compressed contents of the entries of the previous output
+// whose contents haven't changed instead of compressing them again.  A file is considered
+// unchanged if it has the same name, mode and size as an entry in the previous output, and the
+// same bytes as the decompressed entry.  A matching CRC is not enough, different contents can have
+// the same CRC.
+//
+// Reusing compressed contents is only correct if they would be compressed to the same bytes
+// again, so the zip files are marked with a comment that identifies the compression settings and
+// the version of Go that compressed them, and a previous output without the same comment is
+// ignored.
+
+// incrementalCommentPrefix is the start of the comment of a zip file written with
+// ZipArgs.Incremental.
+const incrementalCommentPrefix = "soong_zip incremental "
+
+// incrementalComment returns the comment for zip files written with args.
+func incrementalComment(args ZipArgs) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "go %q\n", runtime.Version())
+	fmt.Fprintf(h, "level %d\n", args.CompressionLevel)
+
+	// Whether a file is stored depends on the non-deflated files, which are not recorded in the
+	// zip file.
+	var nonDeflatedFiles []string
+	for file := range args.NonDeflatedFiles {
+		nonDeflatedFiles = append(nonDeflatedFiles, file)
+	}
+	sort.Strings(nonDeflatedFiles)
+	for _, file := range nonDeflatedFiles {
+		fmt.Fprintf(h, "stored %q\n", file)
+	}
+
+	return incrementalCommentPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// openPreviousZip opens the zip file at path for ZipWriter.previous if it was written with the
+// comment.  It returns nil if the zip file doesn't exist, can't be read or was written with
+// different settings.
+func openPreviousZip(path, comment string) (*os.File, *zip.Reader) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil
+	}
+	if r, err := readPreviousZip(f, comment); err == nil && r != nil {
+		return f, r
+	}
+	// The previous output may be corrupt if the last update failed, build it from scratch.
+	f.Close()
+	return nil, nil
+}
+
+func readPreviousZip(f *os.File, comment string) (*zip.Reader, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil || r.Comment != comment {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reuseEntry writes the entry from the previous output with the name of header if it has the same
+// contents as r.  It returns false if the entry must be compressed again, in which case r is
+// rewound to the start.  header must be complete except for the CRC.
+func (z *ZipWriter) reuseEntry(header *zip.FileHeader, r pathtools.ReaderAtSeekerCloser) (bool, error) {
+	prev := z.previousEntries[header.Name]
+	if prev == nil ||
+		prev.UncompressedSize64 != header.UncompressedSize64 ||
+		prev.ExternalAttrs != header.ExternalAttrs ||
+		prev.ModifiedTime != header.ModifiedTime ||
+		prev.ModifiedDate != header.ModifiedDate {
+		return false, nil
+	}
+	// Files that don't get smaller when they are compressed are stored.
+	if prev.Method != header.Method && prev.Method != zip.Store {
+		return false, nil
+	}
+
+	if same, err := sameContents(r, prev); err != nil {
+		return false, err
+	} else if !same {
+		_, err := r.Seek(0, io.SeekStart)
+		return false, err
+	}
+	r.Close()
+
+	offset, err := prev.DataOffset()
+	if err != nil {
+		return false, err
+	}
+
+	header.Method = prev.Method
+	header.CRC32 = prev.CRC32
+
+	futureReader := make(chan io.Reader, 1)
+	futureReader <- io.NewSectionReader(z.previous, offset, int64(prev.CompressedSize64))
+	close(futureReader)
+
+	ze := &zipEntry{
+		fh:            header,
+		futureReaders: make(chan chan io.Reader, 1),
+	}
+	ze.futureReaders <- futureReader
+	close(ze.futureReaders)
+
+	compressChan := make(chan *zipEntry, 1)
+	compressChan <- ze
+	close(compressChan)
+	z.writeOps <- compressChan
+
+	return true, nil
+}
+
+// sameContents returns true if r has the same contents as the decompressed entry prev.
+func sameContents(r io.Reader, prev *zip.File) (bool, error) {
+	prevReader, err := prev.Open()
+	if err != nil {
+		// The entry is compressed again if the previous output can't be read.
+		return false, nil
+	}
+	defer prevReader.Close()
+
+	buf := make([]byte, 32*1024)
+	prevBuf := make([]byte, len(buf))
+	for {
+		n, err := io.ReadFull(r, buf)
+		done := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !done {
+			return false, err
+		}
+		if _, err := io.ReadFull(prevReader, prevBuf[:n]); err != nil || !bytes.Equal(buf[:n], prevBuf[:n]) {
+			return false, nil
+		}
+		if done {
+			break
+		}
+	}
+	// Reading the end of the previous entry checks its CRC.
+	if n, err := prevReader.Read(prevBuf[:1]); n != 0 || err != io.EOF {
+		return false, nil
+	}
+	return true, nil
+}
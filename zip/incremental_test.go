@@ -0,0 +1,211 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+func TestIncremental(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.zip")
+
+	args := func(files map[string][]byte) ZipArgs {
+		return ZipArgs{
+			FileArgs: NewFileArgsBuilder().
+				File("a").
+				File("b").
+				FileArgs(),
+			OutputFilePath:   out,
+			CompressionLevel: 9,
+			Incremental:      true,
+			Filesystem:       pathtools.MockFs(files),
+			Stderr:           &bytes.Buffer{},
+		}
+	}
+	// zipFromScratch returns the zip file that Zip writes without a previous output.
+	zipFromScratch := func(files map[string][]byte) []byte {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		noPrevious := args(files)
+		noPrevious.OutputFilePath = filepath.Join(t.TempDir(), "missing.zip")
+		if err := zipTo(noPrevious, buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	readOut := func() []byte {
+		t.Helper()
+		data, err := ioutil.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	files := map[string][]byte{"a": fileA, "b": fileB}
+	if err := Zip(args(files)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readOut(), zipFromScratch(files)) {
+		t.Errorf("first build differs from a build from scratch")
+	}
+
+	// Updating one file produces the same zip file as a build from scratch.
+	files = map[string][]byte{"a": fileA, "b": fileC}
+	if err := Zip(args(files)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readOut(), zipFromScratch(files)) {
+		t.Errorf("incremental update differs from a build from scratch")
+	}
+
+	// writePrevious writes a previous output that has a stored entry for a with the contents of
+	// fileA, which would be deflated by a build from scratch.
+	writePrevious := func(comment string) {
+		t.Helper()
+		f, err := os.Create(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		zw := zip.NewWriter(f)
+		if err := zw.SetComment(comment); err != nil {
+			t.Fatal(err)
+		}
+		fh := &zip.FileHeader{Name: "a", Method: zip.Store}
+		fh.SetModTime(jar.DefaultTime)
+		fh.SetMode(0644)
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(fileA)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	methodOfA := func() uint16 {
+		t.Helper()
+		data := readOut()
+		r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range r.File {
+			if f.Name == "a" {
+				return f.Method
+			}
+		}
+		t.Fatalf("missing entry a")
+		return 0
+	}
+
+	// Unchanged files are copied from the previous output.
+	writePrevious(incrementalComment(args(files)))
+	if err := Zip(args(files)); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := methodOfA(), zip.Store; g != w {
+		t.Errorf("expected a to be copied from the previous output with method %d, got %d", w, g)
+	}
+
+	// A file with the same size and CRC as the previous entry but different contents is compressed
+	// again.
+	collision := map[string][]byte{"a": crcCollision(t, fileA), "b": fileC}
+	writePrevious(incrementalComment(args(collision)))
+	if err := Zip(args(collision)); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := methodOfA(), zip.Deflate; g != w {
+		t.Errorf("expected a with a colliding CRC to be compressed again with method %d, got %d", w, g)
+	}
+	if !bytes.Equal(readOut(), zipFromScratch(collision)) {
+		t.Errorf("incremental update with a colliding CRC differs from a build from scratch")
+	}
+
+	// Previous outputs written with different settings are ignored.
+	writePrevious(incrementalCommentPrefix + "other")
+	if err := Zip(args(files)); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := methodOfA(), zip.Deflate; g != w {
+		t.Errorf("expected a to be compressed again with method %d, got %d", w, g)
+	}
+}
+
+// crcCollision returns different contents with the same length and CRC32 as data.  For inputs of
+// the same length the CRC is linear, so the change to the CRC caused by flipping a bit of the first
+// byte can be undone by flipping bits of the last four bytes, which are found by Gaussian
+// elimination.
+func crcCollision(t *testing.T, data []byte) []byte {
+	t.Helper()
+	forged := append([]byte(nil), data...)
+	forged[0] ^= 1
+	crc := crc32.ChecksumIEEE(forged)
+
+	// The change to the CRC caused by flipping each bit of the last four bytes.
+	type row struct{ effect, bits uint32 }
+	var rows []row
+	for i := 0; i < 32; i++ {
+		flipped := append([]byte(nil), forged...)
+		flipped[len(flipped)-4+i/8] ^= 1 << (i % 8)
+		rows = append(rows, row{crc32.ChecksumIEEE(flipped) ^ crc, 1 << i})
+	}
+	for pivot := 0; pivot < 32; pivot++ {
+		bit := uint32(1) << (31 - pivot)
+		for i := pivot; i < len(rows); i++ {
+			if rows[i].effect&bit != 0 {
+				rows[pivot], rows[i] = rows[i], rows[pivot]
+				break
+			}
+		}
+		if rows[pivot].effect&bit == 0 {
+			t.Fatalf("CRC is not linear in the last four bytes")
+		}
+		for i := range rows {
+			if i != pivot && rows[i].effect&bit != 0 {
+				rows[i].effect ^= rows[pivot].effect
+				rows[i].bits ^= rows[pivot].bits
+			}
+		}
+	}
+
+	want := crc32.ChecksumIEEE(data) ^ crc
+	var bits uint32
+	for _, r := range rows {
+		if want&r.effect != 0 {
+			bits ^= r.bits
+		}
+	}
+	for i := 0; i < 32; i++ {
+		if bits&(1<<i) != 0 {
+			forged[len(forged)-4+i/8] ^= 1 << (i % 8)
+		}
+	}
+	if crc32.ChecksumIEEE(forged) != crc32.ChecksumIEEE(data) || bytes.Equal(forged, data) {
+		t.Fatalf("failed to find a CRC collision")
+	}
+	return forged
+}
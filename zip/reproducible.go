@@ -0,0 +1,205 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+// zipReproducibly writes the zip file described by args to w after checking that creating it
+// again with a different number of parallel jobs produces identical bytes.  Both copies of the
+// zip file are held in memory.
+func zipReproducibly(args ZipArgs, w io.Writer) error {
+	first := &bytes.Buffer{}
+	if err := zipTo(args, first); err != nil {
+		return err
+	}
+
+	secondArgs := args
+	secondArgs.NumParallelJobs = otherParallelism(args.NumParallelJobs)
+	// Warnings were already printed by the first run.
+	secondArgs.Stderr = ioutil.Discard
+	second := &bytes.Buffer{}
+	if err := zipTo(secondArgs, second); err != nil {
+		return err
+	}
+
+	if err := CompareZips(first.Bytes(), second.Bytes()); err != nil {
+		return fmt.Errorf("zip file is not reproducible with %d and %d parallel jobs: %w",
+			args.NumParallelJobs, secondArgs.NumParallelJobs, err)
+	}
+
+	_, err := w.Write(first.Bytes())
+	return err
+}
+
+// otherParallelism returns a number of parallel jobs that is different from n.
+func otherParallelism(n int) int {
+	if n > 1 {
+		return 1
+	}
+	if cpus := runtime.NumCPU(); cpus > 1 {
+		return cpus
+	}
+	return 2
+}
+
+// CompareZips returns nil if the zip files a and b are identical, or an error that describes the
+// first difference between them.
+func CompareZips(a, b []byte) error {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+
+	aReader, err := zip.NewReader(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		return fmt.Errorf("first zip file: %w", err)
+	}
+	bReader, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return fmt.Errorf("second zip file: %w", err)
+	}
+
+	for i := 0; i < len(aReader.File) && i < len(bReader.File); i++ {
+		if err := compareZipEntries(a, b, aReader.File[i], bReader.File[i]); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	if len(aReader.File) != len(bReader.File) {
+		return fmt.Errorf("number of entries differs: %d != %d", len(aReader.File), len(bReader.File))
+	}
+	if aReader.Comment != bReader.Comment {
+		return fmt.Errorf("comment differs: %q != %q", aReader.Comment, bReader.Comment)
+	}
+
+	return fmt.Errorf("zip files differ outside of their entries")
+}
+
+func compareZipEntries(a, b []byte, aFile, bFile *zip.File) error {
+	if aFile.Name != bFile.Name {
+		return fmt.Errorf("name differs: %q != %q", aFile.Name, bFile.Name)
+	}
+
+	fields := []struct {
+		name string
+		a, b interface{}
+	}{
+		{"method", aFile.Method, bFile.Method},
+		{"flags", aFile.Flags, bFile.Flags},
+		{"modification time", aFile.ModTime(), bFile.ModTime()},
+		{"creator version", aFile.CreatorVersion, bFile.CreatorVersion},
+		{"reader version", aFile.ReaderVersion, bFile.ReaderVersion},
+		{"external attributes", aFile.ExternalAttrs, bFile.ExternalAttrs},
+		{"crc", aFile.CRC32, bFile.CRC32},
+		{"uncompressed size", aFile.UncompressedSize64, bFile.UncompressedSize64},
+		{"compressed size", aFile.CompressedSize64, bFile.CompressedSize64},
+		{"comment", aFile.Comment, bFile.Comment},
+	}
+	for _, field := range fields {
+		if field.a != field.b {
+			return fmt.Errorf("%s of %q differs: %v != %v", field.name, aFile.Name, field.a, field.b)
+		}
+	}
+	if !bytes.Equal(aFile.Extra, bFile.Extra) {
+		return fmt.Errorf("extra fields of %q differ: %x != %x", aFile.Name, aFile.Extra, bFile.Extra)
+	}
+
+	aData, err := rawZipEntryData(a, aFile)
+	if err != nil {
+		return err
+	}
+	bData, err := rawZipEntryData(b, bFile)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(aData, bData) {
+		return fmt.Errorf("compressed contents of %q differ", aFile.Name)
+	}
+
+	return nil
+}
+
+// rawZipEntryData returns the contents of f in the zip file data without decompressing them.
+func rawZipEntryData(data []byte, f *zip.File) ([]byte, error) {
+	offset, err := f.DataOffset()
+	if err != nil {
+		return nil, err
+	}
+	end := offset + int64(f.CompressedSize64)
+	if offset < 0 || end > int64(len(data)) {
+		return nil, fmt.Errorf("contents of %q are out of bounds", f.Name)
+	}
+	return data[offset:end], nil
+}
+
+// NormalizeZip copies the entries of the zip file r to w without recompressing them, with the
+// modification times set to jar.DefaultTime, the permissions of directories and executable files
+// set to 0755, those of other files set to 0644, and all extra fields removed except for the one
+// that marks the META-INF directory of a jar.
+func NormalizeZip(r *zip.Reader, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, orig := range r.File {
+		// Copy the entry so that the header of the reader is not modified.
+		f := *orig
+		f.SetModTime(jar.DefaultTime)
+		f.SetMode(normalizedMode(orig.Mode()))
+		f.Extra = normalizedExtra(orig.Extra)
+
+		if err := zw.CopyFrom(&f, f.Name); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func normalizedMode(mode os.FileMode) os.FileMode {
+	switch {
+	case mode.IsDir():
+		return 0755 | os.ModeDir
+	case mode&os.ModeSymlink != 0:
+		return 0777 | os.ModeSymlink
+	case mode&0111 != 0:
+		return 0755
+	default:
+		return 0644
+	}
+}
+
+// normalizedExtra returns the extra fields in extra that are kept by NormalizeZip.
+func normalizedExtra(extra []byte) []byte {
+	var ret []byte
+	for len(extra) >= 4 {
+		tag := uint16(extra[0]) | uint16(extra[1])<<8
+		size := int(extra[2]) | int(extra[3])<<8
+		if 4+size > len(extra) {
+			break
+		}
+		if tag == uint16(jar.MetaDirExtra[0])<<8|uint16(jar.MetaDirExtra[1]) {
+			ret = append(ret, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return ret
+}
@@ -0,0 +1,224 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+
+	"github.com/google/blueprint/pathtools"
+)
+
+func TestZipReproducibly(t *testing.T) {
+	// A file large enough to be compressed in parallel blocks.
+	large := make([]byte, minParallelFileSize+parallelBlockSize/2)
+	for i := range large {
+		large[i] = byte(i % 251)
+	}
+
+	args := ZipArgs{
+		FileArgs: fileArgsBuilder().
+			File("a/a/a").
+			File("a/a/b").
+			File("large").
+			FileArgs(),
+		CompressionLevel: 5,
+		NumParallelJobs:  4,
+		Filesystem: pathtools.MockFs(map[string][]byte{
+			"a/a/a": fileA,
+			"a/a/b": fileB,
+			"large": large,
+		}),
+		Stderr: &bytes.Buffer{},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := zipReproducibly(args, buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := &bytes.Buffer{}
+	if err := zipTo(args, want); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Errorf("zipReproducibly output differs from zipTo output")
+	}
+}
+
+func writeTestZip(t *testing.T, headers []zip.FileHeader) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for i := range headers {
+		w, err := zw.CreateHeader(&headers[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !headers[i].Mode().IsDir() {
+			if _, err := w.Write([]byte(headers[i].Name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompareZips(t *testing.T) {
+	header := func(name string, modTime time.Time) zip.FileHeader {
+		fh := zip.FileHeader{Name: name, Method: zip.Deflate}
+		fh.SetModTime(modTime)
+		fh.SetMode(0644)
+		return fh
+	}
+	otherTime := jar.DefaultTime.Add(time.Hour)
+
+	testCases := []struct {
+		name    string
+		a, b    []zip.FileHeader
+		wantErr string
+	}{
+		{
+			name: "identical",
+			a:    []zip.FileHeader{header("a", jar.DefaultTime), header("b", jar.DefaultTime)},
+			b:    []zip.FileHeader{header("a", jar.DefaultTime), header("b", jar.DefaultTime)},
+		},
+		{
+			name:    "order",
+			a:       []zip.FileHeader{header("a", jar.DefaultTime), header("b", jar.DefaultTime)},
+			b:       []zip.FileHeader{header("b", jar.DefaultTime), header("a", jar.DefaultTime)},
+			wantErr: `entry 0: name differs: "a" != "b"`,
+		},
+		{
+			name:    "time",
+			a:       []zip.FileHeader{header("a", jar.DefaultTime), header("b", jar.DefaultTime)},
+			b:       []zip.FileHeader{header("a", jar.DefaultTime), header("b", otherTime)},
+			wantErr: `entry 1: modification time of "b" differs`,
+		},
+		{
+			name:    "missing entry",
+			a:       []zip.FileHeader{header("a", jar.DefaultTime), header("b", jar.DefaultTime)},
+			b:       []zip.FileHeader{header("a", jar.DefaultTime)},
+			wantErr: "number of entries differs: 2 != 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CompareZips(writeTestZip(t, tc.a), writeTestZip(t, tc.b))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+			} else if err == nil {
+				t.Errorf("want error %q, got nil", tc.wantErr)
+			} else if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("want error %q, got %q", tc.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestNormalizeZip(t *testing.T) {
+	modTime := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	extendedTimestamp := []byte{0x55, 0x54, 5, 0, 1, 0, 0, 0, 0}
+
+	metaDir := *jar.MetaDirFileHeader()
+	metaDir.SetModTime(modTime)
+	metaDir.Extra = append(metaDir.Extra, extendedTimestamp...)
+
+	file := zip.FileHeader{Name: "file", Method: zip.Deflate, Extra: extendedTimestamp}
+	file.SetModTime(modTime)
+	file.SetMode(0600)
+
+	executable := zip.FileHeader{Name: "executable", Method: zip.Store}
+	executable.SetModTime(modTime)
+	executable.SetMode(0700)
+
+	dir := zip.FileHeader{Name: "dir/"}
+	dir.SetModTime(modTime)
+	dir.SetMode(0700 | os.ModeDir)
+
+	in := writeTestZip(t, []zip.FileHeader{metaDir, file, executable, dir})
+	r, err := zip.NewReader(bytes.NewReader(in), int64(len(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := NormalizeZip(r, buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	out, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		name  string
+		mode  os.FileMode
+		extra []byte
+	}{
+		{jar.MetaDir, 0755 | os.ModeDir, []byte{jar.MetaDirExtra[1], jar.MetaDirExtra[0], 0, 0}},
+		{"file", 0644, nil},
+		{"executable", 0755, nil},
+		{"dir/", 0755 | os.ModeDir, nil},
+	}
+	if len(out.File) != len(want) {
+		t.Fatalf("want %d entries, got %d", len(want), len(out.File))
+	}
+	for i, w := range want {
+		f := out.File[i]
+		if f.Name != w.name {
+			t.Errorf("entry %d: want name %q, got %q", i, w.name, f.Name)
+		}
+		if f.Mode() != w.mode {
+			t.Errorf("%s: want mode %v, got %v", w.name, w.mode, f.Mode())
+		}
+		if !f.ModTime().Equal(jar.DefaultTime) {
+			t.Errorf("%s: want time %v, got %v", w.name, jar.DefaultTime, f.ModTime())
+		}
+		if !bytes.Equal(f.Extra, w.extra) {
+			t.Errorf("%s: want extra %x, got %x", w.name, w.extra, f.Extra)
+		}
+		if !f.Mode().IsDir() {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents := &bytes.Buffer{}
+			contents.ReadFrom(rc)
+			rc.Close()
+			if contents.String() != w.name {
+				t.Errorf("%s: want contents %q, got %q", w.name, w.name, contents.String())
+			}
+		}
+	}
+
+	// Normalizing doesn't modify the input reader.
+	if !r.File[1].ModTime().Equal(modTime) {
+		t.Errorf("NormalizeZip modified its input")
+	}
+}
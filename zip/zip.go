@@ -221,6 +221,11 @@ type ZipWriter struct {
 
 	stderr io.Writer
 	fs     pathtools.FileSystem
+
+	// The previous output and its entries by name for incremental updates.
+	previous        io.ReaderAt
+	previousEntries map[string]*zip.File
+	comment         string
 }
 
 type zipEntry struct {
@@ -247,6 +252,13 @@ type ZipArgs struct {
 	WriteIfChanged           bool
 	StoreSymlinks            bool
 	IgnoreMissingFiles       bool
+	VerifyReproducible       bool
+
+	// Incremental copies the compressed contents of unchanged files from the existing zip file at
+	// OutputFilePath instead of compressing them again.  The zip file is marked with a comment that
+	// identifies the compression settings, and an existing zip file with a different comment is
+	// ignored.
+	Incremental bool
 
 	Stderr     io.Writer
 	Filesystem pathtools.FileSystem
@@ -280,6 +292,18 @@ func zipTo(args ZipArgs, w io.Writer) error {
 		z.stderr = os.Stderr
 	}
 
+	if args.Incremental {
+		z.comment = incrementalComment(args)
+		if f, r := openPreviousZip(args.OutputFilePath, z.comment); r != nil {
+			defer f.Close()
+			z.previous = f
+			z.previousEntries = make(map[string]*zip.File, len(r.File))
+			for _, file := range r.File {
+				z.previousEntries[file.Name] = file
+			}
+		}
+	}
+
 	pathMappings := []pathMapping{}
 
 	noCompression := args.CompressionLevel == 0
@@ -364,7 +388,8 @@ func Zip(args ZipArgs) error {
 
 	var zipErr error
 
-	if !args.WriteIfChanged {
+	// Incremental updates read the existing output while writing the new one.
+	if !args.WriteIfChanged && !args.Incremental {
 		f, err := os.Create(args.OutputFilePath)
 		if err != nil {
 			return err
@@ -380,7 +405,11 @@ func Zip(args ZipArgs) error {
 		out = f
 	}
 
-	zipErr = zipTo(args, out)
+	if args.VerifyReproducible {
+		zipErr = zipReproducibly(args, out)
+	} else {
+		zipErr = zipTo(args, out)
+	}
 	if zipErr != nil {
 		return zipErr
 	}
@@ -390,6 +419,11 @@ func Zip(args ZipArgs) error {
 		if err != nil {
 			return err
 		}
+	} else if args.Incremental {
+		err := ioutil.WriteFile(args.OutputFilePath, buf.Bytes(), 0666)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -488,6 +522,11 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 	}()
 
 	zipw := zip.NewWriter(f)
+	if z.comment != "" {
+		if err := zipw.SetComment(z.comment); err != nil {
+			return err
+		}
+	}
 
 	var currentWriteOpChan chan *zipEntry
 	var currentWriter io.WriteCloser
@@ -713,6 +752,12 @@ func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.Reader
 
 	header.SetModTime(z.time)
 
+	if z.previousEntries != nil {
+		if reused, err := z.reuseEntry(header, r); err != nil || reused {
+			return err
+		}
+	}
+
 	compressChan := make(chan *zipEntry, 1)
 	z.writeOps <- compressChan
 